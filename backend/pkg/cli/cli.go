@@ -0,0 +1,183 @@
+// Package cli implements the admin commands of the server binary. They are run
+// as "server [flags] <command> [arguments]" with the same configuration as the
+// server, and use the db and repository packages the server uses.
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"social/pkg/config"
+	db "social/pkg/db"
+	"social/pkg/repository"
+)
+
+// ErrUsage is returned when a command is called with invalid arguments. The
+// usage has already been written when it is returned.
+var ErrUsage = errors.New("invalid usage")
+
+const usage = `Usage: server [flags] <command> [arguments]
+
+Without a command the server is started. Commands:
+
+  migrate up                apply the pending migrations
+  migrate down N            roll back the last N migrations
+  migrate version           print the current schema version
+  migrate force V           record version V as applied, after fixing a failed migration
+  backup FILE               copy the SQLite database to FILE while the server runs
+  restore FILE              replace the SQLite database with FILE, with the server stopped
+  user create               create a user, see "user create -h"
+  user reset-password       set a new password, sign the user out and revoke their API tokens
+  user disable              stop a user from logging in and sign them out
+  user enable               let a disabled user log in again
+  session purge             delete expired sessions, or every session of -user
+`
+
+// command is the environment a command runs in.
+type command struct {
+	ctx context.Context
+	cfg *config.Config
+	in  io.Reader
+	out io.Writer
+}
+
+// Run runs the command in args, reading its input from in and writing its
+// output to out.
+func Run(ctx context.Context, cfg *config.Config, args []string, in io.Reader, out io.Writer) error {
+	c := &command{ctx: ctx, cfg: cfg, in: in, out: out}
+
+	if len(args) == 0 {
+		return c.usageError("")
+	}
+	name, args := args[0], args[1:]
+	if name == "migrate" || name == "user" || name == "session" {
+		if len(args) == 0 {
+			return c.usageError(name + " needs a subcommand")
+		}
+		name, args = name+" "+args[0], args[1:]
+	}
+
+	err := c.run(name, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func (c *command) run(name string, args []string) error {
+	switch name {
+	case "migrate up":
+		return c.migrateUp(args)
+	case "migrate down":
+		return c.migrateDown(args)
+	case "migrate version":
+		return c.migrateVersion(args)
+	case "migrate force":
+		return c.migrateForce(args)
+	case "backup":
+		return c.backup(args)
+	case "restore":
+		return c.restore(args)
+	case "user create":
+		return c.userCreate(args)
+	case "user reset-password":
+		return c.userResetPassword(args)
+	case "user disable":
+		return c.userDisable(args)
+	case "user enable":
+		return c.userEnable(args)
+	case "session purge":
+		return c.sessionPurge(args)
+	case "help", "-h", "-help":
+		fmt.Fprint(c.out, usage)
+		return nil
+	}
+	return c.usageError(fmt.Sprintf("unknown command %q", name))
+}
+
+func (c *command) usageError(msg string) error {
+	if msg != "" {
+		fmt.Fprintln(c.out, msg)
+	}
+	fmt.Fprint(c.out, usage)
+	return ErrUsage
+}
+
+// flags returns the flag set of a command, writing its usage to the output.
+func (c *command) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.out)
+	return fs
+}
+
+// parse parses the flags of a command, which takes no positional arguments.
+func (c *command) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return ErrUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(c.out, "%s: unexpected arguments %s\n", fs.Name(), strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return ErrUsage
+	}
+	return nil
+}
+
+// openStore opens the database for the user and session commands. Migrations
+// are not applied, so a database behind the binary is refused instead of being
+// changed by a command that did not ask for it.
+func (c *command) openStore() (*repository.Query, error) {
+	conn, err := db.Open(c.cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := db.OpenReader(c.cfg.Database)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	q := &repository.Query{Db: conn, Reader: reader, Dialect: repository.Dialect(c.cfg.Database.Driver)}
+
+	latest, err := db.LatestSchemaVersion(c.cfg.Database)
+	if err != nil {
+		closeStore(q)
+		return nil, err
+	}
+	version, dirty, err := q.SchemaVersion(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		version, err = 0, nil
+	}
+	if err != nil {
+		closeStore(q)
+		return nil, fmt.Errorf("reading the schema version: %w", err)
+	}
+	if dirty || version != latest {
+		closeStore(q)
+		return nil, fmt.Errorf("the database schema is at version %d (dirty: %t), expected %d: run \"migrate up\" first", version, dirty, latest)
+	}
+	return q, nil
+}
+
+// closeStore closes the pools opened by openStore.
+func closeStore(q *repository.Query) {
+	if q.Reader != nil {
+		q.Reader.Close()
+	}
+	q.Db.Close()
+}
+
+// openSQLite opens the database for the backup commands, which only exist for SQLite.
+func (c *command) openSQLite(name string) (*sql.DB, error) {
+	if c.cfg.Database.Driver != config.DriverSQLite {
+		return nil, fmt.Errorf("%s only supports the sqlite driver, use the tools of %s instead", name, c.cfg.Database.Driver)
+	}
+	return db.Open(c.cfg.Database)
+}
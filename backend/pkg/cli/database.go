@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+
+	db "social/pkg/db"
+	"social/pkg/db/sqlite"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// migrator opens the database and returns a migrator for it. Closing the
+// migrator closes the database.
+func (c *command) migrator() (*migrate.Migrate, error) {
+	conn, err := db.Open(c.cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	m, err := db.NewMigrator(c.cfg.Database, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// migrate runs change on the migrator and prints the version it leaves.
+func (c *command) migrate(change func(m *migrate.Migrate) error) (err error) {
+	m, err := c.migrator()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if srcErr, dbErr := m.Close(); err == nil {
+			err = errors.Join(srcErr, dbErr)
+		}
+	}()
+
+	if err := change(m); err != nil {
+		if !errors.Is(err, migrate.ErrNoChange) {
+			return err
+		}
+		fmt.Fprintln(c.out, "no change")
+	}
+	return c.printVersion(m)
+}
+
+func (c *command) printVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintln(c.out, "no migration applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		fmt.Fprintf(c.out, "version %d (dirty: the migration failed, fix it then run \"migrate force\")\n", version)
+		return nil
+	}
+	fmt.Fprintf(c.out, "version %d\n", version)
+	return nil
+}
+
+func (c *command) migrateUp(args []string) error {
+	if len(args) != 0 {
+		return c.usageError("migrate up takes no arguments")
+	}
+	return c.migrate(func(m *migrate.Migrate) error {
+		return m.Up()
+	})
+}
+
+func (c *command) migrateDown(args []string) error {
+	if len(args) != 1 {
+		return c.usageError("migrate down needs the number of migrations to roll back")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return c.usageError(fmt.Sprintf("migrate down: %q is not a positive number", args[0]))
+	}
+	return c.migrate(func(m *migrate.Migrate) error {
+		return m.Steps(-n)
+	})
+}
+
+func (c *command) migrateVersion(args []string) error {
+	if len(args) != 0 {
+		return c.usageError("migrate version takes no arguments")
+	}
+	return c.migrate(func(m *migrate.Migrate) error {
+		return nil
+	})
+}
+
+func (c *command) migrateForce(args []string) error {
+	if len(args) != 1 {
+		return c.usageError("migrate force needs a version")
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < -1 {
+		return c.usageError(fmt.Sprintf("migrate force: %q is not a version", args[0]))
+	}
+	return c.migrate(func(m *migrate.Migrate) error {
+		return m.Force(version)
+	})
+}
+
+func (c *command) backup(args []string) error {
+	if len(args) != 1 {
+		return c.usageError("backup needs the file to write")
+	}
+	dest := args[0]
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup: %s already exists", dest)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("backup: %w", err)
+	}
+
+	conn, err := c.openSQLite("backup")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := sqlite.Backup(c.ctx, conn, dest); err != nil {
+		os.Remove(dest)
+		return err
+	}
+	fmt.Fprintf(c.out, "backed up %s to %s\n", c.cfg.Database.Path, dest)
+	return nil
+}
+
+func (c *command) restore(args []string) error {
+	if len(args) != 1 {
+		return c.usageError("restore needs the backup file to restore")
+	}
+	src := args[0]
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+
+	conn, err := c.openSQLite("restore")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := sqlite.Restore(c.ctx, conn, src); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "restored %s from %s\n", c.cfg.Database.Path, src)
+	fmt.Fprintln(c.out, "pending migrations are applied when the server starts, or with \"migrate up\"")
+	return nil
+}
@@ -0,0 +1,290 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+// userByEmail opens the store and looks up the user a command acts on.
+func (c *command) userByEmail(email string) (*repository.Query, string, error) {
+	if email == "" {
+		return nil, "", fmt.Errorf("%w: -email is required", ErrUsage)
+	}
+	q, err := c.openStore()
+	if err != nil {
+		return nil, "", err
+	}
+	userID, err := q.FetchUserIDByEmail(email)
+	if err != nil {
+		closeStore(q)
+		return nil, "", fmt.Errorf("%s: %w", email, err)
+	}
+	return q, userID, nil
+}
+
+// newPassword reads the password from the first line of the input when
+// fromStdin is set, so that it stays out of the process list, or generates one.
+func (c *command) newPassword(fromStdin bool) (string, error) {
+	if !fromStdin {
+		generated, err := util.GenerateToken()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(c.out, "generated password: %s\n", generated)
+		return generated, nil
+	}
+
+	line, err := bufio.NewReader(c.in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("reading the password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password on the standard input")
+	}
+	if err := util.CheckPasswordStrength(password); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+func (c *command) userCreate(args []string) error {
+	fs := c.flags("user create")
+	email := fs.String("email", "", "email address (required)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the standard input instead of generating and printing one")
+	firstName := fs.String("first-name", "", "first name (required)")
+	lastName := fs.String("last-name", "", "last name (required)")
+	dob := fs.String("dob", "", "date of birth as YYYY-MM-DD (required)")
+	nickname := fs.String("nickname", "", "nickname")
+	private := fs.Bool("private", false, "make the profile private")
+	admin := fs.Bool("admin", false, "make the user an administrator")
+	verified := fs.Bool("verified", false, "mark the email address as verified")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, required := range []struct{ name, value string }{
+		{"-email", *email}, {"-first-name", *firstName}, {"-last-name", *lastName}, {"-dob", *dob},
+	} {
+		if strings.TrimSpace(required.value) == "" {
+			missing = append(missing, required.name)
+		}
+	}
+	if len(missing) > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: missing %s", ErrUsage, strings.Join(missing, ", "))
+	}
+	if !util.ValidateEmail(*email) {
+		return fmt.Errorf("invalid email address %q", *email)
+	}
+	dateOfBirth, err := time.Parse(time.DateOnly, *dob)
+	if err != nil {
+		return fmt.Errorf("invalid date of birth %q, expected YYYY-MM-DD", *dob)
+	}
+
+	q, err := c.openStore()
+	if err != nil {
+		return err
+	}
+	defer closeStore(q)
+
+	taken, err := q.CheckRow("users", []string{"email"}, []any{*email})
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("%s is already registered", *email)
+	}
+
+	pass, err := c.newPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	hashed, err := util.EncryptPassword(pass)
+	if err != nil {
+		return err
+	}
+
+	var verifiedAt *time.Time
+	if *verified {
+		now := time.Now()
+		verifiedAt = &now
+	}
+	userID := util.UUIDGen()
+	err = q.InsertData("users", []string{
+		"id",
+		"email",
+		"password",
+		"first_name",
+		"last_name",
+		"date_of_birth",
+		"avatar",
+		"nickname",
+		"about_me",
+		"is_public",
+		"is_admin",
+		"verified_at",
+	}, []any{
+		userID,
+		*email,
+		hashed,
+		*firstName,
+		*lastName,
+		dateOfBirth,
+		// empty like the profile fields left blank at registration, which the
+		// profile and export queries expect
+		"",
+		*nickname,
+		"",
+		!*private,
+		*admin,
+		verifiedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "created user %s (%s)\n", *email, userID)
+	return nil
+}
+
+func (c *command) userResetPassword(args []string) error {
+	fs := c.flags("user reset-password")
+	email := fs.String("email", "", "email address of the user (required)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the new password from the standard input instead of generating and printing one")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	q, userID, err := c.userByEmail(*email)
+	if err != nil {
+		return err
+	}
+	defer closeStore(q)
+
+	pass, err := c.newPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	hashed, err := util.EncryptPassword(pass)
+	if err != nil {
+		return err
+	}
+
+	// As with a reset by email, the sessions opened with the old password end,
+	// and so do the API tokens, which could have been created with them.
+	var sessions, tokens []string
+	err = q.WithTx(c.ctx, func(tx repository.Store) error {
+		err := tx.UpdatePassword(userID, hashed)
+		if err != nil {
+			return err
+		}
+		if sessions, err = tx.DeleteAllUserSessions(userID); err != nil {
+			return err
+		}
+		tokens, err = tx.DeleteAllAPITokens(userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "reset the password of %s, %d sessions signed out, %d API tokens revoked\n", *email, len(sessions), len(tokens))
+	return nil
+}
+
+func (c *command) userDisable(args []string) error {
+	fs := c.flags("user disable")
+	email := fs.String("email", "", "email address of the user (required)")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	q, userID, err := c.userByEmail(*email)
+	if err != nil {
+		return err
+	}
+	defer closeStore(q)
+
+	sessions, disabled, err := q.DisableUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !disabled {
+		fmt.Fprintf(c.out, "%s was already disabled, %d sessions signed out\n", *email, len(sessions))
+		return nil
+	}
+	fmt.Fprintf(c.out, "disabled %s, %d sessions signed out\n", *email, len(sessions))
+	return nil
+}
+
+func (c *command) userEnable(args []string) error {
+	fs := c.flags("user enable")
+	email := fs.String("email", "", "email address of the user (required)")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	q, userID, err := c.userByEmail(*email)
+	if err != nil {
+		return err
+	}
+	defer closeStore(q)
+
+	enabled, err := q.EnableUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		fmt.Fprintf(c.out, "%s is not disabled\n", *email)
+		return nil
+	}
+	fmt.Fprintf(c.out, "enabled %s\n", *email)
+	return nil
+}
+
+func (c *command) sessionPurge(args []string) error {
+	fs := c.flags("session purge")
+	email := fs.String("user", "", "email address of a user to sign out of every session")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *email != "" {
+		q, userID, err := c.userByEmail(*email)
+		if err != nil {
+			return err
+		}
+		defer closeStore(q)
+
+		sessions, err := q.DeleteAllUserSessions(userID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "deleted %d sessions of %s\n", len(sessions), *email)
+		return nil
+	}
+
+	q, err := c.openStore()
+	if err != nil {
+		return err
+	}
+	defer closeStore(q)
+
+	sessions, err := q.DeleteExpiredSessions()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "deleted %d expired sessions\n", len(sessions))
+	return nil
+}
@@ -2,21 +2,126 @@ package pkg
 
 import (
 	"database/sql"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
+	"social/pkg/config"
+	"social/pkg/db/postgres"
 	"social/pkg/db/sqlite"
+
+	"github.com/golang-migrate/migrate/v4"
 )
 
-func DBInstance() (*sql.DB, error) {
-	currentDir, err := os.Getwd()
+// DBInstance opens the configured database and applies the pending migrations.
+// Relative paths are resolved against the working directory.
+func DBInstance(cfg config.Database) (*sql.DB, error) {
+	migrations, err := migrationFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		dbPath, err := sqlitePath(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.InitDB(dbPath, migrations)
+	case config.DriverPostgres:
+		return postgres.InitDB(cfg.URL, migrations)
+	}
+	return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+}
+
+// Open opens the configured database without applying migrations.
+func Open(cfg config.Database) (*sql.DB, error) {
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		dbPath, err := sqlitePath(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.Open(dbPath)
+	case config.DriverPostgres:
+		return postgres.Open(cfg.URL)
+	}
+	return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+}
+
+// OpenReader opens the pool the queries that only read run on, so that they do
+// not wait for the single SQLite writer. It returns nil for PostgreSQL, whose
+// pool serves both.
+func OpenReader(cfg config.Database) (*sql.DB, error) {
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		dbPath, err := sqlitePath(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.OpenReader(dbPath)
+	case config.DriverPostgres:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+}
+
+// NewMigrator returns a migrator applying the configured migrations to conn,
+// a database opened with Open. Closing the migrator closes conn.
+func NewMigrator(cfg config.Database, conn *sql.DB) (*migrate.Migrate, error) {
+	migrations, err := migrationFiles(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	dbPath := filepath.Join(currentDir, "pkg", "db", "backend.db")
-	migrationsPath := filepath.Join(currentDir, "pkg", "db", "sqlite")
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		return sqlite.NewMigrator(conn, migrations)
+	case config.DriverPostgres:
+		return postgres.NewMigrator(conn, migrations)
+	}
+	return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+}
+
+// sqlitePath returns the absolute path of the SQLite database and creates its
+// directory, which SQLite does not do, so that a new data directory can be used.
+func sqlitePath(cfg config.Database) (string, error) {
+	dbPath, err := filepath.Abs(cfg.Path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return "", err
+	}
+	return dbPath, nil
+}
 
-	db, err := sqlite.InitDB(dbPath, migrationsPath)
-	return db, err
+// migrationFiles returns the migration files of the configured driver: the ones
+// embedded in the binary, or those in MigrationsPath when it is set.
+func migrationFiles(cfg config.Database) (fs.FS, error) {
+	if cfg.MigrationsPath != "" {
+		dir, err := filepath.Abs(cfg.MigrationsPath)
+		if err != nil {
+			return nil, err
+		}
+		return os.DirFS(dir), nil
+	}
+
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		return sqlite.Migrations, nil
+	case config.DriverPostgres:
+		return postgres.Migrations, nil
+	}
+	return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+}
+
+// LatestSchemaVersion returns the version of the newest configured migration.
+func LatestSchemaVersion(cfg config.Database) (uint, error) {
+	migrations, err := migrationFiles(cfg)
+	if err != nil {
+		return 0, err
+	}
+	return latestVersion(migrations)
 }
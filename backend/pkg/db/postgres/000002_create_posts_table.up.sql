@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS posts (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    group_id TEXT,
+    content TEXT,
+    likes_count INTEGER DEFAULT 0,
+    dislikes_count INTEGER DEFAULT 0,
+    comments_count INTEGER DEFAULT 0,
+    privacy TEXT CHECK(privacy IN ('public', 'almost_private', 'private')) NOT NULL DEFAULT 'public',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- the foreign key on group_id is added once the groups table exists, in 000004
@@ -0,0 +1,3 @@
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_group_id_fkey;
+
+DROP TABLE IF EXISTS groups;
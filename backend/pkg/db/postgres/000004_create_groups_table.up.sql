@@ -0,0 +1,14 @@
+-- Groups Table
+CREATE TABLE IF NOT EXISTS groups (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    title TEXT NOT NULL UNIQUE,
+    description TEXT,
+    creator_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- SQLite does not enforce this key, so deleting a group has never been blocked
+-- by its posts; cascade to keep it that way.
+ALTER TABLE posts ADD CONSTRAINT posts_group_id_fkey
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE;
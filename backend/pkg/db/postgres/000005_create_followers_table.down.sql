@@ -0,0 +1 @@
+DROP TABLE IF EXISTS user_follows;
\ No newline at end of file
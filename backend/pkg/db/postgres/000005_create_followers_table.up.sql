@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_follows (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    follower_id TEXT NOT NULL,
+    following_id TEXT NOT NULL,
+    status TEXT CHECK(status IN ('pending', 'accepted', 'declined')) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ,
+    UNIQUE (follower_id, following_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (following_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
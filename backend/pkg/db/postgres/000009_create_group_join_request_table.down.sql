@@ -0,0 +1 @@
+DROP TABLE IF EXISTS group_join_requests;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS group_join_requests (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    status TEXT CHECK(status IN ('pending', 'accepted', 'declined')) DEFAULT 'pending',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS event_attendance;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS event_attendance (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    status TEXT CHECK(status IN ('going', 'not_going')) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, user_id),
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
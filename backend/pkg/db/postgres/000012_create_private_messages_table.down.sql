@@ -0,0 +1 @@
+DROP TABLE IF EXISTS private_messages;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS group_messages (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    group_id TEXT NOT NULL,
+    sender_id TEXT NOT NULL,
+    content TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+);
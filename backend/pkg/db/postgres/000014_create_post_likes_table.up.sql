@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS post_likes (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    post_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    is_like BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (post_id, user_id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS comment_likes;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE events DROP COLUMN location;
\ No newline at end of file
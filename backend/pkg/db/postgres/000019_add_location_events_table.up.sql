@@ -0,0 +1 @@
+ALTER TABLE events ADD COLUMN location TEXT;
\ No newline at end of file
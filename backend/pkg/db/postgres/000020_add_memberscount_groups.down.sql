@@ -0,0 +1 @@
+ALTER TABLE groups DROP COLUMN members_count;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE groups ADD COLUMN members_count INTEGER DEFAULT 0;
\ No newline at end of file
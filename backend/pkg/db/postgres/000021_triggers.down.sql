@@ -0,0 +1,17 @@
+DROP TRIGGER IF EXISTS increment_likes_count ON post_likes;
+DROP TRIGGER IF EXISTS decrement_likes_count ON post_likes;
+DROP TRIGGER IF EXISTS increment_post_comments_count ON comments;
+DROP TRIGGER IF EXISTS decrement_post_comments_count ON comments;
+DROP TRIGGER IF EXISTS increment_comment_likes_count ON comment_likes;
+DROP TRIGGER IF EXISTS decrement_comment_likes_count ON comment_likes;
+DROP TRIGGER IF EXISTS increment_group_members_count ON group_members;
+DROP TRIGGER IF EXISTS decrement_group_members_count ON group_members;
+
+DROP FUNCTION IF EXISTS increment_likes_count();
+DROP FUNCTION IF EXISTS decrement_likes_count();
+DROP FUNCTION IF EXISTS increment_post_comments_count();
+DROP FUNCTION IF EXISTS decrement_post_comments_count();
+DROP FUNCTION IF EXISTS increment_comment_likes_count();
+DROP FUNCTION IF EXISTS decrement_comment_likes_count();
+DROP FUNCTION IF EXISTS increment_group_members_count();
+DROP FUNCTION IF EXISTS decrement_group_members_count();
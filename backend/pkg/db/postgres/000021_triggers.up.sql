@@ -0,0 +1,129 @@
+
+-- PostgreSQL triggers run a function, so each SQLite trigger becomes a
+-- function and a trigger of the same name.
+
+-- trigger for adding likes count on post
+CREATE FUNCTION increment_likes_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE posts
+    SET likes_count = likes_count + 1
+    WHERE id = NEW.post_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER increment_likes_count
+AFTER INSERT ON post_likes
+FOR EACH ROW
+WHEN (NEW.is_like)
+EXECUTE FUNCTION increment_likes_count();
+
+-- trigger for subtracting likes count on post
+CREATE FUNCTION decrement_likes_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE posts
+    SET likes_count = likes_count - 1
+    WHERE id = OLD.post_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER decrement_likes_count
+AFTER DELETE ON post_likes
+FOR EACH ROW
+WHEN (OLD.is_like)
+EXECUTE FUNCTION decrement_likes_count();
+
+-- trigger for comments count on post
+CREATE FUNCTION increment_post_comments_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE posts
+    SET comments_count = comments_count + 1
+    WHERE id = NEW.post_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER increment_post_comments_count
+AFTER INSERT ON comments
+FOR EACH ROW
+EXECUTE FUNCTION increment_post_comments_count();
+
+-- incase we are deleting comments
+CREATE FUNCTION decrement_post_comments_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE posts
+    SET comments_count = comments_count - 1
+    WHERE id = OLD.post_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER decrement_post_comments_count
+AFTER DELETE ON comments
+FOR EACH ROW
+EXECUTE FUNCTION decrement_post_comments_count();
+
+
+-- comments like counts
+CREATE FUNCTION increment_comment_likes_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE comments
+    SET likes_count = likes_count + 1
+    WHERE id = NEW.comment_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER increment_comment_likes_count
+AFTER INSERT ON comment_likes
+FOR EACH ROW
+WHEN (NEW.is_like)
+EXECUTE FUNCTION increment_comment_likes_count();
+
+
+CREATE FUNCTION decrement_comment_likes_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE comments
+    SET likes_count = likes_count - 1
+    WHERE id = OLD.comment_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER decrement_comment_likes_count
+AFTER DELETE ON comment_likes
+FOR EACH ROW
+WHEN (OLD.is_like)
+EXECUTE FUNCTION decrement_comment_likes_count();
+
+-- group members count increase
+CREATE FUNCTION increment_group_members_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE groups
+    SET members_count = members_count + 1
+    WHERE id = NEW.group_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER increment_group_members_count
+AFTER INSERT ON group_members
+FOR EACH ROW
+EXECUTE FUNCTION increment_group_members_count();
+
+
+
+CREATE FUNCTION decrement_group_members_count() RETURNS trigger AS $$
+BEGIN
+    UPDATE groups
+    SET members_count = members_count - 1
+    WHERE id = OLD.group_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER decrement_group_members_count
+AFTER DELETE ON group_members
+FOR EACH ROW
+EXECUTE FUNCTION decrement_group_members_count();
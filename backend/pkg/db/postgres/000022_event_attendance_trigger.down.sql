@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS trg_attendance_insert ON event_attendance;
+DROP TRIGGER IF EXISTS trg_attendance_update_decrement ON event_attendance;
+DROP TRIGGER IF EXISTS trg_attendance_update_increment ON event_attendance;
+
+DROP FUNCTION IF EXISTS trg_attendance_insert();
+DROP FUNCTION IF EXISTS trg_attendance_update_decrement();
+DROP FUNCTION IF EXISTS trg_attendance_update_increment();
+
+ALTER TABLE events DROP COLUMN going_count;
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN background_image;
\ No newline at end of file
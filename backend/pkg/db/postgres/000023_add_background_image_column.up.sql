@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN background_image TEXT;
\ No newline at end of file
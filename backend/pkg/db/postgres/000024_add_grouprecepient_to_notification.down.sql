@@ -0,0 +1 @@
+ALTER TABLE notifications DROP COLUMN recipient_group_id;
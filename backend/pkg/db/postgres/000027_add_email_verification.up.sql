@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMPTZ;
+
+-- accounts created before verification existed are trusted as they are
+UPDATE users SET verified_at = CURRENT_TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    email TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE mfa_challenges DROP COLUMN remember_me;
+ALTER TABLE sessions DROP COLUMN remember_me;
+ALTER TABLE sessions DROP COLUMN absolute_expires_at;
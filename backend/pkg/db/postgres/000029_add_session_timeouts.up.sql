@@ -0,0 +1,6 @@
+ALTER TABLE sessions ADD COLUMN absolute_expires_at TIMESTAMPTZ;
+ALTER TABLE sessions ADD COLUMN remember_me BOOLEAN DEFAULT FALSE;
+ALTER TABLE mfa_challenges ADD COLUMN remember_me BOOLEAN DEFAULT FALSE;
+
+-- existing sessions keep their current expiry and are not renewed
+UPDATE sessions SET absolute_expires_at = expires_at;
@@ -0,0 +1,11 @@
+-- archives of a user's personal data, built in the background on request
+CREATE TABLE IF NOT EXISTS data_exports (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    status TEXT CHECK(status IN ('pending', 'ready', 'failed')) NOT NULL DEFAULT 'pending',
+    file_path TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- the notifications fixed by the up migration cannot be told apart anymore
+SELECT 1;
@@ -0,0 +1,9 @@
+package postgres
+
+import "embed"
+
+// Migrations holds the migration files of the PostgreSQL schema, so that the binary
+// does not depend on the directory it is started from.
+//
+//go:embed *.sql
+var Migrations embed.FS
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"database/sql"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "github.com/lib/pq"
+)
+
+func InitDB(dsn string, migrations fs.FS) (*sql.DB, error) {
+	db, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewMigrator(db, migrations)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Open opens the database without applying migrations.
+func Open(dsn string) (*sql.DB, error) {
+	return sql.Open("postgres", dsn)
+}
+
+// NewMigrator returns a migrator applying the migration files at the root of
+// migrations to db. Closing the migrator closes db.
+func NewMigrator(db *sql.DB, migrations fs.FS) (*migrate.Migrate, error) {
+	src, err := iofs.New(migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("iofs", src, "postgres", driver)
+}
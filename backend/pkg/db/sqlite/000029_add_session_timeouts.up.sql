@@ -0,0 +1,6 @@
+ALTER TABLE sessions ADD COLUMN absolute_expires_at DATETIME;
+ALTER TABLE sessions ADD COLUMN remember_me BOOLEAN DEFAULT 0;
+ALTER TABLE mfa_challenges ADD COLUMN remember_me BOOLEAN DEFAULT 0;
+
+-- existing sessions keep their current expiry and are not renewed
+UPDATE sessions SET absolute_expires_at = expires_at;
@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN DEFAULT 0;
+
+-- failed logins, counted per account ("user:<id>") and per client address ("ip:<address>")
+CREATE TABLE IF NOT EXISTS login_attempts (
+    attempt_key TEXT PRIMARY KEY NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at DATETIME NOT NULL,
+    locked_until DATETIME
+);
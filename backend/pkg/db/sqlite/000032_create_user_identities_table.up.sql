@@ -0,0 +1,23 @@
+-- accounts at external OpenID Connect providers, linked to local users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (issuer, subject),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- sign-ins waiting for the provider to redirect back
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    id TEXT PRIMARY KEY NOT NULL UNIQUE,
+    state_hash TEXT NOT NULL UNIQUE,
+    provider TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    remember_me BOOLEAN DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
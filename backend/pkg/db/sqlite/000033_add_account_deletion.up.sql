@@ -0,0 +1,2 @@
+-- accounts are deleted once this time has passed, unless the user logs in before
+ALTER TABLE users ADD COLUMN deletion_scheduled_at DATETIME;
@@ -0,0 +1,2 @@
+-- disabled accounts cannot log in, set and cleared by the admin CLI
+ALTER TABLE users ADD COLUMN disabled_at DATETIME;
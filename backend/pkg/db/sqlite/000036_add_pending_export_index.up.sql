@@ -0,0 +1,4 @@
+-- a user has at most one export in preparation; exports left pending by a
+-- server that stopped are never finished
+UPDATE data_exports SET status = 'failed', completed_at = CURRENT_TIMESTAMP WHERE status = 'pending';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_pending ON data_exports(user_id) WHERE status = 'pending';
@@ -0,0 +1,3 @@
+-- group join notifications were stored with the actor "system", which is not a
+-- user: they become the user's own, like the other notifications about themselves
+UPDATE notifications SET actor_id = recipient_id WHERE actor_id = 'system';
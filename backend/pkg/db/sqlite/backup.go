@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// backupStepPages is how many pages are copied at a time, so that writers of
+// the source database are only held up briefly.
+const backupStepPages = 256
+
+// Backup copies the database db to the file at destPath with the SQLite online
+// backup API, which gives a consistent copy while the database is in use.
+// destPath must not exist yet.
+func Backup(ctx context.Context, db *sql.DB, destPath string) error {
+	dest, err := sql.Open("sqlite3", "file:"+destPath+"?mode=rwc")
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	return copyDatabase(ctx, dest, db)
+}
+
+// Restore replaces the content of db with the database in the file at srcPath,
+// after checking that the file is an intact SQLite database.
+func Restore(ctx context.Context, db *sql.DB, srcPath string) error {
+	src, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	var result string
+	if err := src.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("%s: %w", srcPath, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%s: integrity check failed: %s", srcPath, result)
+	}
+
+	return copyDatabase(ctx, db, src)
+}
+
+// copyDatabase copies the main database of src over the one of dest.
+func copyDatabase(ctx context.Context, dest, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver any) error {
+		return srcConn.Raw(func(srcDriver any) error {
+			d, ok := destDriver.(*sqlite3.SQLiteConn)
+			s, ok2 := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok || !ok2 {
+				return errors.New("backup: not a SQLite connection")
+			}
+
+			backup, err := d.Backup("main", s, "main")
+			if err != nil {
+				return fmt.Errorf("backup: %w", err)
+			}
+
+			for {
+				done, err := backup.Step(backupStepPages)
+				if err != nil && !isBusy(err) {
+					backup.Close()
+					return fmt.Errorf("backup: %w", err)
+				}
+				if done {
+					return backup.Finish()
+				}
+				select {
+				case <-ctx.Done():
+					backup.Close()
+					return ctx.Err()
+				case <-time.After(time.Millisecond):
+				}
+			}
+		})
+	})
+}
+
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
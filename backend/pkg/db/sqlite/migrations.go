@@ -0,0 +1,9 @@
+package sqlite
+
+import "embed"
+
+// Migrations holds the migration files of the SQLite schema, so that the binary
+// does not depend on the directory it is started from.
+//
+//go:embed *.sql
+var Migrations embed.FS
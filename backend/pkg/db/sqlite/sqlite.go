@@ -2,35 +2,153 @@ package sqlite
 
 import (
 	"database/sql"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"runtime"
+	"strconv"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func InitDB(dbPath string, migrationsPath string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+func InitDB(dbPath string, migrations fs.FS) (*sql.DB, error) {
+	db, err := Open(dbPath)
 	if err != nil {
 		return nil, err
 	}
 
-	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	m, err := NewMigrator(db, migrations)
 	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		db.Close()
 		return nil, err
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationsPath,
-		"sqlite3", driver,
-	)
+	reportForeignKeyViolations(db)
+	return db, nil
+}
+
+// ForeignKeyViolation is a row whose reference, to a row of Parent, is missing.
+type ForeignKeyViolation struct {
+	Table  string
+	RowID  int64
+	Parent string
+}
+
+// ForeignKeyViolations lists the rows with a dangling reference. Databases
+// created before foreign keys were enforced can hold some, which are kept but
+// can no longer be updated without fixing the reference.
+func ForeignKeyViolations(db *sql.DB) ([]ForeignKeyViolation, error) {
+	rows, err := db.Query("PRAGMA foreign_key_check")
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+	var violations []ForeignKeyViolation
+	for rows.Next() {
+		var v ForeignKeyViolation
+		var rowID sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&v.Table, &rowID, &v.Parent, &fkid); err != nil {
+			return nil, err
+		}
+		v.RowID = rowID.Int64
+		violations = append(violations, v)
+	}
+	return violations, rows.Err()
+}
+
+// reportForeignKeyViolations logs the rows with a dangling reference, so that
+// they can be fixed by hand.
+func reportForeignKeyViolations(db *sql.DB) {
+	violations, err := ForeignKeyViolations(db)
+	if err != nil {
+		slog.Error("failed to check foreign keys", "err", err)
+		return
+	}
+	for _, v := range violations {
+		slog.Warn("row references a missing row", "table", v.Table, "rowid", v.RowID, "parent", v.Parent)
+	}
+}
+
+const (
+	// busyTimeout is how long a connection waits for a lock held by another
+	// one, such as the writer of an admin command, before failing with
+	// "database is locked".
+	busyTimeout = 5 * time.Second
+
+	// minReaders is the least number of connections of the read pool, which
+	// otherwise has one per CPU.
+	minReaders = 4
+)
+
+// dsn returns the data source name of the database file with the settings
+// every connection opens with: WAL journaling so that readers and the writer
+// do not block each other, a busy timeout, foreign keys enforced, so that ON
+// DELETE CASCADE applies, and the NORMAL synchronous level, which is durable
+// with WAL except on power loss. extra adds connection settings.
+func dsn(dbPath string, extra url.Values) string {
+	params := url.Values{
+		"_journal_mode": {"WAL"},
+		"_busy_timeout": {strconv.FormatInt(busyTimeout.Milliseconds(), 10)},
+		"_foreign_keys": {"on"},
+		"_synchronous":  {"NORMAL"},
+	}
+	for key, values := range extra {
+		params[key] = values
+	}
+	return "file:" + dbPath + "?" + params.Encode()
+}
+
+// Open opens the database file without applying migrations. It is the write
+// pool: SQLite allows a single writer, so it has a single connection and its
+// transactions take the write lock when they begin, waiting for the busy
+// timeout instead of failing when another connection writes.
+func Open(dbPath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dsn(dbPath, url.Values{"_txlock": {"immediate"}}))
+	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxIdleTime(0)
+	return db, nil
+}
 
+// OpenReader opens a pool of read-only connections to the database file, which
+// WAL journaling lets read while the connection of Open writes.
+func OpenReader(dbPath string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dsn(dbPath, url.Values{"_query_only": {"on"}}))
+	if err != nil {
+		return nil, err
+	}
+	readers := max(minReaders, runtime.NumCPU())
+	db.SetMaxOpenConns(readers)
+	db.SetMaxIdleConns(readers)
 	return db, nil
 }
+
+// NewMigrator returns a migrator applying the migration files at the root of
+// migrations to db. Closing the migrator closes db.
+func NewMigrator(db *sql.DB, migrations fs.FS) (*migrate.Migrate, error) {
+	src, err := iofs.New(migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("iofs", src, "sqlite3", driver)
+}
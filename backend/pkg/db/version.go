@@ -0,0 +1,33 @@
+package pkg
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// latestVersion returns the version of the newest migration in migrations,
+// the version the database has once every migration is applied.
+func latestVersion(migrations fs.FS) (uint, error) {
+	src, err := iofs.New(migrations, ".")
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		} else if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"social/pkg/mail"
+	"social/pkg/util"
+)
+
+// AccountDeletionPolicy controls how long deleted accounts can still be recovered.
+type AccountDeletionPolicy struct {
+	// GracePeriod is how long after the request the account is actually deleted.
+	// Logging in during that time cancels the deletion.
+	GracePeriod time.Duration
+	// SweepInterval is how often accounts past their grace period are deleted.
+	SweepInterval time.Duration
+}
+
+type DeleteAccountData struct {
+	Password string `json:"password"`
+}
+
+// accountDeletion returns the account deletion policy of the configuration.
+func (app *App) accountDeletion() AccountDeletionPolicy {
+	cfg := app.Config.AccountDeletion
+	return AccountDeletionPolicy{
+		GracePeriod:   cfg.GracePeriod.Duration,
+		SweepInterval: cfg.SweepInterval.Duration,
+	}
+}
+
+// DeleteAccount schedules the deletion of the logged in user's account after
+// the grace period, once the password has been confirmed. The user is logged
+// out everywhere, and logging in again before the deadline cancels the deletion.
+func (app *App) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	var data DeleteAccountData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	if !app.confirmPassword(w, r, userID, data.Password, "Password is incorrect") {
+		return
+	}
+
+	deleteAt := time.Now().Add(app.accountDeletion().GracePeriod)
+	if err := app.Queries.ScheduleAccountDeletion(userID, deleteAt); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to schedule account deletion", Error)
+		return
+	}
+
+	sessionIDs, err := app.Queries.DeleteAllUserSessions(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Account deletion scheduled but failed to log out other sessions", Error)
+		return
+	}
+	tokenIDs, err := app.Queries.DeleteAllAPITokens(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Account deletion scheduled but failed to revoke API tokens", Error)
+		return
+	}
+	app.Hub.DisconnectSessions(append(sessionIDs, tokenIDs...)...)
+	util.ExpireSessionCookie(w, app.Config.Cookies.Options())
+
+	if email, err := app.Queries.FetchUserEmail(userID); err == nil {
+		app.background(func() {
+			err := app.Mailer.Send(mail.Message{
+				To:      email,
+				Subject: "Your account will be deleted",
+				Body: "We received a request to delete your account. It will be permanently deleted on " +
+					deleteAt.UTC().Format("2 January 2006 at 15:04 UTC") + ".\n\n" +
+					"If you change your mind, simply log in before then to cancel the deletion.",
+			})
+			if err != nil {
+				slog.Error("failed to send account deletion email", "user_id", userID, "err", err)
+			}
+		})
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, map[string]any{
+		"message":   "Account scheduled for deletion. Log in before the deadline to cancel.",
+		"delete_at": deleteAt,
+	}, Data)
+}
+
+// DeleteScheduledAccounts deletes the accounts whose grace period has ended every
+// SweepInterval, along with their uploaded files and data exports. It returns
+// when ctx is cancelled.
+func (app *App) DeleteScheduledAccounts(ctx context.Context) {
+	ticker := time.NewTicker(app.accountDeletion().SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			userIDs, err := app.Queries.FetchAccountsDueForDeletion(time.Now())
+			if err != nil {
+				slog.Error("account deletion", "err", err)
+				continue
+			}
+
+			for _, userID := range userIDs {
+				files, err := app.Queries.DeleteAccount(userID)
+				if err != nil {
+					slog.Error("account deletion failed", "user_id", userID, "err", err)
+					continue
+				}
+
+				removeFiles(files)
+				if err := os.RemoveAll(filepath.Join(exportDir(), userID)); err != nil {
+					slog.Error("account deletion: failed to remove data exports", "user_id", userID, "err", err)
+				}
+				slog.Info("account deleted", "user_id", userID)
+			}
+		}
+	}
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 
+	"social/pkg/repository"
 	"social/pkg/util"
 )
 
@@ -34,35 +37,42 @@ func (app *App) AddGroup(w http.ResponseWriter, r *http.Request) {
 
 	groupId := util.UUIDGen()
 
-	err = app.Queries.InsertData("groups", []string{
-		"id",
-		"title",
-		"description",
-		"creator_id",
-	}, []any{
-		groupId,
-		addGroupData.Title,
-		addGroupData.Description,
-		userID,
-	})
-	if err != nil {
-		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to create group", Error)
-		return
-	}
+	// A group is never left without its admin membership.
+	err = app.Queries.WithTx(r.Context(), func(tx repository.Store) error {
+		err := tx.InsertData("groups", []string{
+			"id",
+			"title",
+			"description",
+			"creator_id",
+		}, []any{
+			groupId,
+			addGroupData.Title,
+			addGroupData.Description,
+			userID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create group: %w", err)
+		}
 
-	err = app.Queries.InsertData("group_members", []string{
-		"id",
-		"group_id",
-		"user_id",
-		"role",
-	}, []any{
-		util.UUIDGen(),
-		groupId,
-		userID,
-		"admin",
+		err = tx.InsertData("group_members", []string{
+			"id",
+			"group_id",
+			"user_id",
+			"role",
+		}, []any{
+			util.UUIDGen(),
+			groupId,
+			userID,
+			"admin",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add group admin: %w", err)
+		}
+		return nil
 	})
 	if err != nil {
-		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to add group member", Error)
+		slog.ErrorContext(r.Context(), "failed to create group", "err", err)
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to create group", Error)
 		return
 	}
 	app.JSONResponse(w, r, http.StatusOK, "Group created successfully", Success)
@@ -3,11 +3,14 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
 
+	"social/pkg/repository"
 	"social/pkg/util"
 )
 
@@ -33,6 +36,7 @@ func (app *App) AddPost(w http.ResponseWriter, r *http.Request) {
 	postId := util.UUIDGen()
 
 	files := r.MultipartForm.File["media"]
+	var mediaPaths []string
 
 	// If file was provided, process it
 	if len(files) > 0 {
@@ -78,7 +82,7 @@ func (app *App) AddPost(w http.ResponseWriter, r *http.Request) {
 			case "image/gif":
 				path, err = util.CompressGIF(tempFilePath, true)
 			default:
-				path = "pkg/db/media/" + header.Filename
+				path = util.MediaPath + "/" + header.Filename
 			}
 
 			if err != nil {
@@ -86,19 +90,7 @@ func (app *App) AddPost(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			err = app.Queries.InsertData("media", []string{
-				"id",
-				"url",
-				"parent_id",
-			}, []any{
-				util.UUIDGen(),
-				path,
-				postId,
-			})
-			if err != nil {
-				app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to insert media into database", Error)
-				return
-			}
+			mediaPaths = append(mediaPaths, path)
 		}
 	}
 
@@ -143,24 +135,7 @@ func (app *App) AddPost(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = app.Queries.InsertData("posts", []string{
-		"id",
-		"user_id",
-		"content",
-		"privacy",
-		"group_id",
-	}, []any{
-		postId,
-		userID,
-		content,
-		privacy,
-		GroupID,
-	})
-	if err != nil {
-		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to insert post into database", Error)
-		return
-	}
-
+	var visibleTo []string
 	if privacy == "private" {
 		raw := r.FormValue("visible_to")
 		var userIDs []string
@@ -185,8 +160,46 @@ func (app *App) AddPost(w http.ResponseWriter, r *http.Request) {
 				app.JSONResponse(w, r, http.StatusBadRequest, "Invalid user ID in visible_to field", Error)
 				return
 			}
+			visibleTo = append(visibleTo, id)
+		}
+	}
 
-			err = app.Queries.InsertData("post_visibility", []string{
+	// The post, its media and its audience are saved together or not at all.
+	err = app.Queries.WithTx(r.Context(), func(tx repository.Store) error {
+		err := tx.InsertData("posts", []string{
+			"id",
+			"user_id",
+			"content",
+			"privacy",
+			"group_id",
+		}, []any{
+			postId,
+			userID,
+			content,
+			privacy,
+			GroupID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert post: %w", err)
+		}
+
+		for _, path := range mediaPaths {
+			err = tx.InsertData("media", []string{
+				"id",
+				"url",
+				"parent_id",
+			}, []any{
+				util.UUIDGen(),
+				path,
+				postId,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to insert media: %w", err)
+			}
+		}
+
+		for _, id := range visibleTo {
+			err = tx.InsertData("post_visibility", []string{
 				"id",
 				"post_id",
 				"user_id",
@@ -196,10 +209,15 @@ func (app *App) AddPost(w http.ResponseWriter, r *http.Request) {
 				id,
 			})
 			if err != nil {
-				app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to insert post visibility into database", Error)
-				return
+				return fmt.Errorf("failed to insert post visibility: %w", err)
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to add post", "err", err)
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to insert post into database", Error)
+		return
 	}
 
 	app.JSONResponse(w, r, http.StatusOK, "Post added successfully", Success)
@@ -1,14 +1,28 @@
 package handler
 
 import (
-	"database/sql"
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
+
+	"social/pkg/logging"
+	"social/pkg/repository"
+	"social/pkg/util"
+
+	"github.com/gorilla/websocket"
 )
 
-// AuthMiddleware validates the session and CSRF token
-func (app App) AuthMiddleware(next http.Handler) http.Handler {
+// AuthMiddleware validates the session and CSRF token, or the personal access
+// token sent in an "Authorization: Bearer" header.
+func (app *App) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			app.authenticateToken(w, r, next, token)
+			return
+		}
+
 		// Extract cookies
 		sessionCookie, err := r.Cookie("session_id")
 		if err != nil {
@@ -23,13 +37,8 @@ func (app App) AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Check session validity in the database
-		var expiresAt time.Time
-		err = app.Queries.Db.QueryRow(`
-			SELECT expires_at FROM sessions 
-			WHERE session_token = ? AND csrf_token = ?`,
-			sessionCookie.Value, csrfToken.Value).Scan(&expiresAt)
-
-		if err == sql.ErrNoRows {
+		session, err := app.Queries.FetchSessionAuth(sessionCookie.Value, csrfToken.Value)
+		if errors.Is(err, repository.ErrSessionNotFound) {
 			app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized: session not found", Error)
 			return
 		} else if err != nil {
@@ -37,16 +46,86 @@ func (app App) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if time.Now().After(expiresAt) {
+		now := time.Now()
+		if now.After(session.ExpiresAt) {
 			app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized: session expired", Error)
 			return
 		}
+		logging.SetUserID(r.Context(), session.UserID)
+
+		// Browsers attach both cookies to cross-site requests on their own, so state-changing
+		// requests must also echo the token, which only our own pages can read.
+		if requiresCSRFToken(r) && !validCSRFToken(r, session.CSRFToken) {
+			app.JSONResponse(w, r, http.StatusForbidden, "Forbidden: invalid CSRF token", Error)
+			return
+		}
+
+		if !session.Verified && !readOnlyAllowed(r) {
+			app.JSONResponse(w, r, http.StatusForbidden, "Email address not verified", Error)
+			return
+		}
+
+		// Sessions created before absolute timeouts existed are not renewed.
+		if session.AbsoluteExpiresAt != nil {
+			if renewed, ok := app.sessionPolicy().renewal(now, session.ExpiresAt, *session.AbsoluteExpiresAt, session.RememberMe); ok {
+				if err := app.Queries.RenewSession(sessionCookie.Value, renewed); err == nil {
+					util.RefreshSessionCookie(w, app.Config.Cookies.Options(), sessionCookie.Value, session.CSRFToken, cookieExpiry(renewed, session.RememberMe))
+				}
+			}
+		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// requiresCSRFToken reports whether the request changes state and must carry the CSRF token.
+// Websocket upgrades are GET requests but open a channel that changes state.
+func requiresCSRFToken(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return websocket.IsWebSocketUpgrade(r)
+}
+
+// validCSRFToken compares the token sent in the X-CSRF-Token header with the one
+// stored for the session. Websocket clients cannot set headers and pass it as the
+// csrf_token query parameter instead, which is accepted for upgrades only: in other
+// URLs it would end up in logs, history and Referer headers.
+func validCSRFToken(r *http.Request, expected string) bool {
+	token := r.Header.Get("X-CSRF-Token")
+	if token == "" && websocket.IsWebSocketUpgrade(r) {
+		token = r.URL.Query().Get("csrf_token")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// readOnlyRoutes are the non-GET routes an account with an unverified email may still use.
+// Everyone may get a copy of their own data.
+var readOnlyRoutes = map[string]bool{
+	"POST /api/v1/logout":     true,
+	"POST /api/v1/me/exports": true,
+}
+
+// readOnlyAllowed reports whether an account with an unverified email may make the request.
+// Such accounts can read data and log out, but cannot change anything. The
+// legacy routes reading data with POST, such as getProfile, are replaced by GET routes.
+func readOnlyAllowed(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	pattern := routePattern(r)
+	return strings.HasPrefix(pattern, http.MethodGet+" ") || readOnlyRoutes[pattern]
+}
+
+// GetSessionData returns the id of the user making the request, identified by
+// the session cookie or by a personal access token.
 func (app *App) GetSessionData(r *http.Request) (string, error) {
+	if token, ok := bearerToken(r); ok {
+		return app.Queries.FetchAPITokenUser(util.HashToken(token))
+	}
+
 	sessionCookie, err := r.Cookie("session_id")
 	if err != nil {
 		return "", err
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+)
+
+// background runs fn in a new goroutine that Wait waits for, so that emails
+// and data exports in progress are finished before the server exits.
+func (app *App) background(fn func()) {
+	app.tasks.Add(1)
+	go func() {
+		defer app.tasks.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until the background work has finished or ctx is done.
+func (app *App) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		app.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunJobs starts the periodic jobs. They stop when ctx is cancelled, and Wait
+// waits for the current run to finish. It is called before the server accepts
+// requests, and first fails the data exports a previous run left pending.
+func (app *App) RunJobs(ctx context.Context) {
+	if err := app.Queries.FailPendingExports(); err != nil {
+		slog.Error("failed to reset pending data exports", "err", err)
+	}
+	app.background(func() { app.SweepSessions(ctx) })
+	app.background(func() { app.DeleteScheduledAccounts(ctx) })
+	app.background(func() { app.PurgeDataExports(ctx) })
+}
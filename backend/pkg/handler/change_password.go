@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"social/pkg/util"
+)
+
+type ChangePasswordData struct {
+	CurrentPassword     string `json:"current_password"`
+	NewPassword         string `json:"new_password"`
+	ConfirmedPassword   string `json:"confirmed_password"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+}
+
+// ChangePassword replaces the password of the logged in user after checking the
+// current one. When asked to, it also logs out every other session of the user.
+func (app *App) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized: session cookie missing", Error)
+		return
+	}
+
+	var data ChangePasswordData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	if !app.confirmPassword(w, r, userID, data.CurrentPassword, "Current password is incorrect") {
+		return
+	}
+
+	if err := util.CheckPasswordStrength(data.NewPassword); err != nil {
+		app.JSONResponse(w, r, http.StatusNotAcceptable, err.Error(), Error)
+		return
+	}
+	if data.NewPassword != data.ConfirmedPassword {
+		app.JSONResponse(w, r, http.StatusNotAcceptable, "Passwords do not match.", Error)
+		return
+	}
+	if data.NewPassword == data.CurrentPassword {
+		app.JSONResponse(w, r, http.StatusNotAcceptable, "New password must be different from the current one.", Error)
+		return
+	}
+
+	hashed, err := util.EncryptPassword(data.NewPassword)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	if err := app.Queries.UpdatePassword(userID, hashed); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to update password", Error)
+		return
+	}
+
+	revoked := 0
+	if data.RevokeOtherSessions {
+		sessionIDs, err := app.Queries.DeleteOtherSessions(userID, sessionCookie.Value)
+		if err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Password changed but failed to revoke other sessions", Error)
+			return
+		}
+		app.Hub.DisconnectSessions(sessionIDs...)
+		revoked = len(sessionIDs)
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, map[string]any{
+		"message":          "Password changed successfully",
+		"revoked_sessions": revoked,
+	}, Data)
+}
@@ -30,7 +30,11 @@ func (app *App) AddComment(w http.ResponseWriter, r *http.Request) {
 
 	comment := Comment{}
 
-	comment.PostId = r.FormValue("post_id")
+	// The legacy /api/addComment route sends the post as a form field.
+	comment.PostId = r.PathValue("id")
+	if comment.PostId == "" {
+		comment.PostId = r.FormValue("post_id")
+	}
 	comment.Content = r.FormValue("content")
 	comment.CommentId = r.FormValue("comment_id")
 
@@ -85,7 +89,7 @@ func (app *App) AddComment(w http.ResponseWriter, r *http.Request) {
 			case "image/gif":
 				path, err = util.CompressGIF(tempFilePath, true)
 			default:
-				path = "pkg/db/media/" + header.Filename
+				path = util.MediaPath + "/" + header.Filename
 			}
 
 			if err != nil {
@@ -5,14 +5,15 @@ import (
 	"strings"
 )
 
+// WithCORS allows credentialed cross-origin requests from the configured origins.
 func (app *App) WithCORS(handler http.Handler) http.Handler {
-	allowedOrigins := []string{"http://localhost", "http://localhost:3000"}
+	allowedOrigins := app.Config.CORS.AllowedOrigins
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
 		if isOriginAllowed(origin, allowedOrigins) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
 
@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"social/pkg/model"
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+// exportDir returns the directory of the built archives, which holds one
+// directory per user.
+func exportDir() string {
+	return util.DataPath("exports")
+}
+
+// dataExportTTL is how long an archive can be downloaded before it is deleted.
+const dataExportTTL = 7 * 24 * time.Hour
+
+// RequestDataExport starts building an archive of the logged in user's data.
+// The user is notified over the websocket once it can be downloaded.
+// Requesting a new export deletes the previous ones.
+func (app *App) RequestDataExport(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	exportID, err := app.Queries.CreateDataExport(userID)
+	if errors.Is(err, repository.ErrExportPending) {
+		app.JSONResponse(w, r, http.StatusConflict, err.Error(), Error)
+		return
+	} else if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to request export", Error)
+		return
+	}
+
+	app.background(func() { app.buildDataExport(userID, exportID) })
+
+	app.JSONResponse(w, r, http.StatusAccepted, map[string]any{
+		"id":     exportID,
+		"status": "pending",
+	}, Data)
+}
+
+// DataExports lists the exports of the logged in user and their status.
+func (app *App) DataExports(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	exports, err := app.Queries.FetchDataExports(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to fetch exports", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, exports, Success)
+}
+
+// DownloadDataExport sends the archive given by the id path parameter, or the
+// id query parameter of the legacy /api/downloadDataExport route.
+func (app *App) DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	exportID := r.PathValue("id")
+	if exportID == "" {
+		exportID = r.URL.Query().Get("id")
+	}
+	export, err := app.Queries.FetchDataExport(userID, exportID)
+	if errors.Is(err, repository.ErrExportNotFound) {
+		app.JSONResponse(w, r, http.StatusNotFound, err.Error(), Error)
+		return
+	} else if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to fetch export", Error)
+		return
+	}
+
+	if export.Status != "ready" || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		app.JSONResponse(w, r, http.StatusNotFound, "Export is not available", Error)
+		return
+	}
+
+	file, err := os.Open(export.FilePath)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusNotFound, "Export is not available", Error)
+		return
+	}
+	defer file.Close()
+
+	name := fmt.Sprintf("social-network-export-%s.zip", export.CreatedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, *export.CompletedAt, file)
+}
+
+// buildDataExport writes the archive of a user's data and notifies the user.
+func (app *App) buildDataExport(userID, exportID string) {
+	// Only the new archive is kept.
+	if files, err := app.Queries.DeleteFinishedExports(userID); err == nil {
+		removeFiles(files)
+	}
+
+	path := filepath.Join(exportDir(), userID, exportID+".zip")
+	if err := app.writeDataExport(userID, path); err != nil {
+		slog.Error("data export failed", "export_id", exportID, "err", err)
+		os.Remove(path)
+		if err := app.Queries.FailDataExport(exportID); err != nil {
+			slog.Error("data export", "export_id", exportID, "err", err)
+		}
+		return
+	}
+
+	if err := app.Queries.CompleteDataExport(exportID, path, time.Now().Add(dataExportTTL)); err != nil {
+		slog.Error("data export", "export_id", exportID, "err", err)
+		return
+	}
+
+	const message = "Your data export is ready to download."
+	err := app.Queries.InsertData("notifications",
+		[]string{"id", "recipient_id", "actor_id", "type", "entity_id", "entity_type", "message"},
+		[]any{util.UUIDGen(), userID, userID, "data_export_ready", exportID, "data_export", message},
+	)
+	if err != nil {
+		slog.Error("data export: failed to store notification", "export_id", exportID, "err", err)
+	}
+
+	app.Hub.InfoBasedNotification([]string{userID}, map[string]any{
+		"type":         "data_export_ready",
+		"export_id":    exportID,
+		"message":      message,
+		"download_url": "/api/v1/me/exports/" + exportID,
+	})
+}
+
+// writeDataExport collects the user's data into a ZIP archive at path. Every
+// section is a JSON file, uploaded files are copied into media/.
+func (app *App) writeDataExport(userID, path string) error {
+	user, err := app.Queries.FetchUserData(userID)
+	if err != nil {
+		return err
+	}
+	memberships, err := app.Queries.FetchGroupMemberships(userID)
+	if err != nil {
+		return err
+	}
+	events, err := app.Queries.FetchEventResponses(userID)
+	if err != nil {
+		return err
+	}
+	groupMessages, err := app.Queries.FetchSentGroupMessages(userID)
+	if err != nil {
+		return err
+	}
+	notifications, err := app.Queries.GetUserNotifications(userID)
+	if err != nil {
+		return err
+	}
+	mediaFiles, err := app.Queries.FetchUserMediaFiles(userID)
+	if err != nil {
+		return err
+	}
+
+	partners, err := app.Queries.FetchConversationPartners(userID)
+	if err != nil {
+		return err
+	}
+	privateMessages := map[string][]model.PrivateMessage{}
+	for _, partnerID := range partners {
+		messages, err := app.Queries.GetMessagesBetweenUsers(userID, partnerID)
+		if err != nil {
+			return err
+		}
+		privateMessages[partnerID] = messages
+	}
+
+	sections := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", map[string]any{
+			"id":               user.ID,
+			"email":            user.Email,
+			"first_name":       user.FirstName,
+			"last_name":        user.LastName,
+			"nickname":         user.Nickname,
+			"date_of_birth":    user.DateOfBirth,
+			"about_me":         user.AboutMe,
+			"avatar":           user.Avatar,
+			"background_image": user.BackgroundImage,
+			"is_public":        user.IsPublic,
+			"created_at":       user.CreatedAt,
+		}},
+		{"posts.json", user.Post},
+		{"comments.json", user.Comments},
+		{"likes.json", map[string]any{
+			"posts":    user.LikedPost,
+			"comments": user.LikedComments,
+		}},
+		{"followers.json", user.Followers},
+		{"following.json", user.Following},
+		{"groups.json", memberships},
+		{"events.json", events},
+		{"messages/private.json", privateMessages},
+		{"messages/groups.json", groupMessages},
+		{"notifications.json", notifications},
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for _, section := range sections {
+		entry, err := zw.Create(section.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.data); err != nil {
+			return fmt.Errorf("%s: %w", section.name, err)
+		}
+	}
+
+	for _, file := range mediaFiles {
+		if err := addFileToZip(zw, "media/"+filepath.Base(file), util.MediaFile(file)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func addFileToZip(zw *zip.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entry, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+// PurgeDataExports deletes the archives past their expiry every hour.
+// It returns when ctx is cancelled.
+func (app *App) PurgeDataExports(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			files, err := app.Queries.DeleteExpiredExports(time.Now())
+			if err != nil {
+				slog.Error("data export purge", "err", err)
+				continue
+			}
+			removeFiles(files)
+		}
+	}
+}
+
+// removeFiles deletes archives and uploads, given by the paths they are recorded under.
+func removeFiles(files []string) {
+	for _, file := range files {
+		if err := os.Remove(util.MediaFile(file)); err != nil && !os.IsNotExist(err) {
+			slog.Error("failed to remove file", "file", file, "err", err)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"social/pkg/repository"
 )
 
 type DeleteGroup struct {
@@ -17,9 +20,21 @@ func (app *App) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	groupDetail := DeleteGroup{}
-	if err := json.NewDecoder(r.Body).Decode(&groupDetail); err != nil {
-		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
-		return
+	if groupID := r.PathValue("id"); groupID != "" {
+		groupDetail.Title, err = app.Queries.FetchGroupTitle(groupID)
+		if errors.Is(err, repository.ErrGroupNotFound) {
+			app.JSONResponse(w, r, http.StatusNotFound, err.Error(), Error)
+			return
+		} else if err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Error fetching group", Error)
+			return
+		}
+	} else {
+		// The legacy /api/deleteGroup route sends the group title in the body.
+		if err := json.NewDecoder(r.Body).Decode(&groupDetail); err != nil {
+			app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+			return
+		}
 	}
 
 	err = app.Queries.DeleteGroup(groupDetail.Title, userID)
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"social/pkg/config"
+	"social/pkg/mail"
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+// UnverifiedPolicy decides what an account with an unverified email may do.
+type UnverifiedPolicy string
+
+const (
+	// UnverifiedBlock refuses to log unverified accounts in.
+	UnverifiedBlock UnverifiedPolicy = config.UnverifiedBlock
+	// UnverifiedReadOnly logs unverified accounts in but rejects every state-changing request.
+	UnverifiedReadOnly UnverifiedPolicy = config.UnverifiedReadOnly
+)
+
+// unverifiedPolicy returns the policy of the configuration for unverified accounts.
+func (app *App) unverifiedPolicy() UnverifiedPolicy {
+	return UnverifiedPolicy(app.Config.Login.UnverifiedPolicy)
+}
+
+type VerifyEmailData struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail confirms the email address a verification link was sent to.
+func (app *App) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var data VerifyEmailData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Token == "" {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	_, err := app.Queries.VerifyEmail(util.HashToken(data.Token))
+	if errors.Is(err, repository.ErrInvalidVerificationToken) {
+		app.JSONResponse(w, r, http.StatusBadRequest, err.Error(), Error)
+		return
+	} else if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, "Email verified successfully", Success)
+}
+
+// ResendVerification sends a new verification link to an unverified account.
+// The response is the same whether or not the account exists.
+func (app *App) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var data PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	const response = "If this email belongs to an unverified account, a verification link has been sent"
+
+	email := strings.TrimSpace(data.Email)
+	userID, err := app.Queries.FetchUserIDByEmail(email)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusOK, response, Success)
+		return
+	}
+
+	verified, err := app.Queries.IsEmailVerified(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	if !verified {
+		if err := app.sendVerificationEmail(userID, email); err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+			return
+		}
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, response, Success)
+}
+
+// sendVerificationEmail stores a new verification token for the address and
+// emails the verification link in the background.
+func (app *App) sendVerificationEmail(userID, email string) error {
+	token, err := util.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	err = app.Queries.CreateEmailVerification(userID, email, util.HashToken(token), time.Now().Add(emailVerificationTTL))
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s",
+		app.frontendURL(), url.QueryEscape(token))
+
+	app.background(func() {
+		err := app.Mailer.Send(mail.Message{
+			To:      email,
+			Subject: "Verify your email address",
+			Body: "Please confirm that this is your email address by opening the link below:\n" +
+				link + "\n\n" +
+				"The link is valid for 48 hours.",
+		})
+		if err != nil {
+			slog.Error("failed to send verification email", "user_id", userID, "err", err)
+		}
+	})
+
+	return nil
+}
@@ -9,16 +9,9 @@ type GroupTitle struct {
 	Title string `json:"title"`
 }
 
-// GetGroupData handles the request to fetch group data based on the group title.
-// It uses query parameters to get the group title, fetches the group ID,
+// GetGroupData handles the request to fetch group data based on the group id
+// in the path, or on the title query parameter of the legacy /api/getGroupData route.
 func (app *App) GetGroupData(w http.ResponseWriter, r *http.Request) {
-	// Get group title from query parameters
-	groupTitle := r.URL.Query().Get("title")
-	if groupTitle == "" {
-		app.JSONResponse(w, r, http.StatusBadRequest, "Group title is required", Error)
-		return
-	}
-
 	// Get user ID from session
 	userID, err := app.GetSessionData(r)
 	if err != nil {
@@ -26,10 +19,20 @@ func (app *App) GetGroupData(w http.ResponseWriter, r *http.Request) {
 		userID = ""
 	}
 
-	id, err := app.Queries.FetchGroupId(groupTitle)
-	if err != nil {
-		app.JSONResponse(w, r, http.StatusConflict, "Error fetching group ID", Error)
-		return
+	id := r.PathValue("id")
+	if id == "" {
+		// Get group title from query parameters
+		groupTitle := r.URL.Query().Get("title")
+		if groupTitle == "" {
+			app.JSONResponse(w, r, http.StatusBadRequest, "Group title is required", Error)
+			return
+		}
+
+		id, err = app.Queries.FetchGroupId(groupTitle)
+		if err != nil {
+			app.JSONResponse(w, r, http.StatusConflict, "Error fetching group ID", Error)
+			return
+		}
 	}
 
 	groupData, err := app.Queries.FetchGroupData(id, userID)
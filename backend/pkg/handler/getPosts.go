@@ -1,25 +1,50 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
+
+	"social/pkg/repository"
 )
 
 func (app *App) GetPosts(w http.ResponseWriter, r *http.Request) {
 	// fetch userif to filter the post
 	userID, err := app.GetSessionData(r)
 	if err != nil {
-		fmt.Println(err)
+		slog.WarnContext(r.Context(), "unauthenticated posts request", "err", err)
 		app.JSONResponse(w, r, http.StatusUnauthorized, "Getpost: unathorized ", Error)
 		return
 	}
 
 	posts, err := app.Queries.FetchAllPosts(userID)
 	if err != nil {
-		fmt.Println(err)
+		slog.ErrorContext(r.Context(), "failed to fetch posts", "err", err)
 		app.JSONResponse(w, r, http.StatusInternalServerError, "Error fetching posts", Error)
 		return
 	}
 
 	app.JSONResponse(w, r, http.StatusOK, posts, Data)
 }
+
+// GetPost returns a post the user may see, following the privacy rules of the
+// feed, or the membership of its group for group posts.
+func (app *App) GetPost(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	post, err := app.Queries.FetchPost(r.PathValue("id"), userID)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		app.JSONResponse(w, r, http.StatusNotFound, "Post not found", Error)
+		return
+	} else if err != nil {
+		slog.ErrorContext(r.Context(), "failed to fetch post", "err", err)
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Error fetching post", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, post, Data)
+}
@@ -2,7 +2,7 @@ package handler
 
 import (
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 )
 
@@ -13,15 +13,18 @@ type UserData struct {
 func (app *App) GetProfile(w http.ResponseWriter, r *http.Request) {
 	userID, err := app.GetSessionData(r)
 	if err != nil {
-		fmt.Println(err)
+		slog.WarnContext(r.Context(), "unauthenticated profile request", "err", err)
 		app.JSONResponse(w, r, http.StatusUnauthorized, "Get Profile: unathorized ", Error)
 		return
 	}
 
-	queryId := UserData{}
-	if err := json.NewDecoder(r.Body).Decode(&queryId); err != nil {
-		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
-		return
+	// The legacy /api/getProfile route sends the user in the body.
+	queryId := UserData{UserID: r.PathValue("id")}
+	if queryId.UserID == "" {
+		if err := json.NewDecoder(r.Body).Decode(&queryId); err != nil {
+			app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+			return
+		}
 	}
 
 	isPublic, err := app.Queries.CheckRow("users", []string{
@@ -55,7 +58,7 @@ func (app *App) GetProfile(w http.ResponseWriter, r *http.Request) {
 
 	userData, err := app.Queries.FetchUserData(queryId.UserID)
 	if err != nil {
-		fmt.Println("Error fetching user data:", err)
+		slog.ErrorContext(r.Context(), "failed to fetch user data", "err", err)
 		app.JSONResponse(w, r, http.StatusInternalServerError, "failed to fetch user data", Error)
 		return
 	}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+// readinessTimeout bounds each readiness check.
+const readinessTimeout = 2 * time.Second
+
+// healthCheck is the result of a readiness check. The endpoint is public, so
+// why a check failed is only logged.
+type healthCheck struct {
+	Status string `json:"status"`
+}
+
+// Healthz reports that the process is up and serving requests.
+func (app *App) Healthz(w http.ResponseWriter, r *http.Request) {
+	app.JSONResponse(w, r, http.StatusOK, map[string]string{"status": "ok"}, Data)
+}
+
+// Readyz reports whether the server can handle traffic: the database answers
+// and is fully migrated, uploads can be stored and the websocket hub is running.
+// It answers 503 when any check fails.
+func (app *App) Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]func(ctx context.Context) error{
+		"database":      app.checkDatabase,
+		"migrations":    app.checkMigrations,
+		"media":         checkMediaWritable,
+		"websocket_hub": app.Hub.Ping,
+	}
+
+	status := http.StatusOK
+	results := make(map[string]healthCheck, len(checks))
+	for name, check := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		err := check(ctx)
+		cancel()
+
+		if err != nil {
+			slog.WarnContext(r.Context(), "readiness check failed", "check", name, "err", err)
+			status = http.StatusServiceUnavailable
+			results[name] = healthCheck{Status: "fail"}
+		} else {
+			results[name] = healthCheck{Status: "ok"}
+		}
+	}
+
+	overall := "ok"
+	if status != http.StatusOK {
+		overall = "unavailable"
+	}
+	app.JSONResponse(w, r, status, map[string]any{"status": overall, "checks": results}, Data)
+}
+
+func (app *App) checkDatabase(ctx context.Context) error {
+	if app.Queries == nil {
+		return repository.ErrNoDatabase
+	}
+	return app.Queries.Ping(ctx)
+}
+
+func (app *App) checkMigrations(ctx context.Context) error {
+	if app.Queries == nil {
+		return repository.ErrNoDatabase
+	}
+	version, dirty, err := app.Queries.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed and must be fixed by hand", version)
+	}
+	if version != app.SchemaVersion {
+		return fmt.Errorf("database is at version %d, expected %d", version, app.SchemaVersion)
+	}
+	return nil
+}
+
+// checkMediaWritable creates and removes a file in the upload directory. A
+// stalled file system fails the check once ctx is done.
+func checkMediaWritable(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() { done <- writeMediaProbe() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeMediaProbe() error {
+	if err := os.MkdirAll(util.MediaDir(), 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(util.MediaDir(), ".readyz-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
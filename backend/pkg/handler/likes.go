@@ -19,11 +19,14 @@ func (app *App) LikeComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	like := Like{}
+	like := Like{CommentId: r.PathValue("id")}
 
-	if err = json.NewDecoder(r.Body).Decode(&like); err != nil {
-		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
-		return
+	// The legacy /api/likeComment route sends the comment in the body.
+	if like.CommentId == "" {
+		if err = json.NewDecoder(r.Body).Decode(&like); err != nil {
+			app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+			return
+		}
 	}
 
 	if like.CommentId == "" {
@@ -84,11 +87,14 @@ func (app *App) LikePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	like := Like{}
+	like := Like{PostId: r.PathValue("id")}
 
-	if err = json.NewDecoder(r.Body).Decode(&like); err != nil {
-		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
-		return
+	// The legacy /api/likePost route sends the post in the body.
+	if like.PostId == "" {
+		if err = json.NewDecoder(r.Body).Decode(&like); err != nil {
+			app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+			return
+		}
 	}
 
 	if like.PostId == "" {
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -9,7 +11,18 @@ import (
 	"social/pkg/util"
 )
 
+type LoginOptions struct {
+	RememberMe bool `json:"remember_me"`
+}
+
 func (app *App) Login(w http.ResponseWriter, r *http.Request) {
+	// The body is optional, credentials always travel in the Authorization header.
+	var options LoginOptions
+	if err := json.NewDecoder(r.Body).Decode(&options); err != nil && err != io.EOF {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
@@ -36,27 +49,100 @@ func (app *App) Login(w http.ResponseWriter, r *http.Request) {
 
 	emailOrNickname, password := parts[0], parts[1]
 
+	if app.loginLocked(w, r, ipThrottleKey(r)) {
+		return
+	}
+
 	// Credentials validation
 	userId, encryptedPassword, err := app.Queries.GetUserCredentials(emailOrNickname)
 	if err != nil {
+		app.recordLoginFailure(r, "")
 		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
 		return
 	}
 
+	if app.loginLocked(w, r, accountThrottleKey(userId)) {
+		return
+	}
+
 	// check password hash if it matches
 	if err := util.ValidatePassword(password, encryptedPassword); err != nil {
+		app.recordLoginFailure(r, userId)
 		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
 		return
 	}
 
-	sessionID := util.UUIDGen()
-	csrfToken, err := util.GenerateCSRFToken()
+	if app.unverifiedPolicy() == UnverifiedBlock {
+		verified, err := app.Queries.IsEmailVerified(userId)
+		if err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+			return
+		}
+		if !verified {
+			app.JSONResponse(w, r, http.StatusForbidden, "Email address not verified", Error)
+			return
+		}
+	}
+
+	_, twoFactor, err := app.Queries.FetchTOTP(userId)
 	if err != nil {
 		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
 		return
 	}
 
-	util.SetSessionCookie(w, sessionID, csrfToken)
+	// With two-factor authentication the session is only issued by LoginTwoFactor,
+	// in exchange for the pending token and a valid code. Failed logins are only
+	// cleared there, so that knowing the password does not reset the code limits.
+	if twoFactor {
+		mfaToken, err := util.GenerateToken()
+		if err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+			return
+		}
+
+		err = app.Queries.CreateMFAChallenge(userId, util.HashToken(mfaToken), options.RememberMe, time.Now().Add(mfaChallengeTTL))
+		if err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+			return
+		}
+
+		app.JSONResponse(w, r, http.StatusOK, map[string]any{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		}, Data)
+		return
+	}
+
+	if err := app.Queries.ClearLoginFailures(accountThrottleKey(userId)); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	if err := app.startSession(w, r, userId, options.RememberMe); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, "Login successful", Success)
+}
+
+// startSession stores a new session for the user and sets the session and CSRF cookies.
+// "Remember me" sessions get a longer idle timeout and persistent cookies.
+// Logging in also cancels a pending deletion of the account.
+func (app *App) startSession(w http.ResponseWriter, r *http.Request, userID string, rememberMe bool) error {
+	if _, err := app.Queries.CancelAccountDeletion(userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	absoluteExpiry := now.Add(app.sessionPolicy().AbsoluteTimeout)
+	expiresAt := app.sessionPolicy().expiry(now, absoluteExpiry, rememberMe)
+
+	sessionID := util.UUIDGen()
+	csrfToken, err := util.SetSessionCookie(w, app.Config.Cookies.Options(), sessionID, cookieExpiry(expiresAt, rememberMe))
+	if err != nil {
+		return err
+	}
 
 	err = app.Queries.InsertData("sessions", []string{
 		"id",
@@ -64,17 +150,25 @@ func (app *App) Login(w http.ResponseWriter, r *http.Request) {
 		"session_token",
 		"csrf_token",
 		"expires_at",
+		"absolute_expires_at",
+		"remember_me",
+		"user_agent",
+		"ip_address",
 	}, []any{
 		util.UUIDGen(),
-		userId,
+		userID,
 		sessionID,
 		csrfToken,
-		time.Now().Add(24 * time.Hour),
+		expiresAt,
+		absoluteExpiry,
+		rememberMe,
+		r.UserAgent(),
+		util.ClientIP(r),
 	})
 	if err != nil {
-		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
-		return
+		util.ExpireSessionCookie(w, app.Config.Cookies.Options())
+		return err
 	}
 
-	app.JSONResponse(w, r, http.StatusOK, "Login successful", Success)
+	return nil
 }
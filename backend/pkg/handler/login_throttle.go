@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"social/pkg/util"
+)
+
+// LoginThrottle slows down password guessing. Every failed login adds a delay
+// before the next attempt, doubling each time, and after MaxFailures the
+// account or address is locked out for LockoutDuration.
+type LoginThrottle struct {
+	// MaxAccountFailures locks an account after this many failures in a row.
+	MaxAccountFailures int
+	// MaxIPFailures locks a client address after this many failures in a row.
+	// It is higher than the account limit since several users can share an address.
+	MaxIPFailures int
+	// BaseDelay is the wait imposed after the first failure.
+	BaseDelay time.Duration
+	// LockoutDuration is how long a locked account or address stays locked.
+	LockoutDuration time.Duration
+	// FailureWindow is how long a failure counts towards the limits.
+	FailureWindow time.Duration
+}
+
+type UnlockAccountData struct {
+	UserID string `json:"user_id"`
+}
+
+// loginThrottle returns the login limits of the configuration.
+func (app *App) loginThrottle() LoginThrottle {
+	cfg := app.Config.Login
+	return LoginThrottle{
+		MaxAccountFailures: cfg.MaxAccountFailures,
+		MaxIPFailures:      cfg.MaxIPFailures,
+		BaseDelay:          cfg.BackoffBase.Duration,
+		LockoutDuration:    cfg.LockoutDuration.Duration,
+		FailureWindow:      cfg.FailureWindow.Duration,
+	}
+}
+
+// delay returns how long to refuse logins after the given number of consecutive
+// failures: BaseDelay doubled for every failure, up to LockoutDuration once
+// maxFailures is reached.
+func (t LoginThrottle) delay(failures, maxFailures int) time.Duration {
+	if failures >= maxFailures {
+		return t.LockoutDuration
+	}
+
+	d := time.Duration(float64(t.BaseDelay) * math.Pow(2, float64(failures-1)))
+	if d <= 0 || d > t.LockoutDuration {
+		return t.LockoutDuration
+	}
+	return d
+}
+
+func accountThrottleKey(userID string) string {
+	return "user:" + userID
+}
+
+func ipThrottleKey(r *http.Request) string {
+	return "ip:" + util.ClientIP(r)
+}
+
+// loginLocked refuses the request with 429 Too Many Requests and a Retry-After
+// header when any of the keys is locked. It reports whether it did.
+func (app *App) loginLocked(w http.ResponseWriter, r *http.Request, keys ...string) bool {
+	now := time.Now()
+
+	var lockedUntil time.Time
+	for _, key := range keys {
+		until, err := app.Queries.FetchLoginLock(key)
+		if err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+			return true
+		}
+		if until.After(lockedUntil) {
+			lockedUntil = until
+		}
+	}
+
+	if !lockedUntil.After(now) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(lockedUntil.Sub(now).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	app.JSONResponse(w, r, http.StatusTooManyRequests, "Too many failed login attempts, try again later", Error)
+	return true
+}
+
+// recordLoginFailure counts a failed login against the client address and, when
+// the account is known, against the account, and locks them for the backoff delay.
+func (app *App) recordLoginFailure(r *http.Request, userID string) {
+	now := time.Now()
+	throttle := app.loginThrottle()
+
+	limits := map[string]int{ipThrottleKey(r): throttle.MaxIPFailures}
+	if userID != "" {
+		limits[accountThrottleKey(userID)] = throttle.MaxAccountFailures
+	}
+
+	for key, maxFailures := range limits {
+		failures, err := app.Queries.RecordLoginFailure(key, now, now.Add(-throttle.FailureWindow))
+		if err != nil {
+			slog.ErrorContext(r.Context(), "login throttle", "err", err)
+			continue
+		}
+		if err := app.Queries.LockLogin(key, now.Add(throttle.delay(failures, maxFailures))); err != nil {
+			slog.ErrorContext(r.Context(), "login throttle", "err", err)
+		}
+	}
+}
+
+// confirmPassword checks the password a logged in user gives to confirm a
+// sensitive change. Wrong passwords count as failed logins and the check is
+// refused while the account or address is locked, so a stolen session cannot
+// guess the password faster than the login form. It answers the request with
+// incorrect as the error and returns false when the password is not confirmed.
+func (app *App) confirmPassword(w http.ResponseWriter, r *http.Request, userID, password, incorrect string) bool {
+	if app.loginLocked(w, r, ipThrottleKey(r), accountThrottleKey(userID)) {
+		return false
+	}
+
+	hashedPassword, err := app.Queries.FetchPasswordHash(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return false
+	}
+	if err := util.ValidatePassword(password, hashedPassword); err != nil {
+		app.recordLoginFailure(r, userID)
+		app.JSONResponse(w, r, http.StatusUnauthorized, incorrect, Error)
+		return false
+	}
+	return true
+}
+
+// UnlockAccount lets an administrator lift the lockout of an account before it expires.
+func (app *App) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	adminID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	admin, err := app.Queries.IsAdmin(adminID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+	if !admin {
+		app.JSONResponse(w, r, http.StatusForbidden, "Forbidden", Error)
+		return
+	}
+
+	// The legacy /api/unlockAccount route sends the user in the body.
+	data := UnlockAccountData{UserID: r.PathValue("id")}
+	if data.UserID == "" {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.UserID == "" {
+			app.JSONResponse(w, r, http.StatusBadRequest, "user_id is required", Error)
+			return
+		}
+	}
+
+	if err := app.Queries.ClearLoginFailures(accountThrottleKey(data.UserID)); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to unlock account", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, "Account unlocked", Success)
+}
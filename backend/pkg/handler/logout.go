@@ -25,5 +25,5 @@ func (app *App) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	util.ExpireSessionCookie(w)
+	util.ExpireSessionCookie(w, app.Config.Cookies.Options())
 }
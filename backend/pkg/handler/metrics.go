@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"social/pkg/metrics"
+)
+
+// InstrumentRequests counts requests and their duration per route.
+func (app *App) InstrumentRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		route := routeLabel(r)
+		metrics.HTTPRequests.Inc(route, methodLabel(r.Method), strconv.Itoa(recorder.statusCode()))
+		metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), route)
+	})
+}
+
+// routeLabel returns the path pattern of the route that served r, such as
+// /api/v1/posts/{id}, set by the mux. Unmatched requests share one label so
+// that clients cannot create new series.
+func routeLabel(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	_, path, _ := strings.Cut(r.Pattern, " ")
+	return path
+}
+
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "other"
+}
+
+// Metrics serves the metrics in the Prometheus text format to clients sending
+// the configured token as "Authorization: Bearer <token>". Without a token
+// configured the endpoint is disabled.
+func (app *App) Metrics(w http.ResponseWriter, r *http.Request) {
+	expected := app.Config.Metrics.Token
+	if expected == "" {
+		app.JSONResponse(w, r, http.StatusNotFound, "route not found", Error)
+		return
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := metrics.Default.WriteTo(w); err != nil {
+		slog.WarnContext(r.Context(), "failed to write metrics", "err", err)
+	}
+}
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"social/pkg/model"
+	"social/pkg/oidc"
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+// oidcLoginTTL is how long the user has to complete the sign-in at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcStateCookie binds a sign-in to the browser that started it, so that a
+// callback URL prepared by someone else cannot log the victim into their account.
+const oidcStateCookie = "oidc_state"
+
+var errOIDCEmailTaken = errors.New("an account with this email address already exists")
+
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OIDCProviders lists the configured identity providers for the login page.
+func (app *App) OIDCProviders(w http.ResponseWriter, r *http.Request) {
+	providers := []OIDCProviderInfo{}
+	for _, p := range app.OIDC {
+		providers = append(providers, OIDCProviderInfo{Name: p.Name, DisplayName: p.DisplayName})
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+
+	app.JSONResponse(w, r, http.StatusOK, providers, Success)
+}
+
+// OIDCLogin starts a sign-in with the provider given in the path, or in the
+// query string of the legacy /api/oidcLogin route, and redirects the browser to
+// it. The authorization code flow is protected with PKCE.
+func (app *App) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	if name == "" {
+		name = r.URL.Query().Get("provider")
+	}
+	provider, ok := app.OIDC[name]
+	if !ok {
+		app.JSONResponse(w, r, http.StatusNotFound, "unknown provider", Error)
+		return
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		token, err := util.GenerateToken()
+		if err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+			return
+		}
+		secrets[i] = token
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	err := app.Queries.CreateOIDCLogin(util.HashToken(state), repository.OIDCLogin{
+		Provider:     provider.Name,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		RememberMe:   r.URL.Query().Get("remember_me") == "true",
+	}, time.Now().Add(oidcLoginTTL))
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "oidc sign-in failed", "provider", provider.Name, "err", err)
+		app.JSONResponse(w, r, http.StatusBadGateway, "Identity provider unavailable", Error)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     callbackPath(provider),
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   app.Config.Cookies.Secure,
+		// The provider redirects back with a top-level GET, which Lax allows.
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback completes a sign-in when the provider redirects back. It signs in
+// the user linked to the provider account, creating one on the first sign-in,
+// and sends the browser back to the frontend.
+func (app *App) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		app.redirectLoginError(w, r, "invalid_state")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: r.URL.Path, MaxAge: -1})
+
+	login, err := app.Queries.ConsumeOIDCLogin(util.HashToken(state))
+	if err != nil {
+		app.redirectLoginError(w, r, "invalid_state")
+		return
+	}
+
+	// The user declined, or the provider refused the request.
+	if query.Get("error") != "" {
+		app.redirectLoginError(w, r, "access_denied")
+		return
+	}
+
+	provider, ok := app.OIDC[login.Provider]
+	if !ok {
+		app.redirectLoginError(w, r, "unknown_provider")
+		return
+	}
+
+	rawToken, err := provider.Exchange(r.Context(), query.Get("code"), login.CodeVerifier)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "oidc sign-in failed", "provider", provider.Name, "err", err)
+		app.redirectLoginError(w, r, "provider_error")
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(r.Context(), rawToken, login.Nonce)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "oidc sign-in failed", "provider", provider.Name, "err", err)
+		app.redirectLoginError(w, r, "provider_error")
+		return
+	}
+
+	userID, err := app.identityUser(claims)
+	if errors.Is(err, errOIDCEmailTaken) {
+		app.redirectLoginError(w, r, "email_taken")
+		return
+	} else if err != nil {
+		slog.ErrorContext(r.Context(), "oidc sign-in failed", "provider", provider.Name, "err", err)
+		app.redirectLoginError(w, r, "server_error")
+		return
+	}
+
+	disabled, err := app.Queries.IsUserDisabled(userID)
+	if err != nil {
+		app.redirectLoginError(w, r, "server_error")
+		return
+	}
+	if disabled {
+		app.redirectLoginError(w, r, "account_disabled")
+		return
+	}
+
+	if app.unverifiedPolicy() == UnverifiedBlock {
+		verified, err := app.Queries.IsEmailVerified(userID)
+		if err != nil || !verified {
+			app.redirectLoginError(w, r, "email_not_verified")
+			return
+		}
+	}
+
+	// Accounts with two-factor authentication still need their code.
+	_, twoFactor, err := app.Queries.FetchTOTP(userID)
+	if err != nil {
+		app.redirectLoginError(w, r, "server_error")
+		return
+	}
+	if twoFactor {
+		mfaToken, err := util.GenerateToken()
+		if err == nil {
+			err = app.Queries.CreateMFAChallenge(userID, util.HashToken(mfaToken), login.RememberMe, time.Now().Add(mfaChallengeTTL))
+		}
+		if err != nil {
+			app.redirectLoginError(w, r, "server_error")
+			return
+		}
+		http.Redirect(w, r, app.frontendURL()+"/login?mfa_token="+url.QueryEscape(mfaToken), http.StatusFound)
+		return
+	}
+
+	if err := app.startSession(w, r, userID, login.RememberMe); err != nil {
+		app.redirectLoginError(w, r, "server_error")
+		return
+	}
+
+	http.Redirect(w, r, app.frontendURL()+"/", http.StatusFound)
+}
+
+// identityUser returns the user linked to the provider account in claims.
+// On the first sign-in the account is linked to the user with the same email
+// address when both the provider and our records have verified it, and a new
+// user is created from the claims when there is none.
+func (app *App) identityUser(claims *oidc.Claims) (string, error) {
+	userID, err := app.Queries.FetchIdentityUser(claims.Issuer, claims.Subject)
+	if err == nil {
+		return userID, nil
+	} else if !errors.Is(err, repository.ErrIdentityNotFound) {
+		return "", err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if !util.ValidateEmail(email) {
+		return "", fmt.Errorf("provider did not share a valid email address")
+	}
+
+	if existingID, err := app.Queries.FetchUserIDByEmail(email); err == nil {
+		verified, err := app.Queries.IsEmailVerified(existingID)
+		if err != nil {
+			return "", err
+		}
+		if !verified || !bool(claims.EmailVerified) {
+			return "", errOIDCEmailTaken
+		}
+		return existingID, app.Queries.LinkIdentity(existingID, claims.Issuer, claims.Subject, email)
+	}
+
+	user, err := app.userFromClaims(claims, email)
+	if err != nil {
+		return "", err
+	}
+
+	var verifiedAt *time.Time
+	if claims.EmailVerified {
+		now := time.Now()
+		verifiedAt = &now
+	}
+
+	if err := app.Queries.CreateIdentityUser(user, verifiedAt, claims.Issuer, claims.Subject); err != nil {
+		return "", err
+	}
+
+	if verifiedAt == nil {
+		if err := app.sendVerificationEmail(user.ID, user.Email); err != nil {
+			slog.Error("failed to create email verification", "user_id", user.ID, "err", err)
+		}
+	}
+	return user.ID, nil
+}
+
+// userFromClaims fills a new user from the ID token claims. The account gets a
+// random password, which the user can replace through the password reset flow.
+func (app *App) userFromClaims(claims *oidc.Claims, email string) (model.User, error) {
+	password, err := util.GenerateToken()
+	if err != nil {
+		return model.User{}, err
+	}
+	hashed, err := util.EncryptPassword(password)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(email, "@")
+	}
+
+	// The date of birth can be completed later from the profile settings.
+	dob, _ := time.Parse("2006-01-02", claims.Birthdate)
+
+	nickname := claims.PreferredUsername
+	if util.ValidateNickname(nickname) != nil {
+		nickname = ""
+	} else if taken, err := app.Queries.CheckRow("users", []string{"nickname"}, []any{nickname}); err != nil || taken {
+		nickname = ""
+	}
+
+	return model.User{
+		ID:          util.UUIDGen(),
+		Email:       email,
+		Password:    hashed,
+		FirstName:   firstName,
+		LastName:    lastName,
+		DateOfBirth: dob,
+		Avatar:      claims.Picture,
+		Nickname:    nickname,
+		IsPublic:    true,
+	}, nil
+}
+
+// redirectLoginError sends the browser back to the login page with an error code.
+func (app *App) redirectLoginError(w http.ResponseWriter, r *http.Request, code string) {
+	http.Redirect(w, r, app.frontendURL()+"/login?error="+url.QueryEscape(code), http.StatusFound)
+}
+
+// frontendURL is the base URL of the frontend, for links and redirects.
+func (app *App) frontendURL() string {
+	return strings.TrimSuffix(app.Config.Server.FrontendURL, "/")
+}
+
+// callbackPath is the path the provider redirects back to, which the state
+// cookie is limited to. It follows the configured redirect URL, which can still
+// point at the legacy /api/oidcCallback route.
+func callbackPath(provider *oidc.Provider) string {
+	u, err := url.Parse(provider.RedirectURL)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
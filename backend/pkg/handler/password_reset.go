@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"social/pkg/mail"
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+const passwordResetTTL = time.Hour
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirm struct {
+	Token             string `json:"token"`
+	Password          string `json:"password"`
+	ConfirmedPassword string `json:"confirmed_password"`
+}
+
+// RequestPasswordReset emails a single-use reset link to the account owning the email.
+// The response is the same whether or not the account exists.
+func (app *App) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var data PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	email := strings.TrimSpace(data.Email)
+	if !util.ValidateEmail(email) {
+		app.JSONResponse(w, r, http.StatusBadRequest, "Invalid email address", Error)
+		return
+	}
+
+	const response = "If an account exists for this email, a reset link has been sent"
+
+	userID, err := app.Queries.FetchUserIDByEmail(email)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusOK, response, Success)
+		return
+	}
+
+	token, err := util.GenerateToken()
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	err = app.Queries.CreatePasswordReset(userID, util.HashToken(token), time.Now().Add(passwordResetTTL))
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s",
+		app.frontendURL(), url.QueryEscape(token))
+
+	// Delivery happens in the background so that response time does not reveal
+	// whether the account exists.
+	app.background(func() {
+		err := app.Mailer.Send(mail.Message{
+			To:      email,
+			Subject: "Reset your password",
+			Body: "Someone requested a password reset for your account.\n\n" +
+				"Open the link below within the next hour to choose a new password:\n" +
+				link + "\n\n" +
+				"If you did not request this, you can ignore this email.",
+		})
+		if err != nil {
+			slog.Error("failed to send password reset email", "err", err)
+		}
+	})
+
+	app.JSONResponse(w, r, http.StatusOK, response, Success)
+}
+
+// ResetPassword sets a new password using a reset token and logs the user out everywhere.
+func (app *App) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var data PasswordResetConfirm
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	if data.Token == "" {
+		app.JSONResponse(w, r, http.StatusBadRequest, "Reset token is required", Error)
+		return
+	}
+	if err := util.CheckPasswordStrength(data.Password); err != nil {
+		app.JSONResponse(w, r, http.StatusNotAcceptable, err.Error(), Error)
+		return
+	}
+	if data.Password != data.ConfirmedPassword {
+		app.JSONResponse(w, r, http.StatusNotAcceptable, "Passwords do not match.", Error)
+		return
+	}
+
+	hashed, err := util.EncryptPassword(data.Password)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	// The token is only used up once the password is changed and every session
+	// signed out, so a failure halfway leaves the link working.
+	var revoked []string
+	err = app.Queries.WithTx(r.Context(), func(tx repository.Store) error {
+		userID, err := tx.ConsumePasswordReset(util.HashToken(data.Token))
+		if err != nil {
+			return err
+		}
+		if err := tx.UpdatePassword(userID, hashed); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		revoked, err = tx.DeleteAllUserSessions(userID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrInvalidResetToken) {
+		app.JSONResponse(w, r, http.StatusBadRequest, err.Error(), Error)
+		return
+	} else if err != nil {
+		slog.ErrorContext(r.Context(), "failed to reset password", "err", err)
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+	app.Hub.DisconnectSessions(revoked...)
+
+	app.JSONResponse(w, r, http.StatusOK, "Password reset successfully", Success)
+}
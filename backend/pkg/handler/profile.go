@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
 )
 
@@ -15,7 +15,7 @@ func (app *App) Profile(w http.ResponseWriter, r *http.Request) {
 	// Fetch user data from the database
 	userData, err := app.Queries.FetchUserData(userID)
 	if err != nil {
-		fmt.Println("Error fetching user data:", err)
+		slog.ErrorContext(r.Context(), "failed to fetch user data", "err", err)
 		app.JSONResponse(w, r, http.StatusInternalServerError, "failed to fetch user data", Error)
 		return
 	}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
 
 	"social/pkg/model"
@@ -34,6 +35,8 @@ func (app *App) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID := util.UUIDGen()
+
 	err = app.Queries.InsertData("users", []string{
 		"id",
 		"email",
@@ -46,7 +49,7 @@ func (app *App) Register(w http.ResponseWriter, r *http.Request) {
 		"about_me",
 		"is_public",
 	}, []any{
-		util.UUIDGen(),
+		userID,
 		user.Email,
 		hashed,
 		user.FirstName,
@@ -62,5 +65,10 @@ func (app *App) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	app.JSONResponse(w, r, http.StatusOK, "User registered successfully", Success)
+	// The account exists at this point, a failed email can be retried through /api/resendVerification.
+	if err := app.sendVerificationEmail(userID, user.Email); err != nil {
+		slog.ErrorContext(r.Context(), "failed to create email verification", "user_id", userID, "err", err)
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, "User registered successfully. Check your email to verify your account.", Success)
 }
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"social/pkg/logging"
+	"social/pkg/util"
+)
+
+// RequestLogger gives every request an id and logs it once it is served. The
+// id is taken from the X-Request-ID header when the client sends a valid one,
+// and returned in the response. A W3C traceparent header is logged as trace_id.
+func (app *App) RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get("X-Request-ID")
+		if !logging.ValidID(requestID) {
+			requestID = util.UUIDGen()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := logging.WithRequest(r.Context(), requestID, logging.TraceID(r.Header.Get("traceparent")))
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if recorder.status >= 500 {
+			level = slog.LevelError
+		} else if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			// Probes run every few seconds, only failures are worth seeing by default.
+			level = slog.LevelDebug
+		}
+		// The request, trace and user ids are added from ctx. The query string is
+		// left out as it can hold tokens.
+		slog.LogAttrs(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.statusCode()),
+			slog.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+// statusRecorder remembers the status code written by a handler. It can still
+// be hijacked for websocket upgrades.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	// The connection is handed over after a 101 Switching Protocols.
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *statusRecorder) statusCode() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
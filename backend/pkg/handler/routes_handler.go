@@ -3,101 +3,216 @@ package handler
 import (
 	"net/http"
 	"strings"
+	"sync"
 
+	"social/pkg/config"
+	"social/pkg/mail"
 	"social/pkg/model"
+	"social/pkg/oidc"
 	"social/pkg/repository"
+	"social/pkg/util"
 	"social/pkg/websocket"
 )
 
-var allowedRoutes = map[string][]string{
-	"/api/login":         {"POST", "OPTIONS"},
-	"/api/register":      {"POST", "OPTIONS"},
-	"/api/addPost":       {"POST", "OPTIONS"},
-	"/api/getPosts":      {"GET", "OPTIONS"},
-	"/api/profile":       {"GET", "OPTIONS"},
-	"/api/logout":        {"POST", "OPTIONS"},
-	"/api/addGroup":      {"POST", "OPTIONS"},
-	"/api/getGroupData":  {"GET", "POST", "OPTIONS"},
-	"/pkg/db/media/":     {"GET", "OPTIONS"},
-	"/api/updateUser":    {"PATCH", "OPTIONS"},
-	"/api/groups":        {"GET", "OPTIONS"},
-	"/api/deleteGroup":   {"DELETE", "OPTIONS"},
-	"/api/ws":            {"GET", "OPTIONS"},
-	"/api/rsvp":          {"POST", "OPTIONS"},
-	"/api/users":         {"GET", "OPTIONS"},
-	"/api/addComment":    {"POST", "OPTIONS"},
-	"/api/likeComment":   {"POST", "OPTIONS"},
-	"/api/likePost":      {"POST", "OPTIONS"},
-	"/api/notifications": {"GET", "OPTIONS"},
-	"/api/getProfile":    {"GET", "OPTIONS", "POST"},
+// legacyRoutes maps the verb-style routes of the first version of the API to
+// the /api/v1 routes replacing them. They are served by the same handlers, which
+// still read the ids from the body or the query string for them, and announce
+// their deprecation until clients have moved.
+var legacyRoutes = map[string]string{
+	"POST /api/register":                "POST /api/v1/users",
+	"POST /api/login":                   "POST /api/v1/login",
+	"POST /api/login2FA":                "POST /api/v1/login/2fa",
+	"POST /api/requestPasswordReset":    "POST /api/v1/password-resets",
+	"POST /api/resetPassword":           "POST /api/v1/password-resets/confirm",
+	"POST /api/resendVerification":      "POST /api/v1/email-verifications",
+	"POST /api/verifyEmail":             "POST /api/v1/email-verifications/confirm",
+	"GET /api/oidcProviders":            "GET /api/v1/oidc/providers",
+	"GET /api/oidcLogin":                "GET /api/v1/oidc/{provider}/login",
+	"GET /api/oidcCallback":             "GET /api/v1/oidc/callback",
+	"POST /api/logout":                  "POST /api/v1/logout",
+	"GET /api/profile":                  "GET /api/v1/me",
+	"PATCH /api/updateUser":             "PATCH /api/v1/me",
+	"DELETE /api/deleteAccount":         "DELETE /api/v1/me",
+	"PATCH /api/changePassword":         "PATCH /api/v1/me/password",
+	"POST /api/setup2FA":                "POST /api/v1/me/2fa/setup",
+	"POST /api/enable2FA":               "POST /api/v1/me/2fa",
+	"POST /api/disable2FA":              "DELETE /api/v1/me/2fa",
+	"POST /api/regenerateRecoveryCodes": "POST /api/v1/me/2fa/recovery-codes",
+	"GET /api/sessions":                 "GET /api/v1/me/sessions",
+	"DELETE /api/revokeOtherSessions":   "DELETE /api/v1/me/sessions",
+	"DELETE /api/revokeSession":         "DELETE /api/v1/me/sessions/{id}",
+	"GET /api/tokens":                   "GET /api/v1/me/tokens",
+	"POST /api/createToken":             "POST /api/v1/me/tokens",
+	"DELETE /api/revokeToken":           "DELETE /api/v1/me/tokens/{id}",
+	"GET /api/dataExports":              "GET /api/v1/me/exports",
+	"POST /api/requestDataExport":       "POST /api/v1/me/exports",
+	"GET /api/downloadDataExport":       "GET /api/v1/me/exports/{id}",
+	"GET /api/notifications":            "GET /api/v1/notifications",
+	"GET /api/users":                    "GET /api/v1/users",
+	"GET /api/getProfile":               "GET /api/v1/users/{id}",
+	"POST /api/getProfile":              "GET /api/v1/users/{id}",
+	"POST /api/unlockAccount":           "POST /api/v1/users/{id}/unlock",
+	"GET /api/getPosts":                 "GET /api/v1/posts",
+	"POST /api/addPost":                 "POST /api/v1/posts",
+	"POST /api/addComment":              "POST /api/v1/posts/{id}/comments",
+	"POST /api/likePost":                "POST /api/v1/posts/{id}/like",
+	"POST /api/likeComment":             "POST /api/v1/comments/{id}/like",
+	"GET /api/groups":                   "GET /api/v1/groups",
+	"POST /api/addGroup":                "POST /api/v1/groups",
+	"GET /api/getGroupData":             "GET /api/v1/groups/{id}",
+	"POST /api/getGroupData":            "GET /api/v1/groups/{id}",
+	"DELETE /api/deleteGroup":           "DELETE /api/v1/groups/{id}",
+	"POST /api/rsvp":                    "POST /api/v1/events/{id}/rsvp",
+	"GET /api/ws":                       "GET /api/v1/ws",
 }
 
+// legacyDeprecatedAt is when the legacy routes were deprecated, as sent in the
+// Deprecation header (RFC 9745): 2026-10-17T00:00:00Z.
+const legacyDeprecatedAt = "@1792195200"
+
 type App struct {
-	Queries repository.Query
+	Config  *config.Config
+	Queries repository.Store
 	User    *model.User
 	Hub     *websocket.Hub
-}
+	Mailer  mail.Mailer
+	OIDC    map[string]*oidc.Provider
+
+	// SchemaVersion is the migration version the database must be at to be ready.
+	SchemaVersion uint
 
-// RouteChecker is a middleware that checks if the requested route and method are allowed.
-func (app *App) RouteChecker(next http.Handler) http.Handler {
-	return http.HandlerFunc(
-		func(w http.ResponseWriter, r *http.Request) {
-			allowedURL, ok := allowedRoutes[r.URL.Path]
-			if !ok && !strings.HasPrefix(r.URL.Path, "/pkg/db/media/") {
-				app.JSONResponse(w, r, http.StatusNotFound, "route not found", Error)
-				return
-			}
-
-			method_found := false
-			// Check if the HTTP method is allowed for the route
-			for _, method := range allowedURL {
-				if r.Method == method {
-					method_found = true
-				}
-			}
-
-			if strings.HasPrefix(r.URL.Path, "/pkg/db/media/") && r.Method == http.MethodGet {
-				method_found = true
-			}
-
-			if !method_found {
-				app.JSONResponse(w, r, http.StatusMethodNotAllowed, "method not allowed", Error)
-				return
-			}
-			next.ServeHTTP(w, r)
-		})
+	tasks sync.WaitGroup
 }
 
-// Routes sets up the application routes and returns an http.Handler.
+// Routes sets up the application routes and returns an http.Handler. Each
+// pattern gives the method of its route, so the mux answers other methods with
+// 405 Method Not Allowed; GET routes also answer HEAD.
 func (app *App) Routes() http.Handler {
 	mux := http.NewServeMux()
+	handlers := make(map[string]http.Handler)
+	public := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, handler)
+		handlers[pattern] = handler
+	}
+	protected := func(pattern string, handler http.HandlerFunc) {
+		public(pattern, app.AuthMiddleware(handler).ServeHTTP)
+	}
 
 	// Public routes
-	mux.Handle("/api/register", http.HandlerFunc(app.Register))
-	mux.Handle("/api/login", http.HandlerFunc(app.Login))
+	public("POST /api/v1/users", app.Register)
+	public("POST /api/v1/login", app.Login)
+	public("POST /api/v1/login/2fa", app.LoginTwoFactor)
+	public("POST /api/v1/password-resets", app.RequestPasswordReset)
+	public("POST /api/v1/password-resets/confirm", app.ResetPassword)
+	public("POST /api/v1/email-verifications", app.ResendVerification)
+	public("POST /api/v1/email-verifications/confirm", app.VerifyEmail)
+	public("GET /api/v1/oidc/providers", app.OIDCProviders)
+	public("GET /api/v1/oidc/{provider}/login", app.OIDCLogin)
+	public("GET /api/v1/oidc/callback", app.OIDCCallback)
+	public("GET /metrics", app.Metrics)
+	public("GET /healthz", app.Healthz)
+	public("GET /readyz", app.Readyz)
 
 	// Serve media files
-	fs := http.FileServer(http.Dir("pkg/db/media"))
-	mux.Handle("/pkg/db/media/", http.StripPrefix("/pkg/db/media/", fs))
+	fs := http.FileServer(http.Dir(util.MediaDir()))
+	mux.Handle("GET /pkg/db/media/", http.StripPrefix("/pkg/db/media/", fs))
 
 	// protected routes
-	mux.Handle("/api/addPost", app.AuthMiddleware(http.HandlerFunc(app.AddPost)))
-	mux.Handle("/api/getPosts", app.AuthMiddleware(http.HandlerFunc(app.GetPosts)))
-	mux.Handle("/api/profile", app.AuthMiddleware(http.HandlerFunc(app.Profile)))
-	mux.Handle("/api/logout", app.AuthMiddleware(http.HandlerFunc(app.Logout)))
-	mux.Handle("/api/addGroup", app.AuthMiddleware(http.HandlerFunc(app.AddGroup)))
-	mux.Handle("/api/getGroupData", app.AuthMiddleware(http.HandlerFunc(app.GetGroupData)))
-	mux.Handle("/api/updateUser", app.AuthMiddleware(http.HandlerFunc(app.UpdateUser)))
-	mux.Handle("/api/groups", app.AuthMiddleware(http.HandlerFunc(app.GetAllGroups)))
-	mux.Handle("/api/deleteGroup", app.AuthMiddleware(http.HandlerFunc(app.DeleteGroup)))
-	mux.Handle("/api/ws", app.AuthMiddleware(http.HandlerFunc(app.HandleWebsocket)))
-	mux.Handle("/api/rsvp", app.AuthMiddleware(http.HandlerFunc(app.Rsvp)))
-	mux.Handle("/api/users", app.AuthMiddleware(http.HandlerFunc(app.GetAllUsers)))
-	mux.Handle("/api/addComment", app.AuthMiddleware(http.HandlerFunc(app.AddComment)))
-	mux.Handle("/api/likeComment", app.AuthMiddleware(http.HandlerFunc(app.LikeComment)))
-	mux.Handle("/api/likePost", app.AuthMiddleware(http.HandlerFunc(app.LikePost)))
-	mux.Handle("/api/notifications", app.AuthMiddleware(http.HandlerFunc(app.Notifications)))
-	mux.Handle("/api/getProfile", app.AuthMiddleware(http.HandlerFunc(app.GetProfile)))
-
-	return mux
+	protected("POST /api/v1/logout", app.Logout)
+	protected("GET /api/v1/me", app.Profile)
+	protected("PATCH /api/v1/me", app.UpdateUser)
+	protected("DELETE /api/v1/me", app.DeleteAccount)
+	protected("PATCH /api/v1/me/password", app.ChangePassword)
+	protected("POST /api/v1/me/2fa/setup", app.SetupTwoFactor)
+	protected("POST /api/v1/me/2fa", app.EnableTwoFactor)
+	protected("DELETE /api/v1/me/2fa", app.DisableTwoFactor)
+	protected("POST /api/v1/me/2fa/recovery-codes", app.RegenerateRecoveryCodes)
+	protected("GET /api/v1/me/sessions", app.Sessions)
+	protected("DELETE /api/v1/me/sessions", app.RevokeOtherSessions)
+	protected("DELETE /api/v1/me/sessions/{id}", app.RevokeSession)
+	protected("GET /api/v1/me/tokens", app.Tokens)
+	protected("POST /api/v1/me/tokens", app.CreateToken)
+	protected("DELETE /api/v1/me/tokens/{id}", app.RevokeToken)
+	protected("GET /api/v1/me/exports", app.DataExports)
+	protected("POST /api/v1/me/exports", app.RequestDataExport)
+	protected("GET /api/v1/me/exports/{id}", app.DownloadDataExport)
+	protected("GET /api/v1/notifications", app.Notifications)
+	protected("GET /api/v1/users", app.GetAllUsers)
+	protected("GET /api/v1/users/{id}", app.GetProfile)
+	protected("POST /api/v1/users/{id}/unlock", app.UnlockAccount)
+	protected("GET /api/v1/posts", app.GetPosts)
+	protected("POST /api/v1/posts", app.AddPost)
+	protected("GET /api/v1/posts/{id}", app.GetPost)
+	protected("POST /api/v1/posts/{id}/comments", app.AddComment)
+	protected("POST /api/v1/posts/{id}/like", app.LikePost)
+	protected("POST /api/v1/comments/{id}/like", app.LikeComment)
+	protected("GET /api/v1/groups", app.GetAllGroups)
+	protected("POST /api/v1/groups", app.AddGroup)
+	protected("GET /api/v1/groups/{id}", app.GetGroupData)
+	protected("DELETE /api/v1/groups/{id}", app.DeleteGroup)
+	protected("POST /api/v1/events/{id}/rsvp", app.Rsvp)
+	protected("GET /api/v1/ws", app.HandleWebsocket)
+
+	for legacy, successor := range legacyRoutes {
+		mux.Handle(legacy, deprecated(successor, handlers[successor]))
+	}
+
+	return app.withJSONErrors(mux)
 }
+
+// deprecated serves a legacy route with the handler of the route replacing it,
+// announcing the deprecation and, when its path has no parameters to fill in,
+// linking to its successor.
+func deprecated(successor string, next http.Handler) http.Handler {
+	_, path, _ := strings.Cut(successor, " ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", legacyDeprecatedAt)
+		if !strings.Contains(path, "{") {
+			w.Header().Set("Link", "<"+path+`>; rel="successor-version"`)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withJSONErrors answers the requests no route matches in JSON, as the handlers
+// do, instead of the plain text of the mux: 404 for unknown paths, and 405 with
+// the Allow header for a method the path does not accept.
+func (app *App) withJSONErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notFound, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &headerRecorder{header: make(http.Header)}
+		notFound.ServeHTTP(rec, r)
+		if rec.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			app.JSONResponse(w, r, http.StatusMethodNotAllowed, "method not allowed", Error)
+			return
+		}
+		app.JSONResponse(w, r, http.StatusNotFound, "route not found", Error)
+	})
+}
+
+// routePattern returns the pattern of the route serving r, or the one of the
+// route replacing it for a legacy route, so that both are treated alike.
+func routePattern(r *http.Request) string {
+	if successor, ok := legacyRoutes[r.Pattern]; ok {
+		return successor
+	}
+	return r.Pattern
+}
+
+// headerRecorder keeps the status and headers of a response, dropping its body.
+type headerRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *headerRecorder) Header() http.Header { return rec.header }
+
+func (rec *headerRecorder) Write(b []byte) (int, error) { return len(b), nil }
+
+func (rec *headerRecorder) WriteHeader(status int) { rec.status = status }
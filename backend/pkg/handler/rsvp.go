@@ -25,6 +25,10 @@ func (app *App) Rsvp(w http.ResponseWriter, r *http.Request) {
 		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
 		return
 	}
+	// The legacy /api/rsvp route sends the event in the body.
+	if eventID := r.PathValue("id"); eventID != "" {
+		rsvp.ID = eventID
+	}
 
 	rsvped, err := app.Queries.CheckForRsvp(rsvp.ID, userID)
 	if err != nil {
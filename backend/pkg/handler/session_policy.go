@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SessionPolicy controls how long sessions live and how they are renewed.
+type SessionPolicy struct {
+	// IdleTimeout ends a session that has not been used for this long.
+	IdleTimeout time.Duration
+	// RememberMeIdleTimeout replaces IdleTimeout for sessions created with "remember me".
+	RememberMeIdleTimeout time.Duration
+	// AbsoluteTimeout ends every session this long after login, however active it is.
+	AbsoluteTimeout time.Duration
+	// SweepInterval is how often expired sessions are deleted.
+	SweepInterval time.Duration
+}
+
+// sessionPolicy returns the session timeouts of the configuration.
+func (app *App) sessionPolicy() SessionPolicy {
+	cfg := app.Config.Sessions
+	return SessionPolicy{
+		IdleTimeout:           cfg.IdleTimeout.Duration,
+		RememberMeIdleTimeout: cfg.RememberMeTimeout.Duration,
+		AbsoluteTimeout:       cfg.AbsoluteTimeout.Duration,
+		SweepInterval:         cfg.SweepInterval.Duration,
+	}
+}
+
+func (p SessionPolicy) idleTimeout(rememberMe bool) time.Duration {
+	if rememberMe {
+		return p.RememberMeIdleTimeout
+	}
+	return p.IdleTimeout
+}
+
+// expiry returns when a session used at now expires: after the idle timeout,
+// but never later than its absolute expiry.
+func (p SessionPolicy) expiry(now, absoluteExpiry time.Time, rememberMe bool) time.Time {
+	expiresAt := now.Add(p.idleTimeout(rememberMe))
+	if expiresAt.After(absoluteExpiry) {
+		return absoluteExpiry
+	}
+	return expiresAt
+}
+
+// renewal reports whether a session used at now is close enough to its expiry
+// to be extended, and the new expiry. Sessions are renewed once less than half
+// of their idle timeout is left, so an active user never gets logged out.
+func (p SessionPolicy) renewal(now, expiresAt, absoluteExpiry time.Time, rememberMe bool) (time.Time, bool) {
+	if expiresAt.Sub(now) > p.idleTimeout(rememberMe)/2 {
+		return time.Time{}, false
+	}
+
+	renewed := p.expiry(now, absoluteExpiry, rememberMe)
+	return renewed, renewed.After(expiresAt)
+}
+
+// cookieExpiry returns the expiry of the session cookies. Without "remember me"
+// they are browser-session cookies, dropped when the browser closes.
+func cookieExpiry(expiresAt time.Time, rememberMe bool) time.Time {
+	if !rememberMe {
+		return time.Time{}
+	}
+	return expiresAt
+}
+
+// SweepSessions deletes expired sessions every SweepInterval and disconnects the
+// websocket clients that were opened with them. It returns when ctx is cancelled.
+func (app *App) SweepSessions(ctx context.Context) {
+	ticker := time.NewTicker(app.Config.Sessions.SweepInterval.Duration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := app.Queries.DeleteExpiredSessions()
+			if err != nil {
+				slog.Error("session sweeper", "err", err)
+				continue
+			}
+			app.Hub.DisconnectSessions(expired...)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"social/pkg/repository"
+)
+
+type RevokeSessionData struct {
+	SessionID string `json:"session_id"`
+}
+
+// Sessions lists the active sessions (devices) of the logged in user.
+func (app *App) Sessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized: session cookie missing", Error)
+		return
+	}
+
+	sessions, err := app.Queries.FetchUserSessions(userID, sessionCookie.Value)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to fetch sessions", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, sessions, Success)
+}
+
+// RevokeSession logs out one of the user's sessions and closes its websocket connection.
+func (app *App) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	// The legacy /api/revokeSession route sends the session in the body.
+	data := RevokeSessionData{SessionID: r.PathValue("id")}
+	if data.SessionID == "" {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.SessionID == "" {
+			app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+			return
+		}
+	}
+
+	err = app.Queries.DeleteUserSession(userID, data.SessionID)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		app.JSONResponse(w, r, http.StatusNotFound, "Session not found", Error)
+		return
+	} else if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to revoke session", Error)
+		return
+	}
+
+	app.Hub.DisconnectSessions(data.SessionID)
+
+	app.JSONResponse(w, r, http.StatusOK, "Session revoked", Success)
+}
+
+// RevokeOtherSessions logs out every session of the user except the current one.
+func (app *App) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized: session cookie missing", Error)
+		return
+	}
+
+	revoked, err := app.Queries.DeleteOtherSessions(userID, sessionCookie.Value)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to revoke sessions", Error)
+		return
+	}
+
+	app.Hub.DisconnectSessions(revoked...)
+
+	app.JSONResponse(w, r, http.StatusOK, map[string]int{"revoked": len(revoked)}, Data)
+}
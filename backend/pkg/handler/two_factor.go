@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+type TwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorLogin struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorDisable struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// SetupTwoFactor starts TOTP enrollment by generating a secret for the user.
+// Two-factor authentication is only enabled once EnableTwoFactor confirms a code.
+func (app *App) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	_, enabled, err := app.Queries.FetchTOTP(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+	if enabled {
+		app.JSONResponse(w, r, http.StatusConflict, "Two-factor authentication is already enabled", Error)
+		return
+	}
+
+	email, err := app.Queries.FetchUserEmail(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	if err := app.Queries.SetPendingTOTPSecret(userID, secret); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, map[string]string{
+		"secret":      secret,
+		"otpauth_uri": util.TOTPURI(app.Config.Login.TOTPIssuer, email, secret),
+	}, Data)
+}
+
+// EnableTwoFactor confirms enrollment with a code from the authenticator app and
+// returns the recovery codes. They are only shown this once.
+func (app *App) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	var data TwoFactorCode
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	secret, enabled, err := app.Queries.FetchTOTP(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+	if enabled {
+		app.JSONResponse(w, r, http.StatusConflict, "Two-factor authentication is already enabled", Error)
+		return
+	}
+	if secret == "" {
+		app.JSONResponse(w, r, http.StatusBadRequest, "Two-factor setup has not been started", Error)
+		return
+	}
+
+	step, ok := util.ValidateTOTP(secret, strings.TrimSpace(data.Code), time.Now())
+	if !ok {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Invalid code", Error)
+		return
+	}
+
+	// A code already used for its time step is refused, as at login.
+	fresh, err := app.Queries.UseTOTPStep(userID, step)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+	if !fresh {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Invalid code", Error)
+		return
+	}
+
+	codes, err := app.newRecoveryCodes(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	if err := app.Queries.EnableTOTP(userID); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, map[string]any{"recovery_codes": codes}, Data)
+}
+
+// DisableTwoFactor turns two-factor authentication off. It requires the password
+// and a current code or recovery code.
+func (app *App) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	var data TwoFactorDisable
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	if !app.confirmPassword(w, r, userID, data.Password, "Invalid password") {
+		return
+	}
+
+	ok, err := app.checkSecondFactor(userID, data.Code)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+	if !ok {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Invalid code", Error)
+		return
+	}
+
+	if err := app.Queries.DisableTOTP(userID); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, "Two-factor authentication disabled", Success)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user after checking a current code.
+func (app *App) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.GetSessionData(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Unauthorized", Error)
+		return
+	}
+
+	var data TwoFactorCode
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	ok, err := app.checkSecondFactor(userID, data.Code)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+	if !ok {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Invalid code", Error)
+		return
+	}
+
+	codes, err := app.newRecoveryCodes(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, map[string]any{"recovery_codes": codes}, Data)
+}
+
+// LoginTwoFactor completes a login started by Login for an account with two-factor
+// authentication, exchanging the pending token and a code for a session.
+func (app *App) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var data TwoFactorLogin
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.MFAToken == "" {
+		app.JSONResponse(w, r, http.StatusBadRequest, "invalid request body", Error)
+		return
+	}
+
+	tokenHash := util.HashToken(data.MFAToken)
+
+	userID, rememberMe, err := app.Queries.AttemptMFAChallenge(tokenHash)
+	if errors.Is(err, repository.ErrInvalidMFAToken) {
+		app.JSONResponse(w, r, http.StatusUnauthorized, err.Error(), Error)
+		return
+	} else if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	if app.loginLocked(w, r, ipThrottleKey(r), accountThrottleKey(userID)) {
+		return
+	}
+
+	ok, err := app.checkSecondFactor(userID, data.Code)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+	if !ok {
+		app.recordLoginFailure(r, userID)
+		app.JSONResponse(w, r, http.StatusUnauthorized, "Invalid code", Error)
+		return
+	}
+
+	if err := app.Queries.ClearLoginFailures(accountThrottleKey(userID)); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	if err := app.Queries.DeleteMFAChallenge(tokenHash); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	if err := app.startSession(w, r, userID, rememberMe); err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "Internal server error", Error)
+		return
+	}
+
+	app.JSONResponse(w, r, http.StatusOK, "Login successful", Success)
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code.
+// Each TOTP code and each recovery code can only be used once.
+func (app *App) checkSecondFactor(userID, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	secret, enabled, err := app.Queries.FetchTOTP(userID)
+	if err != nil {
+		return false, err
+	}
+	if !enabled {
+		return false, nil
+	}
+
+	if step, ok := util.ValidateTOTP(secret, code, time.Now()); ok {
+		return app.Queries.UseTOTPStep(userID, step)
+	}
+
+	return app.Queries.UseRecoveryCode(userID, util.HashToken(util.NormalizeRecoveryCode(code)))
+}
+
+// newRecoveryCodes generates and stores a fresh set of recovery codes for the user.
+func (app *App) newRecoveryCodes(userID string) ([]string, error) {
+	codes, err := util.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = util.HashToken(code)
+	}
+
+	if err := app.Queries.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
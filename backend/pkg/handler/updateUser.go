@@ -2,7 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strings"
+
+	"social/pkg/repository"
+	"social/pkg/util"
 )
 
 var allowedUserFields = map[string]bool{
@@ -43,11 +49,61 @@ func (app *App) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A new email address has to be verified again before the account is trusted.
+	var newEmail string
+	if val, ok := updateData["email"]; ok {
+		email, isString := val.(string)
+		email = strings.TrimSpace(email)
+		if !isString || !util.ValidateEmail(email) {
+			app.JSONResponse(w, r, http.StatusBadRequest, "Invalid email address", Error)
+			return
+		}
+
+		currentEmail, err := app.Queries.FetchUserEmail(userID)
+		if err != nil {
+			app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to update user", Error)
+			return
+		}
+
+		for i, col := range columns {
+			if col == "email" {
+				values[i] = email
+			}
+		}
+
+		if email != currentEmail {
+			taken, err := app.Queries.CheckRow("users", []string{"email"}, []any{email})
+			if err != nil {
+				app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to update user", Error)
+				return
+			}
+			if taken {
+				app.JSONResponse(w, r, http.StatusConflict, "An account with this email address already exists", Error)
+				return
+			}
+
+			newEmail = email
+			columns = append(columns, "verified_at")
+			values = append(values, nil)
+		}
+	}
+
 	err = app.Queries.UpdateUser(userID, "users", columns, values)
-	if err != nil {
+	if errors.Is(err, repository.ErrInvalidValue) {
+		app.JSONResponse(w, r, http.StatusBadRequest, "Invalid field value", Error)
+		return
+	} else if err != nil {
 		app.JSONResponse(w, r, http.StatusInternalServerError, "Failed to update user", Error)
 		return
 	}
 
+	if newEmail != "" {
+		if err := app.sendVerificationEmail(userID, newEmail); err != nil {
+			slog.ErrorContext(r.Context(), "failed to create email verification", "err", err)
+		}
+		app.JSONResponse(w, r, http.StatusOK, "User updated successfully. Check your email to verify the new address.", Success)
+		return
+	}
+
 	app.JSONResponse(w, r, http.StatusOK, "User updated successfully", Success)
 }
@@ -3,15 +3,29 @@ package handler
 import (
 	"net/http"
 
+	"social/pkg/logging"
+	"social/pkg/util"
 	socket "social/pkg/websocket"
 
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin:     func(r *http.Request) bool { return true },
+// upgrader accepts websockets opened by pages of the configured CORS origins.
+// Browsers always send the Origin header with upgrades, so a request without one
+// comes from another kind of client, which authenticates with a token.
+func (app *App) upgrader() *websocket.Upgrader {
+	allowedOrigins := app.Config.CORS.AllowedOrigins
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || isOriginAllowed(origin, allowedOrigins)
+		},
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			app.JSONResponse(w, r, status, "failed to upgrade connection: "+reason.Error(), Error)
+		},
+	}
 }
 
 func (app *App) HandleWebsocket(w http.ResponseWriter, r *http.Request) {
@@ -20,9 +34,21 @@ func (app *App) HandleWebsocket(w http.ResponseWriter, r *http.Request) {
 		app.JSONResponse(w, r, http.StatusUnauthorized, "unauthorized", Error)
 		return
 	}
-	conn, err := upgrader.Upgrade(w, r, nil)
+	sessionID, allowed, err := app.connectionOwner(r)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusUnauthorized, "unauthorized", Error)
+		return
+	}
+
+	verified, err := app.Queries.IsEmailVerified(userID)
+	if err != nil {
+		app.JSONResponse(w, r, http.StatusInternalServerError, "failed to load user", Error)
+		return
+	}
+
+	conn, err := app.upgrader().Upgrade(w, r, nil)
 	if err != nil {
-		app.JSONResponse(w, r, http.StatusBadRequest, "failed to upgrade connection", Error)
+		// The upgrader has already answered.
 		return
 	}
 
@@ -34,6 +60,10 @@ func (app *App) HandleWebsocket(w http.ResponseWriter, r *http.Request) {
 
 	client := &socket.Client{
 		UserID:      userID,
+		SessionID:   sessionID,
+		RequestID:   logging.RequestID(r.Context()),
+		ReadOnly:    !verified,
+		Allowed:     allowed,
 		Groups:      groupIDs,
 		Conn:        conn,
 		Send:        make(chan []byte, 256),
@@ -45,6 +75,24 @@ func (app *App) HandleWebsocket(w http.ResponseWriter, r *http.Request) {
 	defer client.Cleanup()
 
 	go client.WritePump()
-	go client.ProcessMessages(&app.Queries, app.Hub)
+	app.Hub.Process(client, app.Queries)
 	client.ReadPump()
 }
+
+// connectionOwner returns the id of the session, or of the personal access token,
+// the websocket is opened with, so the connection can be closed when it is revoked.
+// For a token it also returns the message types its scopes allow; a session may
+// send all of them, which is reported as nil.
+func (app *App) connectionOwner(r *http.Request) (string, map[string]bool, error) {
+	if token, ok := bearerToken(r); ok {
+		tokenID, _, scopes, err := app.Queries.AuthenticateAPIToken(util.HashToken(token))
+		return tokenID, allowedMessages(scopes), err
+	}
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil {
+		return "", nil, err
+	}
+	sessionID, err := app.Queries.FetchSessionID(sessionCookie.Value)
+	return sessionID, nil, err
+}
@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// New returns a logger writing records to w as "json" or "text" at the given
+// level. Records logged with a request context carry its request, trace and user ids.
+func New(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if format == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// contextHandler adds the attributes of the request found in the context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info := requestFromContext(ctx); info != nil {
+		record.AddAttrs(slog.String("request_id", info.id))
+		if info.traceID != "" {
+			record.AddAttrs(slog.String("trace_id", info.traceID))
+		}
+		if userID := info.UserID(); userID != "" {
+			record.AddAttrs(slog.String("user_id", userID))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Request holds what is known about the request being served.
+type Request struct {
+	id      string
+	traceID string
+
+	mu     sync.Mutex
+	userID string
+}
+
+type requestKey struct{}
+
+// WithRequest returns a context for a request with the given ids. traceID is
+// empty when the caller did not send a trace context.
+func WithRequest(ctx context.Context, requestID, traceID string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &Request{id: requestID, traceID: traceID})
+}
+
+func requestFromContext(ctx context.Context) *Request {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(requestKey{}).(*Request)
+	return info
+}
+
+// RequestID returns the id of the request the context belongs to, if any.
+func RequestID(ctx context.Context) string {
+	if info := requestFromContext(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of the request the context belongs to.
+func SetUserID(ctx context.Context, userID string) {
+	if info := requestFromContext(ctx); info != nil {
+		info.mu.Lock()
+		info.userID = userID
+		info.mu.Unlock()
+	}
+}
+
+// UserID returns the authenticated user of the request.
+func (r *Request) UserID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.userID
+}
+
+// ValidID reports whether an id sent by a client can be used as is: at most
+// 128 letters, digits, and "-", "_", "." or ":" characters.
+func ValidID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// TraceID returns the trace id of a W3C traceparent header, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", or "" when it is invalid.
+func TraceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ""
+	}
+	if !isLowerHex(parts[0]) || !isLowerHex(parts[1]) || !isLowerHex(parts[2]) || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	return parts[1]
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"log/slog"
+
+	"social/pkg/config"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails to users.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns an SMTPMailer when an SMTP host is configured, otherwise an
+// OutboxMailer that writes every message to the outbox directory.
+func New(cfg config.Mail) Mailer {
+	if cfg.SMTP.Host == "" {
+		slog.Info("no SMTP host set, writing outgoing mail to the outbox", "dir", cfg.OutboxDir)
+		return &OutboxMailer{Dir: cfg.OutboxDir, From: cfg.From}
+	}
+
+	return &SMTPMailer{
+		Host:     cfg.SMTP.Host,
+		Port:     cfg.SMTP.Port,
+		Username: cfg.SMTP.Username,
+		Password: cfg.SMTP.Password,
+		From:     cfg.From,
+	}
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Default is the registry served on /metrics.
+var Default = NewRegistry()
+
+var (
+	HTTPRequests = Default.NewCounter("http_requests_total",
+		"HTTP requests served, by route, method and status code.", "route", "method", "status")
+	HTTPRequestDuration = Default.NewHistogram("http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by route.", DefaultBuckets, "route")
+
+	WebsocketMessages = Default.NewCounter("websocket_messages_total",
+		"Websocket messages received from clients, by message type.", "type")
+	WebsocketDroppedSends = Default.NewCounter("websocket_dropped_sends_total",
+		"Websocket messages dropped because the recipient's send buffer was full, by kind of send.", "kind")
+)
+
+// RegisterDB exposes the connection pool statistics of db.
+func RegisterDB(db *sql.DB) {
+	registerPool("db_", "", db)
+}
+
+// RegisterReadDB exposes the connection pool statistics of the pool SQLite
+// reads on, as db_read_ metrics.
+func RegisterReadDB(db *sql.DB) {
+	registerPool("db_read_", " Read pool.", db)
+}
+
+func registerPool(prefix, helpSuffix string, db *sql.DB) {
+	stats := []struct {
+		name, help string
+		counter    bool
+		value      func(sql.DBStats) float64
+	}{
+		{"db_max_open_connections", "Maximum number of open connections to the database.", false,
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"db_open_connections", "Established connections, both in use and idle.", false,
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"db_in_use_connections", "Connections currently in use.", false,
+			func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"db_idle_connections", "Idle connections.", false,
+			func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"db_wait_count_total", "Connections waited for.", true,
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"db_wait_duration_seconds_total", "Time spent waiting for a connection.", true,
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"db_max_idle_closed_total", "Connections closed because of the maximum number of idle connections.", true,
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"db_max_idle_time_closed_total", "Connections closed because of the maximum idle time.", true,
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+		{"db_max_lifetime_closed_total", "Connections closed because of the maximum connection lifetime.", true,
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+
+	for _, stat := range stats {
+		read := func() float64 { return stat.value(db.Stats()) }
+		name, help := prefix+strings.TrimPrefix(stat.name, "db_"), stat.help+helpSuffix
+		if stat.counter {
+			Default.NewCounterFunc(name, help, read)
+		} else {
+			Default.NewGaugeFunc(name, help, read)
+		}
+	}
+}
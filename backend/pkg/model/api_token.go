@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// APIToken describes a personal access token. The token itself is only shown
+// once, when it is created, and is stored hashed.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
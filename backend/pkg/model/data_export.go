@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// DataExport is an archive of a user's personal data.
+type DataExport struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	FilePath    string     `json:"-"`
+}
+
+// GroupMembership is a group the user belongs to, as listed in data exports.
+type GroupMembership struct {
+	GroupID  string    `json:"group_id"`
+	Title    string    `json:"title"`
+	Role     string    `json:"role"`
+	Creator  bool      `json:"creator"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// EventResponse is the user's answer to a group event, as listed in data exports.
+type EventResponse struct {
+	EventID     string    `json:"event_id"`
+	GroupID     string    `json:"group_id"`
+	Title       string    `json:"title"`
+	EventTime   time.Time `json:"event_time"`
+	Status      string    `json:"status"`
+	RespondedAt time.Time `json:"responded_at"`
+}
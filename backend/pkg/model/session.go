@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Session describes one active login of a user, as shown on the
+// "active devices" screen. The session and CSRF tokens are never exposed.
+type Session struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
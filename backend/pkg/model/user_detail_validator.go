@@ -60,10 +60,8 @@ func ValidateUserDetails(w http.ResponseWriter, r *http.Request, user *User) (in
 			formErrors["nickname"] = append(formErrors["nickname"], err.Error())
 		}
 	}
-	if password == "" {
-		formErrors["password"] = append(formErrors["password"], "Password is required.")
-	} else if len(password) < 8 {
-		formErrors["password"] = append(formErrors["password"], "Password must be at least 8 characters.")
+	if err := util.CheckPasswordStrength(password); err != nil {
+		formErrors["password"] = append(formErrors["password"], err.Error())
 	} else if confirmPassword == "" {
 		formErrors["confirmed_password"] = append(formErrors["confirmed_password"], "Confirm password is required.")
 	} else if password != confirmPassword {
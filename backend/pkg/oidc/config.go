@@ -0,0 +1,28 @@
+package oidc
+
+import (
+	"social/pkg/config"
+)
+
+// NewProviders returns the configured providers by name. Every provider
+// redirects back to the configured redirect URL, which must be registered with it.
+func NewProviders(cfg config.OIDC) map[string]*Provider {
+	providers := make(map[string]*Provider)
+	for _, p := range cfg.Providers {
+		displayName := p.DisplayName
+		if displayName == "" {
+			displayName = p.Name
+		}
+
+		providers[p.Name] = &Provider{
+			Name:         p.Name,
+			DisplayName:  displayName,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       p.Scopes,
+		}
+	}
+	return providers
+}
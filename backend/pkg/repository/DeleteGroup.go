@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -33,7 +34,7 @@ func (q *Query) CheckIfGroupExist(groupName string) error {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM groups WHERE title = ?)`
 
-	err := q.Db.QueryRow(query, groupName).Scan(&exists)
+	err := q.db().QueryRow(q.Rebind(query), groupName).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("database error: %v", err)
 	}
@@ -48,7 +49,7 @@ func (q *Query) CheckIfGroupExist(groupName string) error {
 func (q *Query) CheckIfUserIsGroupAdmin(groupName, userId string) error {
 	var admin bool
 	query := `SELECT EXISTS(SELECT 1 FROM groups WHERE title = ? AND creator_id = ?)`
-	err := q.Db.QueryRow(query, groupName, userId).Scan(&admin)
+	err := q.db().QueryRow(q.Rebind(query), groupName, userId).Scan(&admin)
 	if err != nil {
 		return fmt.Errorf("database err: %v", err)
 	}
@@ -61,9 +62,13 @@ func (q *Query) CheckIfUserIsGroupAdmin(groupName, userId string) error {
 }
 
 func (q *Query) deleteGroup(groupName string) error {
-	_, err := q.Db.Exec("DELETE FROM groups WHERE title = ?", groupName)
-	if err != nil {
+	return q.withTx(context.Background(), func(tx *Query) error {
+		// posts.group_id has no ON DELETE action in SQLite, so the group's posts go first.
+		_, err := tx.db().Exec(q.Rebind("DELETE FROM posts WHERE group_id = (SELECT id FROM groups WHERE title = ?)"), groupName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.db().Exec(q.Rebind("DELETE FROM groups WHERE title = ?"), groupName)
 		return err
-	}
-	return nil
+	})
 }
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"social/pkg/util"
+)
+
+// IsUploadedFile reports whether file is recorded under util.MediaPath, so
+// that it can be deleted from disk. Only uploads are removed with a deleted
+// account, avatars can also be links to other sites.
+func IsUploadedFile(file string) bool {
+	return strings.HasPrefix(file, util.MediaPath+"/") && !strings.Contains(file, "..")
+}
+
+// ScheduleAccountDeletion marks the account to be deleted at the given time.
+func (q *Query) ScheduleAccountDeletion(userID string, at time.Time) error {
+	return q.UpdateData("users", []string{"id"}, []any{userID}, []string{"deletion_scheduled_at"}, []any{at})
+}
+
+// CancelAccountDeletion clears a scheduled deletion and reports whether there was one.
+func (q *Query) CancelAccountDeletion(userID string) (bool, error) {
+	res, err := q.db().Exec(q.Rebind("UPDATE users SET deletion_scheduled_at = NULL WHERE id = ? AND deletion_scheduled_at IS NOT NULL"), userID)
+	if err != nil {
+		return false, fmt.Errorf("CancelAccountDeletion: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("CancelAccountDeletion: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// FetchAccountsDueForDeletion returns the users whose grace period has ended.
+func (q *Query) FetchAccountsDueForDeletion(now time.Time) ([]string, error) {
+	rows, err := q.db().Query(q.Rebind("SELECT id FROM users WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?"), now)
+	if err != nil {
+		return nil, fmt.Errorf("FetchAccountsDueForDeletion: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("FetchAccountsDueForDeletion: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteAccount permanently removes a user. The ON DELETE CASCADE constraints
+// of the user row remove their posts, comments, likes, messages, memberships,
+// sessions and so on.
+//
+// Groups created by the user are handed over first, following this rule:
+//  1. the longest-standing other admin of the group becomes its creator;
+//  2. without one, the longest-standing member is promoted to admin and becomes its creator;
+//  3. a group without any other member is deleted, with its posts, events and messages.
+//
+// It returns the uploaded files that belonged to the removed avatar, posts and
+// comments and are no longer referenced, for the caller to delete from disk.
+//
+// DeleteAccount runs in a transaction of its own, it must not be called from
+// WithTx.
+func (q *Query) DeleteAccount(userID string) ([]string, error) {
+	tx, err := q.Db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteAccount: %w", err)
+	}
+	defer tx.Rollback()
+
+	deletedGroups, err := q.handOverGroups(tx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteAccount: %w", err)
+	}
+
+	files, err := q.accountMedia(tx, userID, deletedGroups)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteAccount: %w", err)
+	}
+
+	for _, groupID := range deletedGroups {
+		// posts.group_id has no ON DELETE action, so the group's posts go first.
+		if _, err := tx.Exec(q.Rebind("DELETE FROM posts WHERE group_id = ?"), groupID); err != nil {
+			return nil, fmt.Errorf("DeleteAccount: failed to delete group posts: %w", err)
+		}
+		if _, err := tx.Exec(q.Rebind("DELETE FROM groups WHERE id = ?"), groupID); err != nil {
+			return nil, fmt.Errorf("DeleteAccount: failed to delete group: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(q.Rebind("DELETE FROM users WHERE id = ?"), userID); err != nil {
+		return nil, fmt.Errorf("DeleteAccount: failed to delete user: %w", err)
+	}
+	if _, err := tx.Exec(q.Rebind("DELETE FROM login_attempts WHERE attempt_key = ?"), "user:"+userID); err != nil {
+		return nil, fmt.Errorf("DeleteAccount: %w", err)
+	}
+
+	// The same file can in principle be shared, keep the ones still in use.
+	var orphaned []string
+	for _, file := range files {
+		var used bool
+		err := tx.QueryRow(q.Rebind(`
+			SELECT EXISTS(SELECT 1 FROM media WHERE url = ?)
+				OR EXISTS(SELECT 1 FROM users WHERE avatar = ? OR background_image = ?)
+		`), file, file, file).Scan(&used)
+		if err != nil {
+			return nil, fmt.Errorf("DeleteAccount: %w", err)
+		}
+		if !used {
+			orphaned = append(orphaned, file)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("DeleteAccount: %w", err)
+	}
+	return orphaned, nil
+}
+
+// handOverGroups gives every group created by userID a new creator, following
+// the rule documented on DeleteAccount, and returns the groups left without members.
+func (q *Query) handOverGroups(tx *sql.Tx, userID string) ([]string, error) {
+	rows, err := tx.Query(q.Rebind("SELECT id FROM groups WHERE creator_id = ?"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch created groups: %w", err)
+	}
+	var groupIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groupIDs = append(groupIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var orphaned []string
+	for _, groupID := range groupIDs {
+		var successor string
+		err := tx.QueryRow(q.Rebind(`
+			SELECT user_id FROM group_members
+			WHERE group_id = ? AND user_id != ?
+			ORDER BY role = 'admin' DESC, created_at ASC
+			LIMIT 1
+		`), groupID, userID).Scan(&successor)
+		if err == sql.ErrNoRows {
+			orphaned = append(orphaned, groupID)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to pick a new group creator: %w", err)
+		}
+
+		if _, err := tx.Exec(q.Rebind("UPDATE groups SET creator_id = ? WHERE id = ?"), successor, groupID); err != nil {
+			return nil, fmt.Errorf("failed to transfer group: %w", err)
+		}
+		if _, err := tx.Exec(q.Rebind("UPDATE group_members SET role = 'admin' WHERE group_id = ? AND user_id = ?"), groupID, successor); err != nil {
+			return nil, fmt.Errorf("failed to promote new group admin: %w", err)
+		}
+	}
+	return orphaned, nil
+}
+
+// accountMedia deletes the media rows attached to the user's posts and comments
+// and to the posts of groups about to be deleted, and returns the uploaded files
+// they and the user's avatar and background image point to.
+func (q *Query) accountMedia(tx *sql.Tx, userID string, deletedGroups []string) ([]string, error) {
+	postFilter := "user_id = ?"
+	args := []any{userID}
+	if len(deletedGroups) > 0 {
+		postFilter += " OR group_id IN (?" + strings.Repeat(", ?", len(deletedGroups)-1) + ")"
+		for _, id := range deletedGroups {
+			args = append(args, id)
+		}
+	}
+
+	parents := fmt.Sprintf(`
+		SELECT id FROM posts WHERE %[1]s
+		UNION SELECT id FROM comments WHERE user_id = ?
+		UNION SELECT id FROM comments WHERE post_id IN (SELECT id FROM posts WHERE %[1]s)
+	`, postFilter)
+	parentArgs := append(append(append([]any{}, args...), userID), args...)
+
+	rows, err := tx.Query(q.Rebind("DELETE FROM media WHERE parent_id IN ("+parents+") RETURNING url"), parentArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete media: %w", err)
+	}
+	var files []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		files = append(files, url)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var avatar, background sql.NullString
+	err = tx.QueryRow(q.Rebind("SELECT avatar, background_image FROM users WHERE id = ?"), userID).Scan(&avatar, &background)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to fetch profile images: %w", err)
+	}
+	files = append(files, avatar.String, background.String)
+
+	var uploaded []string
+	for _, file := range files {
+		if IsUploadedFile(file) {
+			uploaded = append(uploaded, file)
+		}
+	}
+	return uploaded, nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"social/pkg/model"
+	"social/pkg/util"
+)
+
+var ErrAPITokenNotFound = errors.New("token not found")
+
+// CreateAPIToken stores a new personal access token for userID.
+// A nil expiresAt creates a token that does not expire.
+func (q *Query) CreateAPIToken(userID, name, tokenHash string, scopes []string, expiresAt *time.Time) (string, error) {
+	id := util.UUIDGen()
+	err := q.InsertData("api_tokens", []string{
+		"id",
+		"user_id",
+		"name",
+		"token_hash",
+		"scopes",
+		"expires_at",
+	}, []any{
+		id,
+		userID,
+		name,
+		tokenHash,
+		strings.Join(scopes, " "),
+		expiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// FetchUserAPITokens returns every token of a user, newest first, including expired ones.
+func (q *Query) FetchUserAPITokens(userID string) ([]model.APIToken, error) {
+	rows, err := q.db().Query(q.Rebind(`
+		SELECT id, name, scopes, created_at, expires_at, last_used_at
+		FROM api_tokens
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`), userID)
+	if err != nil {
+		return nil, fmt.Errorf("FetchUserAPITokens: failed to query tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []model.APIToken{}
+	for rows.Next() {
+		var token model.APIToken
+		var scopes string
+		var expiresAt, lastUsedAt sql.NullTime
+
+		if err := rows.Scan(&token.ID, &token.Name, &scopes, &token.CreatedAt, &expiresAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("FetchUserAPITokens: failed to scan token: %w", err)
+		}
+
+		token.Scopes = strings.Fields(scopes)
+		if expiresAt.Valid {
+			token.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			token.LastUsedAt = &lastUsedAt.Time
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("FetchUserAPITokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// AuthenticateAPIToken looks up an unexpired token of an enabled user by its
+// hash, records that it was used and returns its id, owner and scopes.
+func (q *Query) AuthenticateAPIToken(tokenHash string) (tokenID, userID string, scopes []string, err error) {
+	now := time.Now()
+
+	var scopeList string
+	err = q.db().QueryRow(q.Rebind(`
+		UPDATE api_tokens
+		SET last_used_at = ?
+		WHERE token_hash = ? AND (expires_at IS NULL OR expires_at > ?)
+			AND user_id NOT IN (SELECT id FROM users WHERE disabled_at IS NOT NULL)
+		RETURNING id, user_id, scopes
+	`), now, tokenHash, now).Scan(&tokenID, &userID, &scopeList)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", nil, ErrAPITokenNotFound
+		}
+		return "", "", nil, fmt.Errorf("AuthenticateAPIToken: %w", err)
+	}
+	return tokenID, userID, strings.Fields(scopeList), nil
+}
+
+// FetchAPITokenUser returns the owner of an unexpired token, unless they are disabled.
+func (q *Query) FetchAPITokenUser(tokenHash string) (string, error) {
+	var userID string
+	err := q.db().QueryRow(q.Rebind(`
+		SELECT t.user_id FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = ? AND (t.expires_at IS NULL OR t.expires_at > ?) AND u.disabled_at IS NULL
+	`), tokenHash, time.Now()).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrAPITokenNotFound
+		}
+		return "", fmt.Errorf("FetchAPITokenUser: %w", err)
+	}
+	return userID, nil
+}
+
+// DeleteAPIToken revokes one of the user's tokens.
+func (q *Query) DeleteAPIToken(userID, tokenID string) error {
+	res, err := q.db().Exec(q.Rebind("DELETE FROM api_tokens WHERE id = ? AND user_id = ?"), tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteAPIToken: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteAPIToken: %w", err)
+	}
+	if affected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// DeleteAllAPITokens revokes every token of a user and returns their ids.
+func (q *Query) DeleteAllAPITokens(userID string) ([]string, error) {
+	rows, err := q.db().Query(q.Rebind("DELETE FROM api_tokens WHERE user_id = ? RETURNING id"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteAllAPITokens: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("DeleteAllAPITokens: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
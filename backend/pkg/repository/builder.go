@@ -0,0 +1,346 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Builder builds a statement on a single table. Table and column names are
+// checked against Schema and values against the type of their column, so no
+// identifier reaches the SQL without being known and values are always bound.
+//
+// The first error is kept and returned when the statement is run:
+//
+//	ids, err := q.Table("sessions").
+//		Where("user_id", "=", userID).
+//		Returning("id").
+//		Delete().
+//		Strings()
+type Builder struct {
+	q         *Query
+	table     string
+	where     []string
+	args      []any
+	orderBy   []string
+	limit     int
+	returning []string
+	err       error
+}
+
+// comparisons are the operators Where accepts.
+var comparisons = map[string]bool{"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// Table starts a statement on the named table.
+func (q *Query) Table(name string) *Builder {
+	b := &Builder{q: q, table: name}
+	if _, ok := Schema[name]; !ok {
+		b.err = fmt.Errorf("%w %q", ErrUnknownTable, name)
+	}
+	return b
+}
+
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *Builder) column(name string) string {
+	if _, err := Column(b.table, name); err != nil {
+		b.fail(err)
+	}
+	return name
+}
+
+func (b *Builder) value(column string, value any) any {
+	if err := CheckValue(b.table, column, value); err != nil {
+		b.fail(err)
+	}
+	return value
+}
+
+// Where adds the condition "column op value", joined to the others with AND.
+func (b *Builder) Where(column, op string, value any) *Builder {
+	if !comparisons[op] {
+		b.fail(fmt.Errorf("unsupported operator %q", op))
+	}
+	b.where = append(b.where, fmt.Sprintf("%s %s ?", b.column(column), op))
+	b.args = append(b.args, b.value(column, value))
+	return b
+}
+
+// WhereIn adds the condition "column IN (values...)". Without values it
+// matches no row.
+func (b *Builder) WhereIn(column string, values ...any) *Builder {
+	b.column(column)
+	if len(values) == 0 {
+		b.where = append(b.where, "1 = 0")
+		return b
+	}
+	for _, v := range values {
+		b.args = append(b.args, b.value(column, v))
+	}
+	b.where = append(b.where, fmt.Sprintf("%s IN (?%s)", column, strings.Repeat(", ?", len(values)-1)))
+	return b
+}
+
+// OrderBy sorts the selected rows by column, in descending order when desc is set.
+func (b *Builder) OrderBy(column string, desc bool) *Builder {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	b.orderBy = append(b.orderBy, b.column(column)+" "+direction)
+	return b
+}
+
+// Limit caps the number of selected rows.
+func (b *Builder) Limit(n int) *Builder {
+	if n <= 0 {
+		b.fail(fmt.Errorf("invalid limit %d", n))
+	}
+	b.limit = n
+	return b
+}
+
+// Returning makes an insert, update or delete return the given columns of the
+// rows it changed.
+func (b *Builder) Returning(columns ...string) *Builder {
+	for _, col := range columns {
+		b.returning = append(b.returning, b.column(col))
+	}
+	return b
+}
+
+// Select builds a SELECT of the given columns.
+func (b *Builder) Select(columns ...string) *Statement {
+	if len(columns) == 0 {
+		b.fail(errors.New("no columns to select"))
+	}
+	for _, col := range columns {
+		b.column(col)
+	}
+	b.rejectReturning("SELECT")
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), b.table)
+	return b.statement(query + b.whereClause() + b.selectClauses())
+}
+
+// Exists builds a query returning whether a matching row exists.
+func (b *Builder) Exists() *Statement {
+	b.rejectReturning("SELECT")
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s%s LIMIT 1)", b.table, b.whereClause())
+	return b.statement(query)
+}
+
+// Insert builds an INSERT of one row.
+func (b *Builder) Insert(columns []string, values []any) *Statement {
+	if len(columns) == 0 {
+		b.fail(errors.New("no columns provided"))
+	}
+	if len(columns) != len(values) {
+		b.fail(fmt.Errorf("number of columns (%d) does not match number of values (%d)", len(columns), len(values)))
+	}
+	if len(b.where) > 0 {
+		b.fail(errors.New("INSERT does not take conditions"))
+	}
+	b.rejectSelectClauses("INSERT")
+
+	var args []any
+	for i, col := range columns {
+		b.column(col)
+		if i < len(values) {
+			args = append(args, b.value(col, values[i]))
+		}
+	}
+	b.args = args
+
+	placeholders := "?" + strings.Repeat(", ?", max(len(columns)-1, 0))
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", b.table, strings.Join(columns, ", "), placeholders)
+	return b.statement(query + b.returningClause())
+}
+
+// Update builds an UPDATE setting the given columns of the matching rows. It
+// needs at least one condition.
+func (b *Builder) Update(columns []string, values []any) *Statement {
+	if len(columns) == 0 || len(columns) != len(values) {
+		b.fail(errors.New("columns and values length mismatch"))
+	}
+	b.requireWhere("UPDATE")
+	b.rejectSelectClauses("UPDATE")
+
+	set := make([]string, len(columns))
+	var args []any
+	for i, col := range columns {
+		set[i] = b.column(col) + " = ?"
+		if i < len(values) {
+			args = append(args, b.value(col, values[i]))
+		}
+	}
+	b.args = append(args, b.args...)
+
+	query := fmt.Sprintf("UPDATE %s SET %s", b.table, strings.Join(set, ", "))
+	return b.statement(query + b.whereClause() + b.returningClause())
+}
+
+// Delete builds a DELETE of the matching rows. It needs at least one condition.
+func (b *Builder) Delete() *Statement {
+	b.requireWhere("DELETE")
+	b.rejectSelectClauses("DELETE")
+	query := "DELETE FROM " + b.table
+	return b.statement(query + b.whereClause() + b.returningClause())
+}
+
+func (b *Builder) requireWhere(verb string) {
+	if len(b.where) == 0 {
+		b.fail(fmt.Errorf("%s needs a condition", verb))
+	}
+}
+
+// rejectSelectClauses fails statements given ORDER BY or LIMIT, which neither
+// SQLite nor PostgreSQL accept outside of SELECT.
+func (b *Builder) rejectSelectClauses(verb string) {
+	if len(b.orderBy) > 0 || b.limit > 0 {
+		b.fail(fmt.Errorf("%s does not take ORDER BY or LIMIT", verb))
+	}
+}
+
+func (b *Builder) rejectReturning(verb string) {
+	if len(b.returning) > 0 {
+		b.fail(fmt.Errorf("%s does not take RETURNING", verb))
+	}
+}
+
+func (b *Builder) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
+
+func (b *Builder) selectClauses() string {
+	var clauses string
+	if len(b.orderBy) > 0 {
+		clauses += " ORDER BY " + strings.Join(b.orderBy, ", ")
+	}
+	if b.limit > 0 {
+		clauses += fmt.Sprintf(" LIMIT %d", b.limit)
+	}
+	return clauses
+}
+
+func (b *Builder) returningClause() string {
+	if len(b.returning) == 0 {
+		return ""
+	}
+	return " RETURNING " + strings.Join(b.returning, ", ")
+}
+
+func (b *Builder) statement(query string) *Statement {
+	if b.err != nil {
+		return &Statement{err: b.err}
+	}
+	return &Statement{q: b.q, SQL: b.q.Rebind(query), Args: b.args}
+}
+
+// Statement is a statement built by a Builder, ready to run in the transaction
+// or on the database of the Query it was built from.
+type Statement struct {
+	q    *Query
+	SQL  string
+	Args []any
+	err  error
+
+	prepared bool
+}
+
+// Err returns the error that stopped the statement from being built.
+func (s *Statement) Err() error {
+	return s.err
+}
+
+// Prepared makes the statement run as a prepared statement kept by the Query,
+// for the queries run often enough that parsing them every time shows.
+func (s *Statement) Prepared() *Statement {
+	s.prepared = true
+	return s
+}
+
+// Exec runs a statement that returns no rows.
+func (s *Statement) Exec() (sql.Result, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.prepared {
+		stmt, err := s.q.prepared(s.SQL)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.Exec(s.Args...)
+	}
+	return s.q.db().Exec(s.SQL, s.Args...)
+}
+
+// Query runs a statement that returns rows.
+func (s *Statement) Query() (*sql.Rows, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.prepared {
+		stmt, err := s.q.prepared(s.SQL)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.Query(s.Args...)
+	}
+	return s.q.db().Query(s.SQL, s.Args...)
+}
+
+// Scan runs a statement returning at most one row and copies its columns into
+// dest. It returns sql.ErrNoRows when there is no row.
+func (s *Statement) Scan(dest ...any) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.prepared {
+		stmt, err := s.q.prepared(s.SQL)
+		if err != nil {
+			return err
+		}
+		return stmt.QueryRow(s.Args...).Scan(dest...)
+	}
+	return s.q.db().QueryRow(s.SQL, s.Args...).Scan(dest...)
+}
+
+// Strings runs a statement returning a single text column and collects its
+// non-NULL values.
+func (s *Statement) Strings() ([]string, error) {
+	rows, err := s.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v sql.NullString
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		if v.Valid {
+			values = append(values, v.String)
+		}
+	}
+	return values, rows.Err()
+}
+
+// whereEqual adds "column = value" for each of the columns, as the generic
+// helpers take their conditions.
+func (b *Builder) whereEqual(columns []string, values []any) *Builder {
+	for i, col := range columns {
+		b.Where(col, "=", values[i])
+	}
+	return b
+}
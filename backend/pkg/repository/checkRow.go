@@ -2,7 +2,6 @@ package repository
 
 import (
 	"fmt"
-	"strings"
 )
 
 // RowExists checks whether a row exists in the given table
@@ -19,20 +18,8 @@ func (q *Query) CheckRow(table string, whereColumns []string, whereValues []any)
 		return false, fmt.Errorf("RowExists: whereColumns and whereValues must be non-empty and of equal length")
 	}
 
-	// Build WHERE clause
-	whereClauses := make([]string, len(whereColumns))
-	for i, col := range whereColumns {
-		whereClauses[i] = fmt.Sprintf("%s = ?", col)
-	}
-
-	query := fmt.Sprintf(
-		"SELECT EXISTS(SELECT 1 FROM %s WHERE %s LIMIT 1)",
-		table,
-		strings.Join(whereClauses, " AND "),
-	)
-
 	var exists bool
-	err := q.Db.QueryRow(query, whereValues...).Scan(&exists)
+	err := q.Table(table).whereEqual(whereColumns, whereValues).Exists().Prepared().Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("RowExists: failed to execute existence check on table '%s': %w", table, err)
 	}
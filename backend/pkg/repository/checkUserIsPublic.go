@@ -7,7 +7,7 @@ import (
 
 func (q *Query) CheckUserIsPublic(userID string) (bool, error) {
 	var isPublic bool
-	err := q.Db.QueryRow("SELECT is_public FROM users WHERE id = ?", userID).Scan(&isPublic)
+	err := q.db().QueryRow(q.Rebind("SELECT is_public FROM users WHERE id = ?"), userID).Scan(&isPublic)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, fmt.Errorf("CheckUserIsPublic: User not found")
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"social/pkg/model"
+	"social/pkg/util"
+)
+
+var (
+	ErrExportNotFound = errors.New("export not found")
+	ErrExportPending  = errors.New("an export is already being prepared")
+)
+
+// CreateDataExport records a new pending export for the user. Only one export
+// can be in preparation at a time, which a partial unique index enforces so
+// that concurrent requests cannot both insert one.
+func (q *Query) CreateDataExport(userID string) (string, error) {
+	id := util.UUIDGen()
+	res, err := q.db().Exec(q.Rebind(`
+		INSERT INTO data_exports (id, user_id, status)
+		VALUES (?, ?, 'pending')
+		ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+	`), id, userID)
+	if err != nil {
+		return "", fmt.Errorf("CreateDataExport: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return "", fmt.Errorf("CreateDataExport: %w", err)
+	} else if n == 0 {
+		return "", ErrExportPending
+	}
+	return id, nil
+}
+
+// CompleteDataExport marks an export as ready to download from filePath until expiresAt.
+func (q *Query) CompleteDataExport(exportID, filePath string, expiresAt time.Time) error {
+	return q.UpdateData("data_exports", []string{"id"}, []any{exportID},
+		[]string{"status", "file_path", "completed_at", "expires_at"},
+		[]any{"ready", filePath, time.Now(), expiresAt})
+}
+
+// FailDataExport marks an export that could not be built.
+func (q *Query) FailDataExport(exportID string) error {
+	return q.UpdateData("data_exports", []string{"id"}, []any{exportID},
+		[]string{"status", "completed_at"}, []any{"failed", time.Now()})
+}
+
+// FailPendingExports marks the exports still in preparation as failed. It is
+// called at startup: the server building them has stopped and they would
+// otherwise keep their users from requesting a new one.
+func (q *Query) FailPendingExports() error {
+	return q.UpdateData("data_exports", []string{"status"}, []any{"pending"},
+		[]string{"status", "completed_at"}, []any{"failed", time.Now()})
+}
+
+// FetchDataExports returns the exports of a user, newest first.
+func (q *Query) FetchDataExports(userID string) ([]model.DataExport, error) {
+	rows, err := q.db().Query(q.Rebind(`
+		SELECT id, status, file_path, created_at, completed_at, expires_at
+		FROM data_exports
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`), userID)
+	if err != nil {
+		return nil, fmt.Errorf("FetchDataExports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []model.DataExport{}
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("FetchDataExports: %w", err)
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
+
+// FetchDataExport returns one of the user's exports.
+func (q *Query) FetchDataExport(userID, exportID string) (model.DataExport, error) {
+	row := q.db().QueryRow(q.Rebind(`
+		SELECT id, status, file_path, created_at, completed_at, expires_at
+		FROM data_exports
+		WHERE id = ? AND user_id = ?
+	`), exportID, userID)
+
+	export, err := scanDataExport(row)
+	if err == sql.ErrNoRows {
+		return model.DataExport{}, ErrExportNotFound
+	} else if err != nil {
+		return model.DataExport{}, fmt.Errorf("FetchDataExport: %w", err)
+	}
+	return export, nil
+}
+
+func scanDataExport(row interface{ Scan(...any) error }) (model.DataExport, error) {
+	var export model.DataExport
+	var filePath sql.NullString
+	var completedAt, expiresAt sql.NullTime
+
+	if err := row.Scan(&export.ID, &export.Status, &filePath, &export.CreatedAt, &completedAt, &expiresAt); err != nil {
+		return model.DataExport{}, err
+	}
+
+	export.FilePath = filePath.String
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+	return export, nil
+}
+
+// DeleteFinishedExports removes the user's ready and failed exports and returns
+// the archive files to delete from disk.
+func (q *Query) DeleteFinishedExports(userID string) ([]string, error) {
+	return q.deleteExports(q.Table("data_exports").Where("user_id", "=", userID).Where("status", "!=", "pending"))
+}
+
+// DeleteExpiredExports removes the exports past their expiry and returns the
+// archive files to delete from disk.
+func (q *Query) DeleteExpiredExports(now time.Time) ([]string, error) {
+	return q.deleteExports(q.Table("data_exports").Where("expires_at", "<", now))
+}
+
+func (q *Query) deleteExports(b *Builder) ([]string, error) {
+	files, err := b.Returning("file_path").Delete().Strings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete exports: %w", err)
+	}
+	return files, nil
+}
+
+// FetchGroupMemberships returns the groups the user is a member of.
+func (q *Query) FetchGroupMemberships(userID string) ([]model.GroupMembership, error) {
+	rows, err := q.db().Query(q.Rebind(`
+		SELECT g.id, g.title, gm.role, g.creator_id = gm.user_id, gm.created_at
+		FROM group_members gm
+		JOIN groups g ON g.id = gm.group_id
+		WHERE gm.user_id = ?
+		ORDER BY gm.created_at ASC
+	`), userID)
+	if err != nil {
+		return nil, fmt.Errorf("FetchGroupMemberships: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := []model.GroupMembership{}
+	for rows.Next() {
+		var m model.GroupMembership
+		var role sql.NullString
+		if err := rows.Scan(&m.GroupID, &m.Title, &role, &m.Creator, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("FetchGroupMemberships: %w", err)
+		}
+		m.Role = role.String
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+// FetchEventResponses returns the user's RSVPs to group events.
+func (q *Query) FetchEventResponses(userID string) ([]model.EventResponse, error) {
+	rows, err := q.db().Query(q.Rebind(`
+		SELECT e.id, e.group_id, e.title, e.event_time, ea.status, ea.created_at
+		FROM event_attendance ea
+		JOIN events e ON e.id = ea.event_id
+		WHERE ea.user_id = ?
+		ORDER BY e.event_time ASC
+	`), userID)
+	if err != nil {
+		return nil, fmt.Errorf("FetchEventResponses: %w", err)
+	}
+	defer rows.Close()
+
+	responses := []model.EventResponse{}
+	for rows.Next() {
+		var r model.EventResponse
+		if err := rows.Scan(&r.EventID, &r.GroupID, &r.Title, &r.EventTime, &r.Status, &r.RespondedAt); err != nil {
+			return nil, fmt.Errorf("FetchEventResponses: %w", err)
+		}
+		responses = append(responses, r)
+	}
+	return responses, rows.Err()
+}
+
+// FetchConversationPartners returns every user the user exchanged private messages with.
+func (q *Query) FetchConversationPartners(userID string) ([]string, error) {
+	rows, err := q.db().Query(q.Rebind(`
+		SELECT receiver_id FROM private_messages WHERE sender_id = ?
+		UNION
+		SELECT sender_id FROM private_messages WHERE receiver_id = ?
+	`), userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("FetchConversationPartners: %w", err)
+	}
+	defer rows.Close()
+
+	var partners []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("FetchConversationPartners: %w", err)
+		}
+		partners = append(partners, id)
+	}
+	return partners, rows.Err()
+}
+
+// FetchSentGroupMessages returns the group chat messages the user sent, in
+// every group including the ones they have since left.
+func (q *Query) FetchSentGroupMessages(userID string) ([]model.GroupMessage, error) {
+	rows, err := q.db().Query(q.Rebind(`
+		SELECT id, group_id, sender_id, content, created_at
+		FROM group_messages
+		WHERE sender_id = ?
+		ORDER BY created_at ASC
+	`), userID)
+	if err != nil {
+		return nil, fmt.Errorf("FetchSentGroupMessages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []model.GroupMessage{}
+	for rows.Next() {
+		var msg model.GroupMessage
+		var content sql.NullString
+		if err := rows.Scan(&msg.ID, &msg.GroupId, &msg.SenderID, &content, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("FetchSentGroupMessages: %w", err)
+		}
+		msg.Content = content.String
+		msg.Message = content.String
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// FetchUserMediaFiles returns the uploaded files of the user's posts and
+// comments, avatar and background image.
+func (q *Query) FetchUserMediaFiles(userID string) ([]string, error) {
+	rows, err := q.db().Query(q.Rebind(`
+		SELECT url FROM media
+		WHERE parent_id IN (SELECT id FROM posts WHERE user_id = ?)
+			OR parent_id IN (SELECT id FROM comments WHERE user_id = ?)
+		UNION
+		SELECT avatar FROM users WHERE id = ? AND avatar IS NOT NULL
+		UNION
+		SELECT background_image FROM users WHERE id = ? AND background_image IS NOT NULL
+	`), userID, userID, userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("FetchUserMediaFiles: %w", err)
+	}
+	defer rows.Close()
+
+	var files []string
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return nil, fmt.Errorf("FetchUserMediaFiles: %w", err)
+		}
+		if IsUploadedFile(file) {
+			files = append(files, file)
+		}
+	}
+	return files, rows.Err()
+}
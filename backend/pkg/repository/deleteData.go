@@ -2,7 +2,6 @@ package repository
 
 import (
 	"fmt"
-	"strings"
 )
 
 // DeleteData performs a conditional DELETE operation on any table with dynamic WHERE clauses.
@@ -28,19 +27,7 @@ func (q *Query) DeleteData(table string, whereColumns []string, whereValues []an
 		return fmt.Errorf("DeleteData: whereColumns and whereValues length mismatch")
 	}
 
-	// Build WHERE clause: col1 = ? AND col2 = ? ...
-	whereClauses := make([]string, len(whereColumns))
-	for i, col := range whereColumns {
-		whereClauses[i] = fmt.Sprintf("%s = ?", col)
-	}
-
-	query := fmt.Sprintf(
-		"DELETE FROM %s WHERE %s",
-		table,
-		strings.Join(whereClauses, " AND "),
-	)
-
-	_, err := q.Db.Exec(query, whereValues...)
+	_, err := q.Table(table).whereEqual(whereColumns, whereValues).Delete().Exec()
 	if err != nil {
 		return fmt.Errorf("DeleteData failed: %w", err)
 	}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect is the SQL dialect of the database a Query runs against.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// Rebind rewrites the "?" placeholders of query into the form the database
+// expects. Queries in this package are written with "?" and must be passed
+// through Rebind before they are run.
+func (q *Query) Rebind(query string) string {
+	if q.Dialect != Postgres {
+		return query
+	}
+	return rebindNumbered(query)
+}
+
+// rebindNumbered replaces each "?" outside of quoted strings, quoted identifiers
+// and comments with "$1", "$2" and so on.
+func rebindNumbered(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			// A doubled quote inside the literal is an escaped quote, which the
+			// loop reads as the end of one literal and the start of the next.
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DisableUser stops a user from logging in and signs them out everywhere: their
+// sessions, API tokens and pending two-factor logins are deleted. It returns the
+// ids of the deleted sessions and false when the user was already disabled.
+func (q *Query) DisableUser(userID string) (sessionIDs []string, disabled bool, err error) {
+	err = q.withTx(context.Background(), func(tx *Query) error {
+		res, err := tx.db().Exec(tx.Rebind("UPDATE users SET disabled_at = ? WHERE id = ? AND disabled_at IS NULL"), time.Now(), userID)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		disabled = affected > 0
+
+		if sessionIDs, err = tx.DeleteAllUserSessions(userID); err != nil {
+			return err
+		}
+		if _, err := tx.DeleteAllAPITokens(userID); err != nil {
+			return err
+		}
+		_, err = tx.Table("mfa_challenges").Where("user_id", "=", userID).Delete().Exec()
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("DisableUser: %w", err)
+	}
+	return sessionIDs, disabled, nil
+}
+
+// EnableUser lets a disabled user log in again and reports whether they were disabled.
+func (q *Query) EnableUser(userID string) (bool, error) {
+	res, err := q.db().Exec(q.Rebind("UPDATE users SET disabled_at = NULL WHERE id = ? AND disabled_at IS NOT NULL"), userID)
+	if err != nil {
+		return false, fmt.Errorf("EnableUser: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("EnableUser: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// IsUserDisabled reports whether the user was disabled by an administrator.
+func (q *Query) IsUserDisabled(userID string) (bool, error) {
+	var disabled bool
+	err := q.db().QueryRow(q.Rebind("SELECT disabled_at IS NOT NULL FROM users WHERE id = ?"), userID).Scan(&disabled)
+	if err != nil {
+		return false, fmt.Errorf("IsUserDisabled: %w", err)
+	}
+	return disabled, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"social/pkg/util"
+)
+
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// CreateEmailVerification stores the hash of a verification token for the given
+// address, replacing any verification the user had pending.
+func (q *Query) CreateEmailVerification(userID, email, tokenHash string, expiresAt time.Time) error {
+	if err := q.DeleteData("email_verifications", []string{"user_id"}, []any{userID}); err != nil {
+		return fmt.Errorf("CreateEmailVerification: %w", err)
+	}
+
+	return q.InsertData("email_verifications", []string{
+		"id",
+		"user_id",
+		"email",
+		"token_hash",
+		"expires_at",
+	}, []any{
+		util.UUIDGen(),
+		userID,
+		email,
+		tokenHash,
+		expiresAt,
+	})
+}
+
+// VerifyEmail consumes a verification token and marks the user's email as verified.
+// The token is rejected when it expired or when the user changed the address since it was sent.
+// The token is only deleted along with the update, so a failure leaves it usable.
+func (q *Query) VerifyEmail(tokenHash string) (string, error) {
+	now := time.Now()
+
+	var userID string
+	err := q.withTx(context.Background(), func(tx *Query) error {
+		var email string
+		err := tx.db().QueryRow(tx.Rebind(`
+			DELETE FROM email_verifications
+			WHERE token_hash = ? AND expires_at > ?
+			RETURNING user_id, email
+		`), tokenHash, now).Scan(&userID, &email)
+		if err == sql.ErrNoRows {
+			return ErrInvalidVerificationToken
+		} else if err != nil {
+			return err
+		}
+
+		res, err := tx.db().Exec(tx.Rebind("UPDATE users SET verified_at = ? WHERE id = ? AND email = ?"), now, userID, email)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return ErrInvalidVerificationToken
+		}
+		return nil
+	})
+	if errors.Is(err, ErrInvalidVerificationToken) {
+		return "", err
+	} else if err != nil {
+		return "", fmt.Errorf("VerifyEmail: %w", err)
+	}
+	return userID, nil
+}
+
+// IsEmailVerified reports whether the user confirmed their current email address.
+func (q *Query) IsEmailVerified(userID string) (bool, error) {
+	var verified bool
+	err := q.db().QueryRow(q.Rebind("SELECT verified_at IS NOT NULL FROM users WHERE id = ?"), userID).Scan(&verified)
+	if err != nil {
+		return false, fmt.Errorf("IsEmailVerified: %w", err)
+	}
+	return verified, nil
+}
+
+// FetchUserEmail returns the current email address of a user.
+func (q *Query) FetchUserEmail(userID string) (string, error) {
+	var email string
+	err := q.db().QueryRow(q.Rebind("SELECT email FROM users WHERE id = ?"), userID).Scan(&email)
+	if err != nil {
+		return "", fmt.Errorf("FetchUserEmail: %w", err)
+	}
+	return email, nil
+}
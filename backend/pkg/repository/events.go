@@ -25,7 +25,7 @@ func (q *Query) CheckForRsvp(eventID, userID string) (bool, error) {
 	`
 
 	var exists int
-	err := q.Db.QueryRow(query, userID, eventID).Scan(&exists)
+	err := q.db().QueryRow(q.Rebind(query), userID, eventID).Scan(&exists)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// Row does not exist
@@ -52,7 +52,7 @@ func (q *Query) FetchAttendingMembersCount(eventID string) (int, error) {
 	`
 
 	var count int
-	err := q.Db.QueryRow(query, eventID).Scan(&count)
+	err := q.db().QueryRow(q.Rebind(query), eventID).Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// Row does not exist
@@ -76,7 +76,7 @@ func (q *Query) GetUserRsvpStatus(eventID, userID string) (string, error) {
 	`
 
 	var status string
-	err := q.Db.QueryRow(query, userID, eventID).Scan(&status)
+	err := q.db().QueryRow(q.Rebind(query), userID, eventID).Scan(&status)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// User hasn't RSVP'd yet
@@ -3,7 +3,7 @@ package repository
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"social/pkg/model"
 )
 
@@ -16,9 +16,9 @@ func (q *Query) FetchAllGroups(userid string) ([]model.Groups, error) {
 		FROM groups g
 		JOIN users u ON u.id = g.creator_id
 	`
-	rows, err := q.Db.Query(query)
+	rows, err := q.db().Query(q.Rebind(query))
 	if err != nil {
-		log.Printf("FetchAllGroups: db error: %v", err)
+		slog.Error("FetchAllGroups: db error", "err", err)
 		return nil, fmt.Errorf("failed to fetch groups: %w", err)
 	}
 	defer rows.Close()
@@ -39,13 +39,13 @@ func (q *Query) FetchAllGroups(userid string) ([]model.Groups, error) {
 
 		// Always fetch the current user's join request (if any)
 		if userid != "" {
-			row := q.Db.QueryRow(`
+			row := q.db().QueryRow(q.Rebind(`
 				SELECT gjr.id, gjr.user_id, gjr.created_at, gjr.status, u.id, u.first_name, u.last_name, u.nickname, u.avatar
 				FROM group_join_requests gjr
 				JOIN users u ON gjr.user_id = u.id
 				WHERE gjr.group_id = ? AND gjr.user_id = ?
 				ORDER BY gjr.created_at DESC LIMIT 1
-			`, group.ID, userid)
+			`), group.ID, userid)
 			var req model.GroupJoinRequest
 			err := row.Scan(
 				&req.ID,
@@ -74,14 +74,14 @@ func (q *Query) CheckUserMembershipInGroup(userID string, group *model.Groups) {
 	var role string
 
 	query := `SELECT role FROM group_members WHERE user_id = ? AND group_id = ?`
-	err := q.Db.QueryRow(query, userID, group.ID).Scan(&role)
+	err := q.db().QueryRow(q.Rebind(query), userID, group.ID).Scan(&role)
 
 	switch {
 	case err == sql.ErrNoRows:
 		group.IsJoined = false
 		group.UserRole = ""
 	case err != nil:
-		log.Printf("CheckUserMembershipInGroup: db error: %v", err)
+		slog.Error("CheckUserMembershipInGroup: db error", "err", err)
 		group.IsJoined = false
 		group.UserRole = ""
 	default:
@@ -2,7 +2,7 @@ package repository
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"social/pkg/model"
 )
@@ -94,9 +94,9 @@ func (q *Query) FetchNonMutual(userID string) ([]model.Follower, error) {
 		ORDER BY u.created_at ASC
         LIMIT 100
 	`
-	rows, err := q.Db.Query(query, userID, userID, userID, userID, userID, userID, userID)
+	rows, err := q.db().Query(q.Rebind(query), userID, userID, userID, userID, userID, userID, userID)
 	if err != nil {
-		log.Printf("FetchAllUsers: db error: %v", err)
+		slog.Error("FetchAllUsers: db error", "err", err)
 		return nil, fmt.Errorf("failed to fetch non mutuL users: %w", err)
 	}
 
@@ -138,7 +138,7 @@ func (q *Query) FetchSentFollowRequests(userID string) ([]model.Follower, error)
 		LIMIT 100
     `
 
-	rows, err := q.Db.Query(query, userID)
+	rows, err := q.db().Query(q.Rebind(query), userID)
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +174,7 @@ func (q *Query) FetchReceivedFollowRequests(userID string) ([]model.Follower, er
 		LIMIT 100
     `
 
-	rows, err := q.Db.Query(query, userID)
+	rows, err := q.db().Query(q.Rebind(query), userID)
 	if err != nil {
 		return nil, err
 	}
@@ -211,7 +211,7 @@ func (q *Query) GetFollowersNotFollowedBack(userID string) ([]model.Follower, er
         LIMIT 100
     `
 
-	rows, err := q.Db.Query(query, userID, userID)
+	rows, err := q.db().Query(q.Rebind(query), userID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("GetFollowersNotFollowedBack: failed to query followers: %w", err)
 	}
@@ -4,30 +4,44 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 )
 
 // GetUserCredentials takes in either a nickname or email and returns the userid & password, and an error if non are found
 // it checks for the email first then the nickname if you did not pass the email.
+// Disabled users are not found.
 func (q *Query) GetUserCredentials(identifier string) (userID, password string, err error) {
-	row := q.Db.QueryRow(`
+	row := q.db().QueryRow(q.Rebind(`
         SELECT id, password 
         FROM users 
-        WHERE email = ? OR nickname = ?
+        WHERE (email = ? OR nickname = ?) AND disabled_at IS NULL
         LIMIT 1
-    `, identifier, identifier)
+    `), identifier, identifier)
 
 	err = row.Scan(&userID, &password)
 	if err == sql.ErrNoRows {
-		log.Printf("Login failed: user not found by identifier '%s'", identifier)
+		slog.Debug("login failed: unknown email or nickname")
 		return "", "", errors.New("user not found by email or nickname")
 	}
 	if err != nil {
-		log.Printf("Database error when retrieving credentials for '%s': %v", identifier, err)
+		slog.Error("failed to retrieve credentials", "err", err)
 		return "", "", fmt.Errorf("database error: %w", err)
 	}
 
-	log.Printf("User found: %s successfully authenticated", userID)
+	slog.Debug("credentials found", "user_id", userID)
 
 	return userID, password, nil
 }
+
+// FetchPasswordHash returns the stored password hash of a user.
+func (q *Query) FetchPasswordHash(userID string) (string, error) {
+	var password string
+	err := q.db().QueryRow(q.Rebind("SELECT password FROM users WHERE id = ?"), userID).Scan(&password)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.New("user not found")
+		}
+		return "", fmt.Errorf("database error: %w", err)
+	}
+	return password, nil
+}
@@ -12,7 +12,7 @@ func (q *Query) FetchGroupAdmin(groupID string) (string, error) {
 		WHERE id = ?
 	`
 	var admin string
-	err := q.Db.QueryRow(query, groupID).Scan(&admin)
+	err := q.db().QueryRow(q.Rebind(query), groupID).Scan(&admin)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", nil
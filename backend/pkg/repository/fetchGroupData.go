@@ -37,13 +37,13 @@ func (q *Query) FetchGroupData(groupid string, userID string) (model.GroupData,
 
 	// Always fetch the current user's join request (if any)
 	if userID != "" {
-		row := q.Db.QueryRow(`
+		row := q.db().QueryRow(q.Rebind(`
 			SELECT gjr.id, gjr.user_id, gjr.created_at, gjr.status, u.id, u.first_name, u.last_name, u.nickname, u.avatar
 			FROM group_join_requests gjr
 			JOIN users u ON gjr.user_id = u.id
 			WHERE gjr.group_id = ? AND gjr.user_id = ?
 			ORDER BY gjr.created_at DESC LIMIT 1
-		`, groupid, userID)
+		`), groupid, userID)
 		var req model.GroupJoinRequest
 		err := row.Scan(
 			&req.ID,
@@ -75,13 +75,13 @@ func (q *Query) FetchGroupData(groupid string, userID string) (model.GroupData,
 }
 
 func (q *Query) fetchGroupInfo(groupid string, group *model.GroupData) error {
-	row := q.Db.QueryRow(`
+	row := q.db().QueryRow(q.Rebind(`
         SELECT g.id, g.title, g.description,
 		g.created_at, u.id, u.first_name, u.last_name, u.nickname, u.avatar
 		FROM groups g
 		JOIN users u ON u.id = g.creator_id
         WHERE g.id = ?
-    `, groupid)
+    `), groupid)
 
 	user := model.Creator{}
 
@@ -141,7 +141,7 @@ func (q *Query) FetchGroupPostWithMedia(groupid string, user_id string) ([]model
 		WHERE p.group_id = ?
 		ORDER BY p.created_at DESC
 	`
-	rows, err := q.Db.Query(query, user_id, groupid)
+	rows, err := q.db().Query(q.Rebind(query), user_id, groupid)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch posts: %w", err)
 	}
@@ -223,13 +223,13 @@ func (q *Query) FetchGroupPostWithMedia(groupid string, user_id string) ([]model
 }
 
 func (q *Query) fetchGroupMembers(groupid string, group *model.GroupData) error {
-	rows, err := q.Db.Query(`
+	rows, err := q.db().Query(q.Rebind(`
 		SELECT
 			u.id, u.first_name, u.last_name, u.nickname, u.avatar, gm.role
 		FROM group_members gm
 		JOIN users u ON u.id = gm.user_id
 		WHERE gm.group_id = ?
-	`, groupid)
+	`), groupid)
 	if err != nil {
 		return err
 	}
@@ -257,7 +257,7 @@ func (q *Query) fetchGroupMembers(groupid string, group *model.GroupData) error
 
 func (q *Query) fetchGroupEvents(groupID string, group *model.GroupData, userID string) error {
 	// First query: Get all events
-	eventRows, err := q.Db.Query(`
+	eventRows, err := q.db().Query(q.Rebind(`
 		SELECT
 			e.id, e.title, e.description, e.event_time, e.created_at, e.location,
 			ec.id, ec.first_name, ec.last_name, ec.nickname, ec.avatar, e.going_count
@@ -265,7 +265,7 @@ func (q *Query) fetchGroupEvents(groupID string, group *model.GroupData, userID
 		JOIN users ec ON ec.id = e.creator_id
 		WHERE e.group_id = ?
 		ORDER BY e.event_time
-	`, groupID)
+	`), groupID)
 	if err != nil {
 		return err
 	}
@@ -321,7 +321,7 @@ func (q *Query) fetchGroupEvents(groupID string, group *model.GroupData, userID
 			ORDER BY ea.event_id, u.first_name
 		`, strings.Join(placeholders, ","))
 
-		attendeeRows, err := q.Db.Query(attendeeQuery, args...)
+		attendeeRows, err := q.db().Query(q.Rebind(attendeeQuery), args...)
 		if err != nil {
 			return err
 		}
@@ -361,12 +361,12 @@ func (q *Query) fetchGroupEvents(groupID string, group *model.GroupData, userID
 
 func (q *Query) FetchGroupId(title string) (string, error) {
 	var id string
-	row := q.Db.QueryRow(`
+	row := q.db().QueryRow(q.Rebind(`
 		SELECT id
 		FROM groups
 		WHERE title = ?
 		LIMIT 1
-	`, title)
+	`), title)
 
 	err := row.Scan(&id)
 	if err == sql.ErrNoRows {
@@ -395,7 +395,7 @@ func (q *Query) FetchGroupJoinRequest(groupID string, group *model.GroupData) er
 			JOIN users u ON gjr.user_id = u.id
 			WHERE gjr.group_id = ? AND gjr.status = 'pending'`
 
-	rows, err := q.Db.Query(query, groupID)
+	rows, err := q.db().Query(q.Rebind(query), groupID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch group join request: %w", err)
 	}
@@ -429,3 +429,16 @@ func (q *Query) FetchGroupJoinRequest(groupID string, group *model.GroupData) er
 	group.JoinRequest = joinRequests
 	return nil
 }
+
+// FetchGroupTitle returns the title of the group with the given id.
+func (q *Query) FetchGroupTitle(groupID string) (string, error) {
+	var title string
+	err := q.db().QueryRow(q.Rebind("SELECT title FROM groups WHERE id = ?"), groupID).Scan(&title)
+	if err == sql.ErrNoRows {
+		return "", ErrGroupNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("FetchGroupTitle: %w", err)
+	}
+	return title, nil
+}
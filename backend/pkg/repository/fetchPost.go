@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -9,17 +10,23 @@ import (
 	"social/pkg/model"
 )
 
-func (q *Query) FetchPostWithMedia(id string) ([]model.Post, error) {
-	query := `
-		SELECT 
-			p.id, p.content, 
+var ErrPostNotFound = errors.New("post not found")
+
+// postColumns are the columns scanPosts reads, from posts p joined with their
+// media m and author u. The liked column takes the viewer as parameter.
+const postColumns = `
+			p.id, p.group_id, p.content,
 			p.likes_count, p.dislikes_count, p.comments_count, p.privacy, p.created_at,
 			m.id, m.url, u.id, u.first_name, u.last_name, u.nickname, u.avatar,
 			EXISTS (
 				SELECT 1 from post_likes pl
 				WHERE pl.post_id = p.id
 				AND pl.user_id = ?
-			) AS liked
+			) AS liked`
+
+func (q *Query) FetchPostWithMedia(id string) ([]model.Post, error) {
+	query := `
+		SELECT ` + postColumns + `
 		FROM posts p
 		LEFT JOIN media m ON m.parent_id = p.id
 		JOIN users u ON u.id = p.user_id
@@ -48,17 +55,89 @@ func (q *Query) FetchPostWithMedia(id string) ([]model.Post, error) {
   		)
 		ORDER BY p.created_at DESC
 	`
-	rows, err := q.Db.Query(query, id, id, id, id)
+	stmt, err := q.prepared(q.Rebind(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+	}
+	rows, err := stmt.Query(id, id, id, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch posts: %w", err)
 	}
 	defer rows.Close()
 
+	return scanPosts(rows)
+}
+
+// FetchPost returns a post with its media and comments when the user may see
+// it: by the privacy rules of the feed, or by being a member of its group for
+// group posts. It returns ErrPostNotFound otherwise.
+func (q *Query) FetchPost(postID, userID string) (model.Post, error) {
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts p
+		LEFT JOIN media m ON m.parent_id = p.id
+		JOIN users u ON u.id = p.user_id
+		WHERE p.id = ?
+		 AND (
+			p.user_id = ?
+			OR (p.group_id IS NOT NULL AND EXISTS (
+				SELECT 1 FROM group_members gm
+				WHERE gm.group_id = p.group_id
+				AND gm.user_id = ?
+			))
+			OR (p.group_id IS NULL AND (
+				p.privacy = 'public'
+				OR (p.privacy = 'almost_private' AND EXISTS (
+					SELECT 1 FROM user_follows uf
+					WHERE uf.following_id = p.user_id
+					AND uf.follower_id = ?
+					AND uf.status = 'accepted'
+				))
+				OR (p.privacy = 'private' AND EXISTS (
+					SELECT 1 FROM post_visibility pv
+					WHERE pv.post_id = p.id
+					AND pv.user_id = ?
+				))
+			))
+		)
+	`
+	stmt, err := q.prepared(q.Rebind(query))
+	if err != nil {
+		return model.Post{}, fmt.Errorf("failed to fetch post: %w", err)
+	}
+	rows, err := stmt.Query(userID, postID, userID, userID, userID, userID)
+	if err != nil {
+		return model.Post{}, fmt.Errorf("failed to fetch post: %w", err)
+	}
+	defer rows.Close()
+
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return model.Post{}, err
+	}
+	if len(posts) == 0 {
+		return model.Post{}, ErrPostNotFound
+	}
+
+	comments, err := q.FetchCommentsWithMedia([]string{postID}, userID)
+	if err != nil {
+		return model.Post{}, fmt.Errorf("failed to get comments: %w", err)
+	}
+	post := posts[0]
+	post.Comments = comments[postID]
+	return post, nil
+}
+
+// scanPosts reads rows of postColumns, one per media of a post, into posts in
+// the order of the rows.
+func scanPosts(rows *sql.Rows) ([]model.Post, error) {
+	var order []string
 	postsMap := make(map[string]*model.Post)
 
 	for rows.Next() {
 		var (
 			postID        string
+			groupID       sql.NullString
 			content       string
 			likesCount    int
 			dislikesCount int
@@ -76,7 +155,7 @@ func (q *Query) FetchPostWithMedia(id string) ([]model.Post, error) {
 		)
 
 		err := rows.Scan(
-			&postID, &content,
+			&postID, &groupID, &content,
 			&likesCount, &dislikesCount, &commentsCount, &privacy, &createdAt,
 			&mediaID, &mediaURL, &userID, &firstname, &lastname, &nickname, &avatar,
 			&isLiked,
@@ -100,7 +179,7 @@ func (q *Query) FetchPostWithMedia(id string) ([]model.Post, error) {
 			post = &model.Post{
 				ID:            postID,
 				User:          *user,
-				GroupID:       "",
+				GroupID:       groupID.String,
 				Content:       content,
 				LikesCount:    likesCount,
 				DislikesCount: dislikesCount,
@@ -111,6 +190,7 @@ func (q *Query) FetchPostWithMedia(id string) ([]model.Post, error) {
 				IsLiked:       isLiked,
 			}
 			postsMap[postID] = post
+			order = append(order, postID)
 		}
 
 		if mediaID.Valid && mediaURL.Valid {
@@ -119,13 +199,14 @@ func (q *Query) FetchPostWithMedia(id string) ([]model.Post, error) {
 			})
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch posts: %w", err)
+	}
 
-	// Convert map to slice
 	var posts []model.Post
-	for _, post := range postsMap {
-		posts = append(posts, *post)
+	for _, id := range order {
+		posts = append(posts, *postsMap[id])
 	}
-
 	return posts, nil
 }
 
@@ -162,7 +243,7 @@ func (q *Query) FetchCommentsWithMedia(postIDs []string, userID string) (map[str
 			ORDER BY c.created_at ASC
 		`, strings.Join(placeholders, ","))
 
-	rows, err := q.Db.Query(query, args...)
+	rows, err := q.db().Query(q.Rebind(query), args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch comments: %w", err)
 	}
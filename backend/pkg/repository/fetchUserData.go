@@ -105,14 +105,14 @@ func (q *Query) FetchUserComments(userid string, user *model.UserData) error {
 
 // fetchUserInfo first fetches userinfo from the user info table
 func (q *Query) FetchUserInfo(userid string, user *model.UserData) error {
-	row := q.Db.QueryRow(`
+	row := q.db().QueryRow(q.Rebind(`
         SELECT email, first_name, last_name,
 		date_of_birth, avatar, nickname , about_me, created_at ,
 		is_public , background_image
         FROM users
         WHERE id = ?
         LIMIT 1
-    `, userid)
+    `), userid)
 
 	var bgImage sql.NullString
 	// Scan background_image into sql.NullString
@@ -150,7 +150,7 @@ func (q *Query) fetchUserPost(userID string, user *model.UserData) error {
 		ORDER BY p.created_at DESC
 	`
 
-	rows, err := q.Db.Query(query, userID, userID)
+	rows, err := q.db().Query(q.Rebind(query), userID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch user posts: %w", err)
 	}
@@ -226,7 +226,7 @@ func (q *Query) getAllCommentedPostID(userid string) ([]string, error) {
 		WHERE user_id = ?
 	`
 
-	rows, err := q.Db.Query(query, userid)
+	rows, err := q.db().Query(q.Rebind(query), userid)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query posts: %v", err)
 	}
@@ -274,7 +274,7 @@ func (q *Query) fetchPostsByIDs(postIDs []string) ([]model.Post, error) {
 		args[i] = id
 	}
 
-	rows, err := q.Db.Query(query, args...)
+	rows, err := q.db().Query(q.Rebind(query), args...)
 	if err != nil {
 		return []model.Post{}, fmt.Errorf("failed to fetch posts {user commented post} by IDs: %w", err)
 	}
@@ -385,7 +385,7 @@ func (q *Query) getallLikedPostIDs(userid string) ([]string, error) {
 	WHERE user_id = ?
 `
 
-	rows, err := q.Db.Query(query, userid)
+	rows, err := q.db().Query(q.Rebind(query), userid)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query posts: %v", err)
 	}
@@ -30,10 +30,10 @@ func (q *Query) GetUserNotifications(userID string) ([]model.UserNotification, e
 		FROM notifications un
 		JOIN users u ON u.id = un.actor_id
 		WHERE un.recipient_id = ?
-        AND un.is_read = 0;
+        AND un.is_read = FALSE;
 	`
 
-	rows, err := q.Db.Query(query, userID, userID)
+	rows, err := q.db().Query(q.Rebind(query), userID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user notifications: %w", err)
 	}
@@ -19,7 +19,7 @@ func (q *Query) FetchFollowers(userID string) ([]model.Follower, error) {
         LIMIT 100
     `
 
-	rows, err := q.Db.Query(query, userID)
+	rows, err := q.db().Query(q.Rebind(query), userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query followers: %w", err)
 	}
@@ -54,7 +54,7 @@ func (q *Query) FetchFollowing(userID string) ([]model.Follower, error) {
         LIMIT 100
     `
 
-	rows, err := q.Db.Query(query, userID)
+	rows, err := q.db().Query(q.Rebind(query), userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query following: %w", err)
 	}
@@ -85,7 +85,7 @@ func (q *Query) FollowExists(followerID, followingID string) (exists bool, statu
 		LIMIT 1
 	`
 
-	err = q.Db.QueryRow(query, followerID, followingID).Scan(&status)
+	err = q.db().QueryRow(q.Rebind(query), followerID, followingID).Scan(&status)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, "", nil // not found
@@ -1,11 +1,11 @@
 package repository
 
 func (q *Query) GetUserGroupIDs(userID string) ([]string, error) {
-	rows, err := q.Db.Query(`
+	rows, err := q.db().Query(q.Rebind(`
 		SELECT group_id
 		FROM group_members
 		WHERE user_id = ?
-	`, userID)
+	`), userID)
 	if err != nil {
 		return nil, err
 	}
@@ -28,10 +28,10 @@ func (q *Query) GetUserGroupIDs(userID string) ([]string, error) {
 }
 
 func (q *Query) FetchAllGroupMembersId(groupID string) ([]string, error) {
-	rows, err := q.Db.Query(`
+	rows, err := q.db().Query(q.Rebind(`
 		SELECT user_id
 		FROM group_members
-		WHERE group_id = ?`, groupID)
+		WHERE group_id = ?`), groupID)
 
 	if err != nil {
 		return nil, err
@@ -3,38 +3,27 @@ package repository
 import (
 	"database/sql"
 	"fmt"
-	"strings"
+	"sync"
 )
 
 type Query struct {
 	Db *sql.DB
+	// Reader, when set, runs the SELECT queries outside of transactions, so
+	// that they do not wait for the single connection SQLite writes on.
+	Reader *sql.DB
+	// Dialect selects the placeholder syntax of the queries, SQLite when empty.
+	Dialect Dialect
+
+	// tx is the transaction the queries run in, set by WithTx.
+	tx *sql.Tx
+	// stmts holds the prepared statements of the hot queries, created on first
+	// use and shared with the transactions of the Query.
+	stmts     *stmtCache
+	stmtsOnce sync.Once
 }
 
 func (q *Query) InsertData(table string, columns []string, values []any) error {
-	// Validate input
-	if len(columns) == 0 {
-		return fmt.Errorf("no columns provided")
-	}
-	if len(columns) != len(values) {
-		return fmt.Errorf("number of columns (%d) does not match number of values (%d)", len(columns), len(values))
-	}
-
-	// Prepare placeholders for the query
-	placeholders := make([]string, len(columns))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
-
-	// Build the query
-	query := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s)",
-		table,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "),
-	)
-
-	// Execute the query
-	_, err := q.Db.Exec(query, values...)
+	_, err := q.Table(table).Insert(columns, values).Exec()
 	if err != nil {
 		return fmt.Errorf("failed to insert data: %w", err)
 	}
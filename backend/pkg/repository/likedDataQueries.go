@@ -19,7 +19,7 @@ func (q *Query) GetUserLikedPost(userid string, user *model.UserData) error {
 		ORDER BY p.created_at DESC
 	`
 
-	rows, err := q.Db.Query(query, userid)
+	rows, err := q.db().Query(q.Rebind(query), userid)
 	if err != nil {
 		return fmt.Errorf("failed to fetch user posts: %w", err)
 	}
@@ -78,7 +78,7 @@ func (q *Query) GetallLikedCommentsPostIDs(userid string) ([]string, error) {
 	WHERE user_id = ?
 	`
 
-	rows, err := q.Db.Query(query, userid)
+	rows, err := q.db().Query(q.Rebind(query), userid)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query posts: %v", err)
 	}
@@ -120,7 +120,7 @@ func (q *Query) PostIdsFromCommentsId(commentsid []string) ([]string, error) {
 		args[i] = id
 	}
 
-	rows, err := q.Db.Query(query, args...)
+	rows, err := q.db().Query(q.Rebind(query), args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query posts: %v", err)
 	}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FetchLoginLock returns until when logins for key are refused.
+// The zero time means key is not locked.
+func (q *Query) FetchLoginLock(key string) (time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := q.db().QueryRow(q.Rebind("SELECT locked_until FROM login_attempts WHERE attempt_key = ?"), key).Scan(&lockedUntil)
+	if err != nil && err != sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("FetchLoginLock: %w", err)
+	}
+	return lockedUntil.Time, nil
+}
+
+// RecordLoginFailure counts a failed login for key and returns the number of
+// consecutive failures. Failures from before windowStart are forgotten.
+func (q *Query) RecordLoginFailure(key string, now, windowStart time.Time) (int, error) {
+	var failures int
+	err := q.db().QueryRow(q.Rebind(`
+		INSERT INTO login_attempts (attempt_key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (attempt_key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING failures
+	`), key, now, windowStart).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("RecordLoginFailure: %w", err)
+	}
+	return failures, nil
+}
+
+// LockLogin refuses logins for key until the given time.
+func (q *Query) LockLogin(key string, until time.Time) error {
+	return q.UpdateData("login_attempts", []string{"attempt_key"}, []any{key}, []string{"locked_until"}, []any{until})
+}
+
+// ClearLoginFailures resets the failure counters and lockouts of the given keys.
+func (q *Query) ClearLoginFailures(keys ...string) error {
+	for _, key := range keys {
+		if _, err := q.db().Exec(q.Rebind("DELETE FROM login_attempts WHERE attempt_key = ?"), key); err != nil {
+			return fmt.Errorf("ClearLoginFailures: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsAdmin reports whether the user is a site administrator.
+func (q *Query) IsAdmin(userID string) (bool, error) {
+	var admin bool
+	err := q.db().QueryRow(q.Rebind("SELECT is_admin FROM users WHERE id = ?"), userID).Scan(&admin)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("IsAdmin: %w", err)
+	}
+	return admin, nil
+}
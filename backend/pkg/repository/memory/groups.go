@@ -0,0 +1,324 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"social/pkg/model"
+	"social/pkg/repository"
+)
+
+func (s *Store) FetchAllGroups(userid string) ([]model.Groups, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var groups []model.Groups
+	for _, g := range s.tables["groups"] {
+		if _, ok := s.byID("users", g.str("creator_id")); !ok {
+			continue
+		}
+		group := model.Groups{
+			ID:           g.str("id"),
+			Title:        g.str("title"),
+			About:        g.str("description"),
+			Creator:      s.creator(g.str("creator_id")),
+			CreatedAt:    g.time("created_at"),
+			MembersCount: int(g.integer("members_count")),
+		}
+		if m, ok := s.member(group.ID, userid); ok {
+			group.IsJoined = true
+			group.UserRole = m.str("role")
+		}
+		if userid != "" {
+			group.UserJoinRequest = s.latestJoinRequest(group.ID, userid)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (s *Store) member(groupID, userID string) (row, bool) {
+	return s.first("group_members", func(m row) bool {
+		return m.str("group_id") == groupID && m.str("user_id") == userID
+	})
+}
+
+func (s *Store) latestJoinRequest(groupID, userID string) *model.GroupJoinRequest {
+	rows := s.find("group_join_requests", func(r row) bool {
+		return r.str("group_id") == groupID && r.str("user_id") == userID
+	})
+	sortBy(rows, "created_at", true)
+	for _, r := range rows {
+		if req, ok := s.joinRequest(r); ok {
+			return &req
+		}
+	}
+	return nil
+}
+
+// joinRequest returns the request with its user, and false when the user does not exist.
+func (s *Store) joinRequest(r row) (model.GroupJoinRequest, bool) {
+	if _, ok := s.byID("users", r.str("user_id")); !ok {
+		return model.GroupJoinRequest{}, false
+	}
+	return model.GroupJoinRequest{
+		ID:        r.str("id"),
+		UserID:    r.str("user_id"),
+		User:      s.userSummary(r.str("user_id")),
+		CreatedAt: r.time("created_at"),
+		Status:    r.str("status"),
+	}, true
+}
+
+func (s *Store) FetchGroupData(groupid string, userID string) (model.GroupData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.byID("groups", groupid)
+	if !ok {
+		return model.GroupData{}, errors.New("no group data found")
+	}
+	if _, ok := s.byID("users", g.str("creator_id")); !ok {
+		return model.GroupData{}, errors.New("no group data found")
+	}
+
+	group := model.GroupData{
+		ID:        g.str("id"),
+		Title:     g.str("title"),
+		About:     g.str("description"),
+		Creator:   s.creator(g.str("creator_id")),
+		CreatedAt: g.time("created_at"),
+	}
+	group.Posts = s.withComments(s.posts(func(p row) bool { return p.str("group_id") == groupid }, userID), userID)
+
+	for _, m := range s.find("group_members", func(m row) bool { return m.str("group_id") == groupid }) {
+		if _, ok := s.byID("users", m.str("user_id")); !ok {
+			continue
+		}
+		member := s.creator(m.str("user_id"))
+		member.Role = m.str("role")
+		group.Members = append(group.Members, member)
+	}
+
+	group.Events = s.groupEvents(groupid, userID)
+
+	if userID != "" {
+		group.UserJoinRequest = s.latestJoinRequest(groupid, userID)
+	}
+	if g.str("creator_id") == userID {
+		group.JoinRequest = s.pendingJoinRequests(groupid)
+	}
+	return group, nil
+}
+
+func (s *Store) groupEvents(groupID, userID string) []model.Events {
+	rows := s.find("events", func(e row) bool {
+		_, ok := s.byID("users", e.str("creator_id"))
+		return ok && e.str("group_id") == groupID
+	})
+	sortBy(rows, "event_time", false)
+
+	var events []model.Events
+	for _, e := range rows {
+		event := model.Events{
+			ID:          e.str("id"),
+			Title:       e.str("title"),
+			Description: e.str("description"),
+			Creator:     s.creator(e.str("creator_id")),
+			EventTime:   e.time("event_time"),
+			CreatedAt:   e.time("created_at"),
+			RsvpCount:   int(e.integer("going_count")),
+			Location:    e.str("location"),
+			Attendees:   []model.Creator{},
+		}
+		if userID != "" {
+			event.UserRsvpStatus = "not_going"
+			if a, ok := s.attendance(event.ID, userID); ok {
+				event.UserRsvpStatus = a.str("status")
+			}
+		}
+
+		for _, a := range s.find("event_attendance", func(a row) bool {
+			return a.str("event_id") == event.ID && a.str("status") == "going"
+		}) {
+			if _, ok := s.byID("users", a.str("user_id")); ok {
+				event.Attendees = append(event.Attendees, s.creator(a.str("user_id")))
+			}
+		}
+		sort.SliceStable(event.Attendees, func(i, j int) bool {
+			return event.Attendees[i].FirstName < event.Attendees[j].FirstName
+		})
+
+		events = append(events, event)
+	}
+	return events
+}
+
+func (s *Store) attendance(eventID, userID string) (row, bool) {
+	return s.first("event_attendance", func(a row) bool {
+		return a.str("event_id") == eventID && a.str("user_id") == userID
+	})
+}
+
+func (s *Store) pendingJoinRequests(groupID string) []model.GroupJoinRequest {
+	var requests []model.GroupJoinRequest
+	for _, r := range s.find("group_join_requests", func(r row) bool {
+		return r.str("group_id") == groupID && r.str("status") == "pending"
+	}) {
+		if req, ok := s.joinRequest(r); ok {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+func (s *Store) FetchGroupId(title string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.first("groups", func(g row) bool { return g.str("title") == title })
+	if !ok {
+		return "", errors.New("the group does not exist")
+	}
+	return g.str("id"), nil
+}
+
+func (s *Store) FetchGroupTitle(groupID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.byID("groups", groupID)
+	if !ok {
+		return "", repository.ErrGroupNotFound
+	}
+	return g.str("title"), nil
+}
+
+func (s *Store) FetchGroupAdmin(groupID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.byID("groups", groupID)
+	if !ok {
+		return "", nil
+	}
+	return g.str("creator_id"), nil
+}
+
+func (s *Store) FetchGroupJoinRequest(groupID string, group *model.GroupData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group.JoinRequest = s.pendingJoinRequests(groupID)
+	return nil
+}
+
+func (s *Store) FetchAllGroupMembersId(groupID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, m := range s.find("group_members", func(m row) bool { return m.str("group_id") == groupID }) {
+		ids = append(ids, m.str("user_id"))
+	}
+	return ids, nil
+}
+
+func (s *Store) GetUserGroupIDs(userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, m := range s.find("group_members", func(m row) bool { return m.str("user_id") == userID }) {
+		ids = append(ids, m.str("group_id"))
+	}
+	return ids, nil
+}
+
+func (s *Store) FetchGroupMemberships(userID string) ([]model.GroupMembership, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := s.find("group_members", func(m row) bool { return m.str("user_id") == userID })
+	sortBy(rows, "created_at", false)
+
+	memberships := []model.GroupMembership{}
+	for _, m := range rows {
+		g, ok := s.byID("groups", m.str("group_id"))
+		if !ok {
+			continue
+		}
+		memberships = append(memberships, model.GroupMembership{
+			GroupID:  g.str("id"),
+			Title:    g.str("title"),
+			Role:     m.str("role"),
+			Creator:  g.str("creator_id") == userID,
+			JoinedAt: m.time("created_at"),
+		})
+	}
+	return memberships, nil
+}
+
+func (s *Store) DeleteGroup(groupName, userId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.first("groups", func(g row) bool { return g.str("title") == groupName })
+	if !ok {
+		return repository.ErrGroupNotFound
+	}
+	if g.str("creator_id") != userId {
+		return repository.ErrUnauthorized
+	}
+	s.delete("groups", func(g row) bool { return g.str("title") == groupName })
+	return nil
+}
+
+func (s *Store) CheckForRsvp(eventID, userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.attendance(eventID, userID)
+	return ok, nil
+}
+
+func (s *Store) FetchAttendingMembersCount(eventID string) (int, error) {
+	if eventID == "" {
+		return 0, fmt.Errorf("FetchAttendingMembersCount: eventID cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.byID("events", eventID)
+	if !ok {
+		return 0, fmt.Errorf("FetchAttendingMembersCount: no event found with ID %s", eventID)
+	}
+	return int(e.integer("going_count")), nil
+}
+
+func (s *Store) FetchEventResponses(userID string) ([]model.EventResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	responses := []model.EventResponse{}
+	for _, a := range s.find("event_attendance", func(a row) bool { return a.str("user_id") == userID }) {
+		e, ok := s.byID("events", a.str("event_id"))
+		if !ok {
+			continue
+		}
+		responses = append(responses, model.EventResponse{
+			EventID:     e.str("id"),
+			GroupID:     e.str("group_id"),
+			Title:       e.str("title"),
+			EventTime:   e.time("event_time"),
+			Status:      a.str("status"),
+			RespondedAt: a.time("created_at"),
+		})
+	}
+	sort.SliceStable(responses, func(i, j int) bool {
+		return responses[i].EventTime.Before(responses[j].EventTime)
+	})
+	return responses, nil
+}
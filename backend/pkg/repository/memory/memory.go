@@ -0,0 +1,642 @@
+// Package memory implements repository.Store in memory, for tests of handlers
+// and websocket processors that should not need a database.
+//
+// Rows are kept per table as column to value maps, following the SQL schema:
+// unknown tables and columns are rejected, unique constraints are enforced,
+// deleting a row deletes the rows referencing it like ON DELETE CASCADE does and
+// the counter columns maintained by triggers in the database are kept up to date.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"social/pkg/repository"
+)
+
+var _ repository.Store = (*Store)(nil)
+
+// Store is an in-memory repository.Store. The zero value is not usable, create
+// one with New.
+type Store struct {
+	mu     sync.Mutex
+	tables map[string][]row
+
+	// Now returns the current time, time.Now when nil.
+	Now func() time.Time
+	// Version is the schema version reported by SchemaVersion.
+	Version uint
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{tables: make(map[string][]row)}
+}
+
+type row map[string]any
+
+// table holds what the memory store needs to know about a table besides its
+// columns, which come from repository.Schema.
+type table struct {
+	defaults map[string]any
+	// unique lists the column sets that must be unique, besides id.
+	unique [][]string
+	// references maps a column to the table whose id it holds. Rows are deleted
+	// along with the row they reference.
+	references map[string]string
+}
+
+var schema = map[string]table{
+	"users": {
+		defaults: map[string]any{"is_public": true, "is_admin": false},
+		unique:   [][]string{{"email"}},
+	},
+	"posts": {
+		defaults:   map[string]any{"likes_count": int64(0), "dislikes_count": int64(0), "comments_count": int64(0), "privacy": "public"},
+		references: map[string]string{"user_id": "users", "group_id": "groups"},
+	},
+	"comments": {
+		defaults:   map[string]any{"likes_count": int64(0), "dislikes_count": int64(0)},
+		references: map[string]string{"post_id": "posts", "user_id": "users"},
+	},
+	"groups": {
+		defaults:   map[string]any{"members_count": int64(0)},
+		unique:     [][]string{{"title"}},
+		references: map[string]string{"creator_id": "users"},
+	},
+	"user_follows": {
+		defaults:   map[string]any{"status": "pending"},
+		unique:     [][]string{{"follower_id", "following_id"}},
+		references: map[string]string{"follower_id": "users", "following_id": "users"},
+	},
+	"post_visibility": {
+		references: map[string]string{"post_id": "posts", "user_id": "users"},
+	},
+	"group_members": {
+		defaults:   map[string]any{"role": "member"},
+		unique:     [][]string{{"group_id", "user_id"}},
+		references: map[string]string{"group_id": "groups", "user_id": "users"},
+	},
+	"group_invitations": {
+		defaults:   map[string]any{"status": "pending"},
+		unique:     [][]string{{"group_id", "receiver_id"}},
+		references: map[string]string{"group_id": "groups", "sender_id": "users", "receiver_id": "users"},
+	},
+	"group_join_requests": {
+		defaults:   map[string]any{"status": "pending"},
+		unique:     [][]string{{"group_id", "user_id"}},
+		references: map[string]string{"group_id": "groups", "user_id": "users"},
+	},
+	"events": {
+		defaults:   map[string]any{"going_count": int64(0)},
+		references: map[string]string{"group_id": "groups", "creator_id": "users"},
+	},
+	"event_attendance": {
+		unique:     [][]string{{"event_id", "user_id"}},
+		references: map[string]string{"event_id": "events", "user_id": "users"},
+	},
+	"private_messages": {
+		defaults:   map[string]any{"is_read": false},
+		references: map[string]string{"sender_id": "users", "receiver_id": "users"},
+	},
+	"group_messages": {
+		references: map[string]string{"group_id": "groups", "sender_id": "users"},
+	},
+	"post_likes": {
+		unique:     [][]string{{"post_id", "user_id"}},
+		references: map[string]string{"post_id": "posts", "user_id": "users"},
+	},
+	"comment_likes": {
+		unique:     [][]string{{"comment_id", "user_id"}},
+		references: map[string]string{"comment_id": "comments", "user_id": "users"},
+	},
+	"notifications": {
+		defaults:   map[string]any{"is_read": false},
+		references: map[string]string{"recipient_id": "users", "actor_id": "users"},
+	},
+	"sessions": {
+		defaults:   map[string]any{"remember_me": false},
+		unique:     [][]string{{"session_token"}, {"csrf_token"}},
+		references: map[string]string{"user_id": "users"},
+	},
+	"media": {},
+	"password_resets": {
+		unique:     [][]string{{"token_hash"}},
+		references: map[string]string{"user_id": "users"},
+	},
+	"email_verifications": {
+		unique:     [][]string{{"token_hash"}},
+		references: map[string]string{"user_id": "users"},
+	},
+	"recovery_codes": {
+		references: map[string]string{"user_id": "users"},
+	},
+	"mfa_challenges": {
+		defaults:   map[string]any{"attempts": int64(0), "remember_me": false},
+		unique:     [][]string{{"token_hash"}},
+		references: map[string]string{"user_id": "users"},
+	},
+	"login_attempts": {
+		defaults: map[string]any{"failures": int64(0)},
+		unique:   [][]string{{"attempt_key"}},
+	},
+	"api_tokens": {
+		unique:     [][]string{{"token_hash"}},
+		references: map[string]string{"user_id": "users"},
+	},
+	"user_identities": {
+		unique:     [][]string{{"issuer", "subject"}},
+		references: map[string]string{"user_id": "users"},
+	},
+	"oidc_logins": {
+		defaults: map[string]any{"remember_me": false},
+		unique:   [][]string{{"state_hash"}},
+	},
+	"data_exports": {
+		defaults:   map[string]any{"status": "pending"},
+		references: map[string]string{"user_id": "users"},
+	},
+}
+
+// counter is a column the database keeps up to date with a trigger: the number
+// of rows of table that reference the row of parent through key and match counts.
+type counter struct {
+	table, key     string
+	parent, column string
+	counts         func(r row) bool
+}
+
+var counters = []counter{
+	{"post_likes", "post_id", "posts", "likes_count", func(r row) bool { return r.boolean("is_like") }},
+	{"comment_likes", "comment_id", "comments", "likes_count", func(r row) bool { return r.boolean("is_like") }},
+	{"comments", "post_id", "posts", "comments_count", func(row) bool { return true }},
+	{"group_members", "group_id", "groups", "members_count", func(row) bool { return true }},
+	{"event_attendance", "event_id", "events", "going_count", func(r row) bool { return r.str("status") == "going" }},
+}
+
+func (s *Store) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Ping always succeeds.
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+// WithTx runs fn on the store and restores the tables as they were before when
+// fn returns an error or panics. Transactions are not isolated: writes made by
+// other callers while fn runs are lost on rollback as well.
+func (s *Store) WithTx(ctx context.Context, fn func(tx repository.Store) error) error {
+	s.mu.Lock()
+	snapshot := make(map[string][]row, len(s.tables))
+	for name, rows := range s.tables {
+		copied := make([]row, len(rows))
+		for i, r := range rows {
+			copied[i] = maps.Clone(r)
+		}
+		snapshot[name] = copied
+	}
+	s.mu.Unlock()
+
+	committed := false
+	defer func() {
+		if !committed {
+			s.mu.Lock()
+			s.tables = snapshot
+			s.mu.Unlock()
+		}
+	}()
+
+	if err := fn(s); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// SchemaVersion returns Version.
+func (s *Store) SchemaVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	return s.Version, false, nil
+}
+
+func (s *Store) InsertData(table string, columns []string, values []any) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns provided")
+	}
+	if len(columns) != len(values) {
+		return fmt.Errorf("number of columns (%d) does not match number of values (%d)", len(columns), len(values))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := make(row, len(columns))
+	for i, col := range columns {
+		r[col] = values[i]
+	}
+	if err := s.insert(table, r); err != nil {
+		return fmt.Errorf("failed to insert data: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) UpdateData(table string, whereColumns []string, whereValues []any, columns []string, values []any) error {
+	if len(columns) == 0 || len(columns) != len(values) {
+		return fmt.Errorf("UpdateData: columns and values length mismatch")
+	}
+	if len(whereColumns) == 0 || len(whereColumns) != len(whereValues) {
+		return fmt.Errorf("UpdateData: whereColumns and whereValues length mismatch")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set := make(row, len(columns))
+	for i, col := range columns {
+		set[col] = values[i]
+	}
+	if _, err := s.update(table, where(whereColumns, whereValues), set); err != nil {
+		return fmt.Errorf("UpdateData failed: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) CheckRow(table string, whereColumns []string, whereValues []any) (bool, error) {
+	if len(whereColumns) == 0 || len(whereColumns) != len(whereValues) {
+		return false, fmt.Errorf("RowExists: whereColumns and whereValues must be non-empty and of equal length")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkColumns(table, whereColumns); err != nil {
+		return false, fmt.Errorf("RowExists: failed to execute existence check on table '%s': %w", table, err)
+	}
+	_, found := s.first(table, where(whereColumns, whereValues))
+	return found, nil
+}
+
+func (s *Store) DeleteData(table string, whereColumns []string, whereValues []any) error {
+	if len(whereColumns) == 0 || len(whereColumns) != len(whereValues) {
+		return fmt.Errorf("DeleteData: whereColumns and whereValues length mismatch")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkColumns(table, whereColumns); err != nil {
+		return fmt.Errorf("DeleteData failed: %w", err)
+	}
+	s.delete(table, where(whereColumns, whereValues))
+	return nil
+}
+
+func checkColumns(name string, columns []string) error {
+	for _, col := range columns {
+		if _, err := repository.Column(name, col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkValues checks the columns of r and that their values fit them.
+func checkValues(name string, r row) error {
+	for col, v := range r {
+		if err := repository.CheckValue(name, col, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insert adds r to the table, filling in the default values.
+func (s *Store) insert(name string, r row) error {
+	if err := checkValues(name, r); err != nil {
+		return err
+	}
+
+	t := schema[name]
+	for col, v := range r {
+		r[col] = normalize(v)
+	}
+	for col, v := range t.defaults {
+		if _, ok := r[col]; !ok {
+			r[col] = v
+		}
+	}
+	if _, ok := r["created_at"]; !ok {
+		if _, err := repository.Column(name, "created_at"); err == nil {
+			r["created_at"] = s.now()
+		}
+	}
+
+	if err := s.checkUnique(name, r, -1); err != nil {
+		return err
+	}
+	s.tables[name] = append(s.tables[name], r)
+	s.count(name, nil, r)
+	return nil
+}
+
+// update sets the given columns of the rows matching match and returns how many there were.
+func (s *Store) update(name string, match func(row) bool, set row) (int, error) {
+	if err := checkValues(name, set); err != nil {
+		return 0, err
+	}
+
+	var matched []row
+	for i, r := range s.tables[name] {
+		if !match(r) {
+			continue
+		}
+		updated := make(row, len(r))
+		for col, v := range r {
+			updated[col] = v
+		}
+		for col, v := range set {
+			updated[col] = normalize(v)
+		}
+		if err := s.checkUnique(name, updated, i); err != nil {
+			return 0, err
+		}
+		matched = append(matched, r)
+	}
+
+	for _, r := range matched {
+		old := make(row, len(r))
+		for col, v := range r {
+			old[col] = v
+		}
+		for col, v := range set {
+			r[col] = normalize(v)
+		}
+		s.count(name, old, r)
+	}
+	return len(matched), nil
+}
+
+// delete removes the rows matching match, and the rows referencing them, and
+// returns the removed rows of the table.
+func (s *Store) delete(name string, match func(row) bool) []row {
+	var kept, deleted []row
+	for _, r := range s.tables[name] {
+		if match(r) {
+			deleted = append(deleted, r)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	s.tables[name] = kept
+
+	for _, r := range deleted {
+		s.count(name, r, nil)
+	}
+
+	ids := make(map[string]bool, len(deleted))
+	for _, r := range deleted {
+		ids[r.str("id")] = true
+	}
+	for child, t := range schema {
+		for col, parent := range t.references {
+			if parent == name {
+				s.delete(child, func(r row) bool { return ids[r.str(col)] })
+			}
+		}
+	}
+	return deleted
+}
+
+// checkUnique fails when r conflicts with a row of the table other than the
+// one at index self.
+func (s *Store) checkUnique(name string, r row, self int) error {
+	t := schema[name]
+	keys := t.unique
+	if _, err := repository.Column(name, "id"); err == nil {
+		keys = append([][]string{{"id"}}, keys...)
+	}
+
+	for _, key := range keys {
+		values := make([]any, len(key))
+		for i, col := range key {
+			values[i] = r[col]
+		}
+		match := where(key, values)
+		for i, other := range s.tables[name] {
+			if i != self && match(other) {
+				return fmt.Errorf("UNIQUE constraint failed: %s.%s", name, strings.Join(key, ", "+name+"."))
+			}
+		}
+	}
+	return nil
+}
+
+// count updates the counters affected by a row changing from old to new, either
+// of which is nil on inserts and deletes.
+func (s *Store) count(name string, old, new row) {
+	for _, c := range counters {
+		if c.table != name {
+			continue
+		}
+		if old != nil && c.counts(old) {
+			s.addToCounter(c, old.str(c.key), -1)
+		}
+		if new != nil && c.counts(new) {
+			s.addToCounter(c, new.str(c.key), 1)
+		}
+	}
+}
+
+func (s *Store) addToCounter(c counter, id string, delta int64) {
+	if parent, ok := s.first(c.parent, func(r row) bool { return r.str("id") == id }); ok {
+		parent[c.column] = parent.integer(c.column) + delta
+	}
+}
+
+func (s *Store) first(name string, match func(row) bool) (row, bool) {
+	for _, r := range s.tables[name] {
+		if match(r) {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Store) find(name string, match func(row) bool) []row {
+	var rows []row
+	for _, r := range s.tables[name] {
+		if match(r) {
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+func (s *Store) byID(name, id string) (row, bool) {
+	return s.first(name, func(r row) bool { return r.str("id") == id })
+}
+
+// where matches the rows whose columns equal the given values, as "col = ?"
+// conditions do: NULL is never equal to anything.
+func where(columns []string, values []any) func(row) bool {
+	return func(r row) bool {
+		for i, col := range columns {
+			if !equal(r[col], normalize(values[i])) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// sortBy orders rows by the time in col, keeping insertion order for equal times.
+func sortBy(rows []row, col string, desc bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if desc {
+			return rows[i].time(col).After(rows[j].time(col))
+		}
+		return rows[i].time(col).Before(rows[j].time(col))
+	})
+}
+
+func limit(rows []row, n int) []row {
+	if len(rows) > n {
+		return rows[:n]
+	}
+	return rows
+}
+
+// normalize converts v to one of the types rows hold: nil, string, int64, bool or time.Time.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return int64(v)
+	case []byte:
+		return string(v)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case sql.NullString:
+		if !v.Valid {
+			return nil
+		}
+		return v.String
+	case sql.NullTime:
+		if !v.Valid {
+			return nil
+		}
+		return v.Time
+	}
+	return v
+}
+
+func equal(a, b any) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if ab, ok := a.(bool); ok {
+		a = boolToInt(ab)
+	}
+	if bb, ok := b.(bool); ok {
+		b = boolToInt(bb)
+	}
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+	return a == b
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (r row) str(col string) string {
+	switch v := r[col].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (r row) null(col string) sql.NullString {
+	if r[col] == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: r.str(col), Valid: true}
+}
+
+func (r row) boolean(col string) bool {
+	switch v := r[col].(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	}
+	return false
+}
+
+func (r row) integer(col string) int64 {
+	v, _ := r[col].(int64)
+	return v
+}
+
+// timeLayouts are the formats time values given as strings are read in.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+func (r row) time(col string) time.Time {
+	switch v := r[col].(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func (r row) timePtr(col string) *time.Time {
+	if r[col] == nil {
+		return nil
+	}
+	t := r.time(col)
+	return &t
+}
+
+// errNoRows wraps sql.ErrNoRows the way the SQL implementation reports a
+// missing row it did not expect.
+func errNoRows(method string) error {
+	return fmt.Errorf("%s: %w", method, sql.ErrNoRows)
+}
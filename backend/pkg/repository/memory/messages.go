@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"social/pkg/model"
+)
+
+func (s *Store) GetMessagesBetweenUsers(userAID, userBID string) ([]model.PrivateMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := s.find("private_messages", func(m row) bool {
+		sender, receiver := m.str("sender_id"), m.str("receiver_id")
+		return (sender == userAID && receiver == userBID) || (sender == userBID && receiver == userAID)
+	})
+	sortBy(rows, "created_at", false)
+
+	var messages []model.PrivateMessage
+	for _, m := range rows {
+		messages = append(messages, model.PrivateMessage{
+			ID:          m.str("id"),
+			SenderID:    m.str("sender_id"),
+			ReceiverID:  m.str("receiver_id"),
+			RecipientID: m.str("receiver_id"),
+			Content:     m.str("content"),
+			Message:     m.str("content"),
+			IsRead:      m.boolean("is_read"),
+			CreatedAt:   m.time("created_at"),
+		})
+	}
+	return messages, nil
+}
+
+func (s *Store) GetGroupMessages(groupID string) ([]model.GroupMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []model.GroupMessage
+	for _, msg := range s.groupMessages("group_id", groupID) {
+		msg.Sender = s.userSummary(msg.SenderID)
+		msg.Sender.ID = msg.SenderID
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+func (s *Store) FetchSentGroupMessages(userID string) ([]model.GroupMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]model.GroupMessage{}, s.groupMessages("sender_id", userID)...), nil
+}
+
+// groupMessages returns the group messages whose column is value, oldest first.
+func (s *Store) groupMessages(column, value string) []model.GroupMessage {
+	rows := s.find("group_messages", func(m row) bool { return m.str(column) == value })
+	sortBy(rows, "created_at", false)
+
+	var messages []model.GroupMessage
+	for _, m := range rows {
+		messages = append(messages, model.GroupMessage{
+			ID:        m.str("id"),
+			GroupId:   m.str("group_id"),
+			SenderID:  m.str("sender_id"),
+			Content:   m.str("content"),
+			Message:   m.str("content"),
+			CreatedAt: m.time("created_at"),
+		})
+	}
+	return messages
+}
+
+func (s *Store) FetchConversationPartners(userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var partners []string
+	seen := make(map[string]bool)
+	for _, m := range s.tables["private_messages"] {
+		partner := ""
+		switch userID {
+		case m.str("sender_id"):
+			partner = m.str("receiver_id")
+		case m.str("receiver_id"):
+			partner = m.str("sender_id")
+		default:
+			continue
+		}
+		if !seen[partner] {
+			seen[partner] = true
+			partners = append(partners, partner)
+		}
+	}
+	return partners, nil
+}
+
+func (s *Store) GetUserNotifications(userID string) ([]model.UserNotification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var notifications []model.UserNotification
+	for _, n := range s.find("notifications", func(n row) bool {
+		return n.str("recipient_id") == userID && !n.boolean("is_read")
+	}) {
+		u, ok := s.byID("users", n.str("actor_id"))
+		if !ok {
+			continue
+		}
+		notifications = append(notifications, model.UserNotification{
+			ID:         n.str("id"),
+			Type:       n.str("type"),
+			IsRead:     n.boolean("is_read"),
+			Message:    n.str("message"),
+			CreatedAt:  n.time("created_at"),
+			GroupID:    n.null("recipient_group_id"),
+			EntityID:   n.null("entity_id"),
+			EntityType: n.null("entity_type"),
+			Actor: &model.User{
+				ID:          u.str("id"),
+				Email:       u.str("email"),
+				FirstName:   u.str("first_name"),
+				LastName:    u.str("last_name"),
+				Avatar:      u.str("avatar"),
+				Nickname:    u.str("nickname"),
+				IsPublic:    u.boolean("is_public"),
+				DateOfBirth: u.time("date_of_birth"),
+				AboutMe:     u.str("about_me"),
+				CreatedAt:   u.time("created_at"),
+			},
+		})
+	}
+	return notifications, nil
+}
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"social/pkg/model"
+	"social/pkg/repository"
+)
+
+func (s *Store) FetchAllPosts(userID string) ([]model.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// posts of deleted users are not shown, as the SQL joins the author
+	posts := s.posts(func(p row) bool {
+		_, ok := s.byID("users", p.str("user_id"))
+		return ok && p["group_id"] == nil && s.visible(p, userID)
+	}, userID)
+	return s.withComments(posts, userID), nil
+}
+
+func (s *Store) FetchPost(postID, userID string) (model.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := s.posts(func(p row) bool {
+		if p.str("id") != postID {
+			return false
+		}
+		if _, ok := s.byID("users", p.str("user_id")); !ok {
+			return false
+		}
+		if p["group_id"] != nil {
+			_, member := s.member(p.str("group_id"), userID)
+			return member || p.str("user_id") == userID
+		}
+		return s.visible(p, userID)
+	}, userID)
+	if len(posts) == 0 {
+		return model.Post{}, repository.ErrPostNotFound
+	}
+	return s.withComments(posts, userID)[0], nil
+}
+
+// visible reports whether the privacy of p, a post outside of groups, lets
+// userID see it.
+func (s *Store) visible(p row, userID string) bool {
+	author := p.str("user_id")
+	switch {
+	case author == userID:
+		return true
+	case p.str("privacy") == "public":
+		return true
+	case p.str("privacy") == "almost_private":
+		_, ok := s.first("user_follows", func(f row) bool {
+			return f.str("following_id") == author && f.str("follower_id") == userID && f.str("status") == "accepted"
+		})
+		return ok
+	case p.str("privacy") == "private":
+		_, ok := s.first("post_visibility", func(v row) bool {
+			return v.str("post_id") == p.str("id") && v.str("user_id") == userID
+		})
+		return ok
+	}
+	return false
+}
+
+// posts returns the posts matching match, newest first, with their author and
+// media and whether viewer liked them. An empty viewer likes nothing.
+func (s *Store) posts(match func(row) bool, viewer string) []model.Post {
+	rows := s.find("posts", match)
+	sortBy(rows, "created_at", true)
+
+	var posts []model.Post
+	for _, p := range rows {
+		post := model.Post{
+			ID:            p.str("id"),
+			User:          s.creator(p.str("user_id")),
+			GroupID:       p.str("group_id"),
+			Content:       p.str("content"),
+			LikesCount:    int(p.integer("likes_count")),
+			DislikesCount: int(p.integer("dislikes_count")),
+			CommentsCount: int(p.integer("comments_count")),
+			Privacy:       p.str("privacy"),
+			CreatedAt:     p.time("created_at"),
+			Media:         s.media(p.str("id")),
+		}
+		if viewer != "" {
+			_, post.IsLiked = s.first("post_likes", func(l row) bool {
+				return l.str("post_id") == post.ID && l.str("user_id") == viewer
+			})
+		}
+		posts = append(posts, post)
+	}
+	return posts
+}
+
+// postsByIDs returns the posts with the given ids.
+func (s *Store) postsByIDs(ids map[string]bool) []model.Post {
+	if len(ids) == 0 {
+		return []model.Post{}
+	}
+	return s.posts(func(p row) bool { return ids[p.str("id")] }, "")
+}
+
+// withComments attaches their comments to posts.
+func (s *Store) withComments(posts []model.Post, viewer string) []model.Post {
+	for i := range posts {
+		rows := s.find("comments", func(c row) bool { return c.str("post_id") == posts[i].ID })
+		sortBy(rows, "created_at", false)
+
+		for _, c := range rows {
+			comment := model.Comment{
+				ID:            c.str("id"),
+				PostID:        c.str("post_id"),
+				User:          s.creator(c.str("user_id")),
+				Content:       c.str("content"),
+				Media:         s.media(c.str("id")),
+				LikesCount:    int(c.integer("likes_count")),
+				DislikesCount: int(c.integer("dislikes_count")),
+				CreatedAt:     c.time("created_at"),
+			}
+			_, comment.IsLiked = s.first("comment_likes", func(l row) bool {
+				return l.str("comment_id") == comment.ID && l.str("user_id") == viewer
+			})
+			posts[i].Comments = append(posts[i].Comments, comment)
+		}
+	}
+	return posts
+}
+
+func (s *Store) media(parentID string) []model.Media {
+	media := []model.Media{}
+	for _, m := range s.find("media", func(m row) bool { return m.str("parent_id") == parentID }) {
+		media = append(media, model.Media{URL: m.str("url")})
+	}
+	return media
+}
+
+// creator returns the public profile of a user, empty when they do not exist.
+func (s *Store) creator(userID string) model.Creator {
+	u, ok := s.byID("users", userID)
+	if !ok {
+		return model.Creator{}
+	}
+	return model.Creator{
+		ID:        u.str("id"),
+		FirstName: u.str("first_name"),
+		LastName:  u.str("last_name"),
+		Nickname:  u.str("nickname"),
+		Avatar:    u.str("avatar"),
+	}
+}
+
+func (s *Store) userSummary(userID string) model.UserSummary {
+	c := s.creator(userID)
+	return model.UserSummary{
+		ID:        c.ID,
+		Firstname: c.FirstName,
+		Lastname:  c.LastName,
+		Nickname:  c.Nickname,
+		Avatar:    c.Avatar,
+	}
+}
@@ -0,0 +1,342 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"social/pkg/model"
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+func (s *Store) FetchSessionAuth(sessionToken, csrfToken string) (repository.SessionAuth, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.first("sessions", func(r row) bool {
+		return r.str("session_token") == sessionToken && r.str("csrf_token") == csrfToken
+	})
+	if !ok {
+		return repository.SessionAuth{}, repository.ErrSessionNotFound
+	}
+	u, ok := s.byID("users", session.str("user_id"))
+	if !ok || u["disabled_at"] != nil {
+		return repository.SessionAuth{}, repository.ErrSessionNotFound
+	}
+
+	return repository.SessionAuth{
+		UserID:            session.str("user_id"),
+		CSRFToken:         session.str("csrf_token"),
+		ExpiresAt:         session.time("expires_at"),
+		RememberMe:        session.boolean("remember_me"),
+		AbsoluteExpiresAt: session.timePtr("absolute_expires_at"),
+		Verified:          u["verified_at"] != nil,
+	}, nil
+}
+
+func (s *Store) FetchSessionUser(sessionID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, _ := s.first("sessions", func(r row) bool { return r.str("session_token") == sessionID })
+	return session.str("user_id"), nil
+}
+
+func (s *Store) FetchSessionID(sessionToken string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.first("sessions", func(r row) bool { return r.str("session_token") == sessionToken })
+	if !ok {
+		return "", repository.ErrSessionNotFound
+	}
+	return session.str("id"), nil
+}
+
+func (s *Store) ConnectionActive(ownerID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	owner, ok := s.first("sessions", func(r row) bool {
+		return r.str("id") == ownerID && r.time("expires_at").After(now)
+	})
+	if !ok {
+		owner, ok = s.first("api_tokens", func(r row) bool {
+			return r.str("id") == ownerID && (r["expires_at"] == nil || r.time("expires_at").After(now))
+		})
+	}
+	if !ok {
+		return false, nil
+	}
+	u, ok := s.byID("users", owner.str("user_id"))
+	return ok && u["disabled_at"] == nil, nil
+}
+
+func (s *Store) FetchUserSessions(userID, currentToken string) ([]model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	rows := s.find("sessions", func(r row) bool {
+		return r.str("user_id") == userID && r.time("expires_at").After(now)
+	})
+	sortBy(rows, "created_at", true)
+
+	sessions := []model.Session{}
+	for _, r := range rows {
+		sessions = append(sessions, model.Session{
+			ID:        r.str("id"),
+			UserAgent: r.str("user_agent"),
+			IPAddress: r.str("ip_address"),
+			CreatedAt: r.time("created_at"),
+			ExpiresAt: r.time("expires_at"),
+			Current:   r.str("session_token") == currentToken,
+		})
+	}
+	return sessions, nil
+}
+
+func (s *Store) RenewSession(sessionToken string, expiresAt time.Time) error {
+	return s.UpdateData("sessions", []string{"session_token"}, []any{sessionToken}, []string{"expires_at"}, []any{expiresAt})
+}
+
+func (s *Store) DeleteSession(sessionToken string) error {
+	now := s.now()
+	s.deleteSessions(func(r row) bool {
+		return r.str("session_token") == sessionToken || r.time("expires_at").Before(now)
+	})
+	return nil
+}
+
+func (s *Store) DeleteUserSession(userID, sessionID string) error {
+	if len(s.deleteSessions(func(r row) bool { return r.str("id") == sessionID && r.str("user_id") == userID })) == 0 {
+		return repository.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *Store) DeleteOtherSessions(userID, currentToken string) ([]string, error) {
+	return s.deleteSessions(func(r row) bool {
+		return r.str("user_id") == userID && r.str("session_token") != currentToken
+	}), nil
+}
+
+func (s *Store) DeleteAllUserSessions(userID string) ([]string, error) {
+	return s.deleteSessions(func(r row) bool { return r.str("user_id") == userID }), nil
+}
+
+func (s *Store) DeleteExpiredSessions() ([]string, error) {
+	now := s.now()
+	return s.deleteSessions(func(r row) bool { return r.time("expires_at").Before(now) }), nil
+}
+
+// deleteSessions removes the matching sessions and returns their ids.
+func (s *Store) deleteSessions(match func(row) bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, r := range s.delete("sessions", match) {
+		ids = append(ids, r.str("id"))
+	}
+	return ids
+}
+
+func (s *Store) CreateMFAChallenge(userID, tokenHash string, rememberMe bool, expiresAt time.Time) error {
+	return s.InsertData("mfa_challenges",
+		[]string{"id", "user_id", "token_hash", "remember_me", "expires_at"},
+		[]any{util.UUIDGen(), userID, tokenHash, rememberMe, expiresAt})
+}
+
+func (s *Store) AttemptMFAChallenge(tokenHash string) (userID string, rememberMe bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	challenge, ok := s.first("mfa_challenges", func(r row) bool {
+		return r.str("token_hash") == tokenHash && r.time("expires_at").After(now) &&
+			r.integer("attempts") < repository.MaxMFAAttempts
+	})
+	if !ok {
+		return "", false, repository.ErrInvalidMFAToken
+	}
+	challenge["attempts"] = challenge.integer("attempts") + 1
+	return challenge.str("user_id"), challenge.boolean("remember_me"), nil
+}
+
+func (s *Store) DeleteMFAChallenge(tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.delete("mfa_challenges", func(r row) bool {
+		return r.str("token_hash") == tokenHash || r.time("expires_at").Before(now)
+	})
+	return nil
+}
+
+func (s *Store) CreateOIDCLogin(stateHash string, login repository.OIDCLogin, expiresAt time.Time) error {
+	return s.InsertData("oidc_logins",
+		[]string{"id", "state_hash", "provider", "code_verifier", "nonce", "remember_me", "expires_at"},
+		[]any{util.UUIDGen(), stateHash, login.Provider, login.CodeVerifier, login.Nonce, login.RememberMe, expiresAt})
+}
+
+func (s *Store) ConsumeOIDCLogin(stateHash string) (repository.OIDCLogin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	deleted := s.delete("oidc_logins", func(r row) bool {
+		return r.str("state_hash") == stateHash && r.time("expires_at").After(now)
+	})
+	if len(deleted) == 0 {
+		return repository.OIDCLogin{}, repository.ErrInvalidOIDCState
+	}
+
+	login := deleted[0]
+	return repository.OIDCLogin{
+		Provider:     login.str("provider"),
+		CodeVerifier: login.str("code_verifier"),
+		Nonce:        login.str("nonce"),
+		RememberMe:   login.boolean("remember_me"),
+	}, nil
+}
+
+func (s *Store) CreateAPIToken(userID, name, tokenHash string, scopes []string, expiresAt *time.Time) (string, error) {
+	id := util.UUIDGen()
+	err := s.InsertData("api_tokens",
+		[]string{"id", "user_id", "name", "token_hash", "scopes", "expires_at"},
+		[]any{id, userID, name, tokenHash, strings.Join(scopes, " "), expiresAt})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *Store) FetchUserAPITokens(userID string) ([]model.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := s.find("api_tokens", func(r row) bool { return r.str("user_id") == userID })
+	sortBy(rows, "created_at", true)
+
+	tokens := []model.APIToken{}
+	for _, r := range rows {
+		tokens = append(tokens, model.APIToken{
+			ID:         r.str("id"),
+			Name:       r.str("name"),
+			Scopes:     strings.Fields(r.str("scopes")),
+			CreatedAt:  r.time("created_at"),
+			ExpiresAt:  r.timePtr("expires_at"),
+			LastUsedAt: r.timePtr("last_used_at"),
+		})
+	}
+	return tokens, nil
+}
+
+// validToken returns the unexpired token with the given hash, unless its owner is disabled.
+func (s *Store) validToken(tokenHash string, now time.Time) (row, bool) {
+	token, ok := s.first("api_tokens", func(r row) bool {
+		return r.str("token_hash") == tokenHash && (r["expires_at"] == nil || r.time("expires_at").After(now))
+	})
+	if !ok {
+		return nil, false
+	}
+	if u, ok := s.byID("users", token.str("user_id")); !ok || u["disabled_at"] != nil {
+		return nil, false
+	}
+	return token, true
+}
+
+func (s *Store) AuthenticateAPIToken(tokenHash string) (tokenID, userID string, scopes []string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	token, ok := s.validToken(tokenHash, now)
+	if !ok {
+		return "", "", nil, repository.ErrAPITokenNotFound
+	}
+	token["last_used_at"] = now
+	return token.str("id"), token.str("user_id"), strings.Fields(token.str("scopes")), nil
+}
+
+func (s *Store) FetchAPITokenUser(tokenHash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.validToken(tokenHash, s.now())
+	if !ok {
+		return "", repository.ErrAPITokenNotFound
+	}
+	return token.str("user_id"), nil
+}
+
+func (s *Store) DeleteAPIToken(userID, tokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.delete("api_tokens", func(r row) bool { return r.str("id") == tokenID && r.str("user_id") == userID })) == 0 {
+		return repository.ErrAPITokenNotFound
+	}
+	return nil
+}
+
+func (s *Store) DeleteAllAPITokens(userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, r := range s.delete("api_tokens", func(r row) bool { return r.str("user_id") == userID }) {
+		ids = append(ids, r.str("id"))
+	}
+	return ids, nil
+}
+
+func (s *Store) FetchLoginLock(key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.first("login_attempts", func(r row) bool { return r.str("attempt_key") == key })
+	if !ok {
+		return time.Time{}, nil
+	}
+	return attempt.time("locked_until"), nil
+}
+
+func (s *Store) RecordLoginFailure(key string, now, windowStart time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.first("login_attempts", func(r row) bool { return r.str("attempt_key") == key })
+	if !ok {
+		if err := s.insert("login_attempts", row{"attempt_key": key, "failures": 1, "last_failure_at": now}); err != nil {
+			return 0, fmt.Errorf("RecordLoginFailure: %w", err)
+		}
+		return 1, nil
+	}
+
+	if attempt.time("last_failure_at").Before(windowStart) {
+		attempt["failures"] = int64(1)
+	} else {
+		attempt["failures"] = attempt.integer("failures") + 1
+	}
+	attempt["last_failure_at"] = now
+	return int(attempt.integer("failures")), nil
+}
+
+func (s *Store) LockLogin(key string, until time.Time) error {
+	return s.UpdateData("login_attempts", []string{"attempt_key"}, []any{key}, []string{"locked_until"}, []any{until})
+}
+
+func (s *Store) ClearLoginFailures(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		s.delete("login_attempts", func(r row) bool { return r.str("attempt_key") == key })
+	}
+	return nil
+}
@@ -0,0 +1,695 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"social/pkg/model"
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+func (s *Store) FetchUserInfo(userid string, user *model.UserData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.userInfo(userid, user)
+}
+
+func (s *Store) userInfo(userid string, user *model.UserData) error {
+	u, ok := s.byID("users", userid)
+	if !ok {
+		return errors.New("no user data found")
+	}
+	user.Email = u.str("email")
+	user.FirstName = u.str("first_name")
+	user.LastName = u.str("last_name")
+	user.DateOfBirth = u.time("date_of_birth")
+	user.Avatar = u.str("avatar")
+	user.Nickname = u.str("nickname")
+	user.AboutMe = u.str("about_me")
+	user.CreatedAt = u.time("created_at")
+	user.IsPublic = u.boolean("is_public")
+	user.BackgroundImage = u.str("background_image")
+	return nil
+}
+
+func (s *Store) FetchUserData(userid string) (model.UserData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var user model.UserData
+	if err := s.userInfo(userid, &user); err != nil {
+		return model.UserData{}, err
+	}
+	user.ID = userid
+
+	user.Post = s.withComments(s.posts(func(p row) bool { return p.str("user_id") == userid }, userid), userid)
+
+	commented := make(map[string]bool)
+	for _, c := range s.find("comments", func(c row) bool { return c.str("user_id") == userid }) {
+		commented[c.str("post_id")] = true
+	}
+	user.Comments = s.withComments(s.postsByIDs(commented), userid)
+
+	liked := make(map[string]bool)
+	for _, l := range s.find("post_likes", func(l row) bool { return l.str("user_id") == userid }) {
+		liked[l.str("post_id")] = true
+	}
+	user.LikedPost = s.withComments(s.postsByIDs(liked), userid)
+
+	likedComments := make(map[string]bool)
+	for _, l := range s.find("comment_likes", func(l row) bool { return l.str("user_id") == userid }) {
+		if c, ok := s.byID("comments", l.str("comment_id")); ok {
+			likedComments[c.str("post_id")] = true
+		}
+	}
+	user.LikedComments = s.withComments(s.postsByIDs(likedComments), userid)
+
+	user.Followers = s.follows("following_id", userid, "accepted")
+	user.Following = s.follows("follower_id", userid, "accepted")
+	return user, nil
+}
+
+func (s *Store) FetchAllUsers(userID string) (model.AllUsers, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := model.AllUsers{
+		Followers:       s.follows("following_id", userID, "accepted"),
+		Following:       s.follows("follower_id", userID, "accepted"),
+		SentRequest:     s.follows("follower_id", userID, "pending"),
+		ReceivedRequest: s.follows("following_id", userID, "pending"),
+	}
+
+	// between reports whether userID and other follow each other with one of the statuses
+	between := func(other string, statuses ...string) bool {
+		_, found := s.first("user_follows", func(f row) bool {
+			return ((f.str("follower_id") == other && f.str("following_id") == userID) ||
+				(f.str("follower_id") == userID && f.str("following_id") == other)) &&
+				(len(statuses) == 0 || slices.Contains(statuses, f.str("status")))
+		})
+		return found
+	}
+
+	others := s.find("users", func(u row) bool {
+		id := u.str("id")
+		return id != userID &&
+			(!between(id, "accepted") || between(id, "declined")) &&
+			!between(id, "pending")
+	})
+	sortBy(others, "created_at", false)
+	for _, u := range limit(others, 100) {
+		users.NonMutual = append(users.NonMutual, follower(u))
+	}
+
+	followers := s.find("user_follows", func(f row) bool {
+		if f.str("following_id") != userID || f.str("status") != "accepted" {
+			return false
+		}
+		_, followedBack := s.first("user_follows", func(back row) bool {
+			return back.str("follower_id") == userID && back.str("following_id") == f.str("follower_id") &&
+				slices.Contains([]string{"accepted", "pending", "declined"}, back.str("status"))
+		})
+		return !followedBack
+	})
+	sortBy(followers, "created_at", false)
+	for _, f := range limit(followers, 100) {
+		if u, ok := s.byID("users", f.str("follower_id")); ok {
+			users.NonMutual = append(users.NonMutual, follower(u))
+		}
+	}
+
+	return users, nil
+}
+
+// follows returns the users on the other side of the follows where column is
+// userID and the status matches, oldest first.
+func (s *Store) follows(column, userID, status string) []model.Follower {
+	other := "follower_id"
+	if column == "follower_id" {
+		other = "following_id"
+	}
+
+	rows := s.find("user_follows", func(f row) bool {
+		return f.str(column) == userID && f.str("status") == status
+	})
+	sortBy(rows, "created_at", false)
+
+	var users []model.Follower
+	for _, f := range limit(rows, 100) {
+		if u, ok := s.byID("users", f.str(other)); ok {
+			users = append(users, follower(u))
+		}
+	}
+	return users
+}
+
+func follower(u row) model.Follower {
+	return model.Follower{
+		ID:        u.str("id"),
+		FirstName: u.str("first_name"),
+		LastName:  u.str("last_name"),
+		Avatar:    u.str("avatar"),
+		IsPublic:  u.boolean("is_public"),
+	}
+}
+
+func (s *Store) FollowExists(followerID, followingID string) (exists bool, status string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.first("user_follows", func(f row) bool {
+		return f.str("follower_id") == followerID && f.str("following_id") == followingID
+	})
+	if !ok {
+		return false, "", nil
+	}
+	return true, f.str("status"), nil
+}
+
+func (s *Store) CheckUserIsPublic(userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.byID("users", userID)
+	if !ok {
+		return false, fmt.Errorf("CheckUserIsPublic: User not found")
+	}
+	return u.boolean("is_public"), nil
+}
+
+func (s *Store) UpdateUser(userid string, table string, columns []string, values []any) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("UpdateUser: no columns provided (update data)")
+	}
+	if len(columns) != len(values) {
+		return fmt.Errorf("UpdateUser: number of columns (%d) does not match number of values (%d)", len(columns), len(values))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set := make(row, len(columns))
+	for i, col := range columns {
+		set[col] = values[i]
+	}
+	if _, err := s.update(table, func(r row) bool { return r.str("id") == userid }, set); err != nil {
+		return fmt.Errorf("failed to update data: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) IsAdmin(userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.byID("users", userID)
+	return ok && u.boolean("is_admin"), nil
+}
+
+func (s *Store) IsUserDisabled(userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.byID("users", userID)
+	if !ok {
+		return false, errNoRows("IsUserDisabled")
+	}
+	return u["disabled_at"] != nil, nil
+}
+
+func (s *Store) GetUserCredentials(identifier string) (userID, password string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.first("users", func(u row) bool {
+		return (u.str("email") == identifier || (u["nickname"] != nil && u.str("nickname") == identifier)) && u["disabled_at"] == nil
+	})
+	if !ok {
+		return "", "", errors.New("user not found by email or nickname")
+	}
+	return u.str("id"), u.str("password"), nil
+}
+
+func (s *Store) FetchPasswordHash(userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.byID("users", userID)
+	if !ok {
+		return "", errors.New("user not found")
+	}
+	return u.str("password"), nil
+}
+
+func (s *Store) UpdatePassword(userID, hashedPassword string) error {
+	return s.UpdateData("users", []string{"id"}, []any{userID}, []string{"password"}, []any{hashedPassword})
+}
+
+func (s *Store) FetchUserIDByEmail(email string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.first("users", func(u row) bool { return u.str("email") == email })
+	if !ok {
+		return "", errors.New("user not found")
+	}
+	return u.str("id"), nil
+}
+
+func (s *Store) FetchUserEmail(userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.byID("users", userID)
+	if !ok {
+		return "", errNoRows("FetchUserEmail")
+	}
+	return u.str("email"), nil
+}
+
+func (s *Store) CreatePasswordReset(userID, tokenHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delete("password_resets", func(r row) bool { return r.str("user_id") == userID && r["used_at"] == nil })
+	return s.insert("password_resets", row{
+		"id":         util.UUIDGen(),
+		"user_id":    userID,
+		"token_hash": tokenHash,
+		"expires_at": expiresAt,
+	})
+}
+
+func (s *Store) ConsumePasswordReset(tokenHash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	reset, ok := s.first("password_resets", func(r row) bool {
+		return r.str("token_hash") == tokenHash && r["used_at"] == nil && r.time("expires_at").After(now)
+	})
+	if !ok {
+		return "", repository.ErrInvalidResetToken
+	}
+	reset["used_at"] = now
+	return reset.str("user_id"), nil
+}
+
+func (s *Store) CreateEmailVerification(userID, email, tokenHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delete("email_verifications", func(r row) bool { return r.str("user_id") == userID })
+	return s.insert("email_verifications", row{
+		"id":         util.UUIDGen(),
+		"user_id":    userID,
+		"email":      email,
+		"token_hash": tokenHash,
+		"expires_at": expiresAt,
+	})
+}
+
+func (s *Store) VerifyEmail(tokenHash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	deleted := s.delete("email_verifications", func(r row) bool {
+		return r.str("token_hash") == tokenHash && r.time("expires_at").After(now)
+	})
+	if len(deleted) == 0 {
+		return "", repository.ErrInvalidVerificationToken
+	}
+
+	userID, email := deleted[0].str("user_id"), deleted[0].str("email")
+	n, _ := s.update("users", func(u row) bool { return u.str("id") == userID && u.str("email") == email }, row{"verified_at": now})
+	if n == 0 {
+		return "", repository.ErrInvalidVerificationToken
+	}
+	return userID, nil
+}
+
+func (s *Store) IsEmailVerified(userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.byID("users", userID)
+	if !ok {
+		return false, errNoRows("IsEmailVerified")
+	}
+	return u["verified_at"] != nil, nil
+}
+
+func (s *Store) FetchTOTP(userID string) (secret string, enabled bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.byID("users", userID)
+	if !ok {
+		return "", false, errNoRows("FetchTOTP")
+	}
+	return u.str("totp_secret"), u["totp_enabled_at"] != nil, nil
+}
+
+func (s *Store) SetPendingTOTPSecret(userID, secret string) error {
+	return s.UpdateData("users", []string{"id"}, []any{userID},
+		[]string{"totp_secret", "totp_enabled_at", "totp_last_step"}, []any{secret, nil, nil})
+}
+
+func (s *Store) EnableTOTP(userID string) error {
+	return s.UpdateData("users", []string{"id"}, []any{userID},
+		[]string{"totp_enabled_at"}, []any{s.now()})
+}
+
+func (s *Store) DisableTOTP(userID string) error {
+	err := s.UpdateData("users", []string{"id"}, []any{userID},
+		[]string{"totp_secret", "totp_enabled_at", "totp_last_step"}, []any{nil, nil, nil})
+	if err != nil {
+		return err
+	}
+	return s.DeleteData("recovery_codes", []string{"user_id"}, []any{userID})
+}
+
+func (s *Store) UseTOTPStep(userID string, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.update("users", func(u row) bool {
+		return u.str("id") == userID && (u["totp_last_step"] == nil || u.integer("totp_last_step") < step)
+	}, row{"totp_last_step": step})
+	if err != nil {
+		return false, fmt.Errorf("UseTOTPStep: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (s *Store) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delete("recovery_codes", func(r row) bool { return r.str("user_id") == userID })
+	for _, hash := range codeHashes {
+		err := s.insert("recovery_codes", row{
+			"id":        util.UUIDGen(),
+			"user_id":   userID,
+			"code_hash": hash,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert data: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.update("recovery_codes", func(r row) bool {
+		return r.str("user_id") == userID && r.str("code_hash") == codeHash && r["used_at"] == nil
+	}, row{"used_at": s.now()})
+	if err != nil {
+		return false, fmt.Errorf("UseRecoveryCode: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (s *Store) FetchIdentityUser(issuer, subject string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := s.first("user_identities", func(r row) bool {
+		return r.str("issuer") == issuer && r.str("subject") == subject
+	})
+	if !ok {
+		return "", repository.ErrIdentityNotFound
+	}
+	return identity.str("user_id"), nil
+}
+
+func (s *Store) LinkIdentity(userID, issuer, subject, email string) error {
+	return s.InsertData("user_identities",
+		[]string{"id", "user_id", "issuer", "subject", "email"},
+		[]any{util.UUIDGen(), userID, issuer, subject, email})
+}
+
+func (s *Store) CreateIdentityUser(user model.User, verifiedAt *time.Time, issuer, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.insert("users", row{
+		"id":            user.ID,
+		"email":         user.Email,
+		"password":      user.Password,
+		"first_name":    user.FirstName,
+		"last_name":     user.LastName,
+		"date_of_birth": user.DateOfBirth,
+		"avatar":        user.Avatar,
+		"nickname":      user.Nickname,
+		"about_me":      user.AboutMe,
+		"is_public":     user.IsPublic,
+		"verified_at":   verifiedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("CreateIdentityUser: failed to insert user: %w", err)
+	}
+
+	err = s.insert("user_identities", row{
+		"id":      util.UUIDGen(),
+		"user_id": user.ID,
+		"issuer":  issuer,
+		"subject": subject,
+		"email":   user.Email,
+	})
+	if err != nil {
+		// roll back the user created above
+		s.delete("users", func(u row) bool { return u.str("id") == user.ID })
+		return fmt.Errorf("CreateIdentityUser: failed to link identity: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ScheduleAccountDeletion(userID string, at time.Time) error {
+	return s.UpdateData("users", []string{"id"}, []any{userID}, []string{"deletion_scheduled_at"}, []any{at})
+}
+
+func (s *Store) CancelAccountDeletion(userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.update("users", func(u row) bool {
+		return u.str("id") == userID && u["deletion_scheduled_at"] != nil
+	}, row{"deletion_scheduled_at": nil})
+	if err != nil {
+		return false, fmt.Errorf("CancelAccountDeletion: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *Store) FetchAccountsDueForDeletion(now time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, u := range s.find("users", func(u row) bool {
+		return u["deletion_scheduled_at"] != nil && !u.time("deletion_scheduled_at").After(now)
+	}) {
+		ids = append(ids, u.str("id"))
+	}
+	return ids, nil
+}
+
+// DeleteAccount follows the rules documented on repository.Query.DeleteAccount.
+func (s *Store) DeleteAccount(userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// hand over the groups created by the user
+	var deletedGroups []string
+	for _, g := range s.find("groups", func(g row) bool { return g.str("creator_id") == userID }) {
+		members := s.find("group_members", func(m row) bool {
+			return m.str("group_id") == g.str("id") && m.str("user_id") != userID
+		})
+		if len(members) == 0 {
+			deletedGroups = append(deletedGroups, g.str("id"))
+			continue
+		}
+		sortBy(members, "created_at", false)
+		successor := members[0]
+		for _, m := range members {
+			if m.str("role") == "admin" {
+				successor = m
+				break
+			}
+		}
+		g["creator_id"] = successor.str("user_id")
+		successor["role"] = "admin"
+	}
+
+	inDeletedGroup := func(p row) bool { return slices.Contains(deletedGroups, p.str("group_id")) }
+	parents := make(map[string]bool)
+	for _, p := range s.find("posts", func(p row) bool { return p.str("user_id") == userID || inDeletedGroup(p) }) {
+		parents[p.str("id")] = true
+	}
+	for _, c := range s.find("comments", func(c row) bool { return c.str("user_id") == userID || parents[c.str("post_id")] }) {
+		parents[c.str("id")] = true
+	}
+
+	var files []string
+	for _, m := range s.delete("media", func(m row) bool { return parents[m.str("parent_id")] }) {
+		files = append(files, m.str("url"))
+	}
+	if u, ok := s.byID("users", userID); ok {
+		files = append(files, u.str("avatar"), u.str("background_image"))
+	}
+
+	s.delete("posts", inDeletedGroup)
+	s.delete("groups", func(g row) bool { return slices.Contains(deletedGroups, g.str("id")) })
+	s.delete("users", func(u row) bool { return u.str("id") == userID })
+	s.delete("login_attempts", func(r row) bool { return r.str("attempt_key") == "user:"+userID })
+
+	var orphaned []string
+	for _, file := range files {
+		if !repository.IsUploadedFile(file) {
+			continue
+		}
+		_, inMedia := s.first("media", func(m row) bool { return m.str("url") == file })
+		_, inProfile := s.first("users", func(u row) bool {
+			return u.str("avatar") == file || u.str("background_image") == file
+		})
+		if !inMedia && !inProfile {
+			orphaned = append(orphaned, file)
+		}
+	}
+	return orphaned, nil
+}
+
+func (s *Store) CreateDataExport(userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, pending := s.first("data_exports", func(e row) bool {
+		return e.str("user_id") == userID && e.str("status") == "pending"
+	}); pending {
+		return "", repository.ErrExportPending
+	}
+
+	id := util.UUIDGen()
+	if err := s.insert("data_exports", row{"id": id, "user_id": userID, "status": "pending"}); err != nil {
+		return "", fmt.Errorf("failed to insert data: %w", err)
+	}
+	return id, nil
+}
+
+func (s *Store) CompleteDataExport(exportID, filePath string, expiresAt time.Time) error {
+	return s.UpdateData("data_exports", []string{"id"}, []any{exportID},
+		[]string{"status", "file_path", "completed_at", "expires_at"},
+		[]any{"ready", filePath, s.now(), expiresAt})
+}
+
+func (s *Store) FailDataExport(exportID string) error {
+	return s.UpdateData("data_exports", []string{"id"}, []any{exportID},
+		[]string{"status", "completed_at"}, []any{"failed", s.now()})
+}
+
+func (s *Store) FailPendingExports() error {
+	return s.UpdateData("data_exports", []string{"status"}, []any{"pending"},
+		[]string{"status", "completed_at"}, []any{"failed", s.now()})
+}
+
+func (s *Store) FetchDataExports(userID string) ([]model.DataExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := s.find("data_exports", func(e row) bool { return e.str("user_id") == userID })
+	sortBy(rows, "created_at", true)
+
+	exports := []model.DataExport{}
+	for _, e := range rows {
+		exports = append(exports, dataExport(e))
+	}
+	return exports, nil
+}
+
+func (s *Store) FetchDataExport(userID, exportID string) (model.DataExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.first("data_exports", func(e row) bool {
+		return e.str("id") == exportID && e.str("user_id") == userID
+	})
+	if !ok {
+		return model.DataExport{}, repository.ErrExportNotFound
+	}
+	return dataExport(e), nil
+}
+
+func dataExport(e row) model.DataExport {
+	return model.DataExport{
+		ID:          e.str("id"),
+		Status:      e.str("status"),
+		FilePath:    e.str("file_path"),
+		CreatedAt:   e.time("created_at"),
+		CompletedAt: e.timePtr("completed_at"),
+		ExpiresAt:   e.timePtr("expires_at"),
+	}
+}
+
+func (s *Store) DeleteFinishedExports(userID string) ([]string, error) {
+	return s.deleteExports(func(e row) bool {
+		return e.str("user_id") == userID && e.str("status") != "pending"
+	}), nil
+}
+
+func (s *Store) DeleteExpiredExports(now time.Time) ([]string, error) {
+	return s.deleteExports(func(e row) bool {
+		return e["expires_at"] != nil && e.time("expires_at").Before(now)
+	}), nil
+}
+
+func (s *Store) deleteExports(match func(row) bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var files []string
+	for _, e := range s.delete("data_exports", match) {
+		if e["file_path"] != nil {
+			files = append(files, e.str("file_path"))
+		}
+	}
+	return files
+}
+
+func (s *Store) FetchUserMediaFiles(userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parents := make(map[string]bool)
+	for _, p := range s.find("posts", func(p row) bool { return p.str("user_id") == userID }) {
+		parents[p.str("id")] = true
+	}
+	for _, c := range s.find("comments", func(c row) bool { return c.str("user_id") == userID }) {
+		parents[c.str("id")] = true
+	}
+
+	var candidates []string
+	for _, m := range s.find("media", func(m row) bool { return parents[m.str("parent_id")] }) {
+		candidates = append(candidates, m.str("url"))
+	}
+	if u, ok := s.byID("users", userID); ok {
+		candidates = append(candidates, u.str("avatar"), u.str("background_image"))
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, file := range candidates {
+		if !seen[file] && repository.IsUploadedFile(file) {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
@@ -17,7 +17,7 @@ func (q *Query) GetMessagesBetweenUsers(userAID, userBID string) ([]model.Privat
 		ORDER BY created_at ASC
 	`
 
-	rows, err := q.Db.Query(query, userAID, userBID, userBID, userAID)
+	rows, err := q.db().Query(q.Rebind(query), userAID, userBID, userBID, userAID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch messages between users: %w", err)
 	}
@@ -54,7 +54,7 @@ func (q *Query) GetGroupMessages(groupID string) ([]model.GroupMessage, error) {
 		ORDER BY gm.created_at ASC
 	`
 
-	rows, err := q.Db.Query(query, groupID)
+	rows, err := q.db().Query(q.Rebind(query), groupID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch group messages: %w", err)
 	}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"social/pkg/util"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// FetchUserIDByEmail returns the id of the user registered with the given email.
+func (q *Query) FetchUserIDByEmail(email string) (string, error) {
+	var userID string
+	err := q.db().QueryRow(q.Rebind("SELECT id FROM users WHERE email = ?"), email).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.New("user not found")
+		}
+		return "", fmt.Errorf("FetchUserIDByEmail: %w", err)
+	}
+	return userID, nil
+}
+
+// CreatePasswordReset stores the hash of a new reset token for userID.
+// Any reset token the user requested earlier and did not use is discarded.
+func (q *Query) CreatePasswordReset(userID, tokenHash string, expiresAt time.Time) error {
+	_, err := q.db().Exec(q.Rebind("DELETE FROM password_resets WHERE user_id = ? AND used_at IS NULL"), userID)
+	if err != nil {
+		return fmt.Errorf("CreatePasswordReset: failed to discard old tokens: %w", err)
+	}
+
+	return q.InsertData("password_resets", []string{
+		"id",
+		"user_id",
+		"token_hash",
+		"expires_at",
+	}, []any{
+		util.UUIDGen(),
+		userID,
+		tokenHash,
+		expiresAt,
+	})
+}
+
+// ConsumePasswordReset marks the reset token as used and returns the user it
+// was issued for. A token can only be consumed once and only before it expires.
+func (q *Query) ConsumePasswordReset(tokenHash string) (string, error) {
+	now := time.Now()
+
+	var userID string
+	err := q.db().QueryRow(q.Rebind(`
+		UPDATE password_resets
+		SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING user_id
+	`), now, tokenHash, now).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidResetToken
+		}
+		return "", fmt.Errorf("ConsumePasswordReset: %w", err)
+	}
+	return userID, nil
+}
+
+// UpdatePassword replaces the stored password hash of a user.
+func (q *Query) UpdatePassword(userID, hashedPassword string) error {
+	return q.UpdateData("users", []string{"id"}, []any{userID}, []string{"password"}, []any{hashedPassword})
+}
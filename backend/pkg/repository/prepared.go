@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// stmtCache holds prepared statements by pool and SQL, so that the hot queries
+// are parsed and planned once instead of on every call.
+type stmtCache struct {
+	mu    sync.Mutex
+	stmts map[stmtKey]*sql.Stmt
+}
+
+type stmtKey struct {
+	db    *sql.DB
+	query string
+}
+
+func (c *stmtCache) lookup(db *sql.DB, query string) *sql.Stmt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stmts[stmtKey{db: db, query: query}]
+}
+
+// get returns the statement of query on db, preparing it when it is not cached.
+// It is prepared without holding the lock, which would otherwise wait for the
+// write pool while a transaction holding its connection waits in lookup.
+func (c *stmtCache) get(db *sql.DB, query string) (*sql.Stmt, error) {
+	if stmt := c.lookup(db, query); stmt != nil {
+		return stmt, nil
+	}
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := stmtKey{db: db, query: query}
+	if cached, ok := c.stmts[key]; ok {
+		// Prepared concurrently by another caller.
+		stmt.Close()
+		return cached, nil
+	}
+	c.stmts[key] = stmt
+	return stmt, nil
+}
+
+func (q *Query) statements() *stmtCache {
+	q.stmtsOnce.Do(func() {
+		if q.stmts == nil {
+			q.stmts = &stmtCache{stmts: make(map[stmtKey]*sql.Stmt)}
+		}
+	})
+	return q.stmts
+}
+
+// prepared returns the statement of query, already rebound, from the cache,
+// preparing it on the pool the query runs on the first time.
+//
+// In a transaction a cached statement is bound to it, and one not cached yet is
+// prepared on it: the SQLite write pool has a single connection, held by the
+// transaction. Either is closed with the transaction.
+func (q *Query) prepared(query string) (*sql.Stmt, error) {
+	if q.tx != nil {
+		if stmt := q.statements().lookup(q.Db, query); stmt != nil {
+			return q.tx.Stmt(stmt), nil
+		}
+		return q.tx.Prepare(query)
+	}
+
+	db := q.Db
+	if q.Reader != nil && isSelect(query) {
+		db = q.Reader
+	}
+	return q.statements().get(db, query)
+}
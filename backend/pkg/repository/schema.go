@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+var (
+	ErrUnknownTable  = errors.New("unknown table")
+	ErrUnknownColumn = errors.New("unknown column")
+	ErrInvalidValue  = errors.New("invalid value")
+)
+
+// ColumnType is the kind of value a column holds.
+type ColumnType int
+
+const (
+	Text ColumnType = iota + 1
+	Integer
+	Boolean
+	Timestamp
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case Text:
+		return "text"
+	case Integer:
+		return "integer"
+	case Boolean:
+		return "boolean"
+	case Timestamp:
+		return "timestamp"
+	}
+	return "unknown"
+}
+
+// Schema lists every table and column created by the migrations in pkg/db/sqlite
+// and pkg/db/postgres. Table and column names only reach SQL after being looked
+// up here, so a migration adding one has to add it here as well.
+var Schema = map[string]map[string]ColumnType{
+	"users": {
+		"id": Text, "email": Text, "password": Text, "first_name": Text, "last_name": Text,
+		"date_of_birth": Timestamp, "avatar": Text, "nickname": Text, "about_me": Text, "is_public": Boolean,
+		"created_at": Timestamp, "background_image": Text, "verified_at": Timestamp, "totp_secret": Text,
+		"totp_enabled_at": Timestamp, "totp_last_step": Integer, "is_admin": Boolean, "deletion_scheduled_at": Timestamp,
+		"disabled_at": Timestamp,
+	},
+	"posts": {
+		"id": Text, "user_id": Text, "group_id": Text, "content": Text, "likes_count": Integer,
+		"dislikes_count": Integer, "comments_count": Integer, "privacy": Text, "created_at": Timestamp,
+	},
+	"comments": {
+		"id": Text, "post_id": Text, "user_id": Text, "content": Text, "likes_count": Integer,
+		"dislikes_count": Integer, "created_at": Timestamp,
+	},
+	"groups": {
+		"id": Text, "title": Text, "description": Text, "creator_id": Text, "created_at": Timestamp, "members_count": Integer,
+	},
+	"user_follows": {
+		"id": Text, "follower_id": Text, "following_id": Text, "status": Text, "created_at": Timestamp, "updated_at": Timestamp,
+	},
+	"post_visibility": {
+		"id": Text, "post_id": Text, "user_id": Text,
+	},
+	"group_members": {
+		"id": Text, "group_id": Text, "user_id": Text, "role": Text, "created_at": Timestamp,
+	},
+	"group_invitations": {
+		"id": Text, "group_id": Text, "sender_id": Text, "receiver_id": Text, "status": Text, "created_at": Timestamp,
+	},
+	"group_join_requests": {
+		"id": Text, "group_id": Text, "user_id": Text, "status": Text, "created_at": Timestamp,
+	},
+	"events": {
+		"id": Text, "group_id": Text, "creator_id": Text, "title": Text, "description": Text, "event_time": Timestamp,
+		"created_at": Timestamp, "location": Text, "going_count": Integer,
+	},
+	"event_attendance": {
+		"id": Text, "event_id": Text, "user_id": Text, "status": Text, "created_at": Timestamp,
+	},
+	"private_messages": {
+		"id": Text, "sender_id": Text, "receiver_id": Text, "content": Text, "is_read": Boolean, "created_at": Timestamp,
+	},
+	"group_messages": {
+		"id": Text, "group_id": Text, "sender_id": Text, "content": Text, "created_at": Timestamp,
+	},
+	"post_likes": {
+		"id": Text, "post_id": Text, "user_id": Text, "is_like": Boolean, "created_at": Timestamp,
+	},
+	"comment_likes": {
+		"id": Text, "comment_id": Text, "user_id": Text, "is_like": Boolean, "created_at": Timestamp,
+	},
+	"notifications": {
+		"id": Text, "recipient_id": Text, "actor_id": Text, "type": Text, "entity_id": Text, "entity_type": Text,
+		"is_read": Boolean, "message": Text, "created_at": Timestamp, "recipient_group_id": Text,
+	},
+	"sessions": {
+		"id": Text, "user_id": Text, "session_token": Text, "csrf_token": Text, "expires_at": Timestamp,
+		"created_at": Timestamp, "user_agent": Text, "ip_address": Text, "absolute_expires_at": Timestamp, "remember_me": Boolean,
+	},
+	"media": {
+		"id": Text, "url": Text, "parent_id": Text,
+	},
+	"password_resets": {
+		"id": Text, "user_id": Text, "token_hash": Text, "expires_at": Timestamp, "used_at": Timestamp, "created_at": Timestamp,
+	},
+	"email_verifications": {
+		"id": Text, "user_id": Text, "email": Text, "token_hash": Text, "expires_at": Timestamp, "created_at": Timestamp,
+	},
+	"recovery_codes": {
+		"id": Text, "user_id": Text, "code_hash": Text, "used_at": Timestamp, "created_at": Timestamp,
+	},
+	"mfa_challenges": {
+		"id": Text, "user_id": Text, "token_hash": Text, "attempts": Integer, "expires_at": Timestamp,
+		"created_at": Timestamp, "remember_me": Boolean,
+	},
+	"login_attempts": {
+		"attempt_key": Text, "failures": Integer, "last_failure_at": Timestamp, "locked_until": Timestamp,
+	},
+	"api_tokens": {
+		"id": Text, "user_id": Text, "name": Text, "token_hash": Text, "scopes": Text, "expires_at": Timestamp,
+		"last_used_at": Timestamp, "created_at": Timestamp,
+	},
+	"user_identities": {
+		"id": Text, "user_id": Text, "issuer": Text, "subject": Text, "email": Text, "created_at": Timestamp,
+	},
+	"oidc_logins": {
+		"id": Text, "state_hash": Text, "provider": Text, "code_verifier": Text, "nonce": Text, "remember_me": Boolean,
+		"expires_at": Timestamp, "created_at": Timestamp,
+	},
+	"data_exports": {
+		"id": Text, "user_id": Text, "status": Text, "file_path": Text, "created_at": Timestamp,
+		"completed_at": Timestamp, "expires_at": Timestamp,
+	},
+}
+
+// timestampLayouts are the layouts a string has to match to be stored in a
+// Timestamp column.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Column returns the type of a column, or an error wrapping ErrUnknownTable or
+// ErrUnknownColumn when the schema has no such table or column.
+func Column(table, column string) (ColumnType, error) {
+	columns, ok := Schema[table]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownTable, table)
+	}
+	t, ok := columns[column]
+	if !ok {
+		return 0, fmt.Errorf("%w %q in table %q", ErrUnknownColumn, column, table)
+	}
+	return t, nil
+}
+
+// CheckValue returns an error wrapping ErrInvalidValue when value cannot be
+// stored in the column. NULL is accepted everywhere, NOT NULL constraints are
+// left to the database.
+func CheckValue(table, column string, value any) error {
+	t, err := Column(table, column)
+	if err != nil {
+		return err
+	}
+	if !fits(t, value) {
+		return fmt.Errorf("%w for %s.%s: %T is not %s", ErrInvalidValue, table, column, value, t)
+	}
+	return nil
+}
+
+func fits(t ColumnType, value any) bool {
+	if v, ok := value.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return true
+		}
+		underlying, err := v.Value()
+		if err != nil {
+			return false
+		}
+		value = underlying
+	}
+	if value == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return true
+		}
+		rv = rv.Elem()
+	}
+	value = rv.Interface()
+
+	switch t {
+	case Text:
+		_, isBytes := value.([]byte)
+		return rv.Kind() == reflect.String || isBytes
+	case Integer:
+		return isInteger(rv)
+	case Boolean:
+		// SQLite stores booleans as 0 and 1.
+		if rv.Kind() == reflect.Bool {
+			return true
+		}
+		n := fmt.Sprint(value)
+		return isInteger(rv) && (n == "0" || n == "1")
+	case Timestamp:
+		if _, ok := value.(time.Time); ok {
+			return true
+		}
+		if rv.Kind() != reflect.String {
+			return false
+		}
+		for _, layout := range timestampLayouts {
+			if _, err := time.Parse(layout, rv.String()); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isInteger reports whether v is an integer, including the whole float64 numbers
+// JSON decodes integers to.
+func isInteger(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		return f == math.Trunc(f) && !math.IsInf(f, 0)
+	}
+	return false
+}
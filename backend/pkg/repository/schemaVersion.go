@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoDatabase is reported by the health checks when no database is configured.
+var ErrNoDatabase = errors.New("no database configured")
+
+// SchemaVersion returns the migration version recorded by golang-migrate and
+// whether a migration failed halfway through.
+func (q *Query) SchemaVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	if q.Db == nil {
+		return 0, false, ErrNoDatabase
+	}
+	err = q.db().QueryRowContext(ctx, q.Rebind("SELECT version, dirty FROM schema_migrations LIMIT 1")).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, fmt.Errorf("SchemaVersion: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Ping checks that the database answers.
+func (q *Query) Ping(ctx context.Context) error {
+	if q.Db == nil {
+		return ErrNoDatabase
+	}
+	return q.Db.PingContext(ctx)
+}
@@ -12,8 +12,12 @@ func (q *Query) FetchSessionUser(sessionID string) (string, error) {
 		FROM sessions 
 		WHERE session_token = ?
 	`
+	stmt, err := q.prepared(q.Rebind(query))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch session user: %w", err)
+	}
 	var userID string
-	err := q.Db.QueryRow(query, sessionID).Scan(&userID)
+	err = stmt.QueryRow(sessionID).Scan(&userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", nil
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"social/pkg/model"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// FetchUserSessions returns every unexpired session of a user, newest first.
+// The session matching currentToken is flagged as the current one.
+func (q *Query) FetchUserSessions(userID, currentToken string) ([]model.Session, error) {
+	rows, err := q.db().Query(q.Rebind(`
+		SELECT id, session_token, user_agent, ip_address, created_at, expires_at
+		FROM sessions
+		WHERE user_id = ? AND expires_at > ?
+		ORDER BY created_at DESC
+	`), userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("FetchUserSessions: failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+	for rows.Next() {
+		var session model.Session
+		var token string
+		var userAgent, ipAddress sql.NullString
+
+		if err := rows.Scan(
+			&session.ID,
+			&token,
+			&userAgent,
+			&ipAddress,
+			&session.CreatedAt,
+			&session.ExpiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("FetchUserSessions: failed to scan session: %w", err)
+		}
+
+		session.UserAgent = userAgent.String
+		session.IPAddress = ipAddress.String
+		session.Current = token == currentToken
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("FetchUserSessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// FetchSessionID returns the row id of the session identified by its token.
+func (q *Query) FetchSessionID(sessionToken string) (string, error) {
+	var id string
+	err := q.db().QueryRow(q.Rebind("SELECT id FROM sessions WHERE session_token = ?"), sessionToken).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrSessionNotFound
+		}
+		return "", fmt.Errorf("FetchSessionID: %w", err)
+	}
+	return id, nil
+}
+
+// ConnectionActive reports whether the session or API token a websocket was
+// opened with, given by its id, is still valid: it has not been revoked nor has
+// it expired, and its user is not disabled.
+func (q *Query) ConnectionActive(ownerID string) (bool, error) {
+	stmt, err := q.prepared(q.Rebind(`
+		SELECT EXISTS (
+			SELECT 1 FROM sessions s JOIN users u ON u.id = s.user_id
+			WHERE s.id = ? AND s.expires_at > ? AND u.disabled_at IS NULL
+		) OR EXISTS (
+			SELECT 1 FROM api_tokens t JOIN users u ON u.id = t.user_id
+			WHERE t.id = ? AND (t.expires_at IS NULL OR t.expires_at > ?) AND u.disabled_at IS NULL
+		)
+	`))
+	if err != nil {
+		return false, fmt.Errorf("ConnectionActive: %w", err)
+	}
+
+	now := time.Now()
+	var active bool
+	if err := stmt.QueryRow(ownerID, now, ownerID, now).Scan(&active); err != nil {
+		return false, fmt.Errorf("ConnectionActive: %w", err)
+	}
+	return active, nil
+}
+
+// DeleteUserSession revokes a single session belonging to userID.
+func (q *Query) DeleteUserSession(userID, sessionID string) error {
+	res, err := q.db().Exec(q.Rebind("DELETE FROM sessions WHERE id = ? AND user_id = ?"), sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("DeleteUserSession: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("DeleteUserSession: %w", err)
+	}
+	if affected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteOtherSessions revokes every session of userID except the one identified
+// by currentToken and returns the ids of the revoked sessions.
+func (q *Query) DeleteOtherSessions(userID, currentToken string) ([]string, error) {
+	return q.deleteSessions(q.Table("sessions").Where("user_id", "=", userID).Where("session_token", "!=", currentToken))
+}
+
+// deleteSessions removes the sessions matching the conditions of b and returns
+// their ids so that the matching websocket clients can be disconnected.
+func (q *Query) deleteSessions(b *Builder) ([]string, error) {
+	ids, err := b.Returning("id").Delete().Strings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return ids, nil
+}
+
+// DeleteAllUserSessions revokes every session of userID and returns their ids.
+func (q *Query) DeleteAllUserSessions(userID string) ([]string, error) {
+	return q.deleteSessions(q.Table("sessions").Where("user_id", "=", userID))
+}
+
+// RenewSession moves the expiry of a session.
+func (q *Query) RenewSession(sessionToken string, expiresAt time.Time) error {
+	return q.UpdateData("sessions", []string{"session_token"}, []any{sessionToken}, []string{"expires_at"}, []any{expiresAt})
+}
+
+// DeleteExpiredSessions removes every expired session and returns their ids.
+func (q *Query) DeleteExpiredSessions() ([]string, error) {
+	return q.deleteSessions(q.Table("sessions").Where("expires_at", "<", time.Now()))
+}
+
+// SessionAuth is what the authentication middleware needs to know about a session.
+type SessionAuth struct {
+	UserID     string
+	CSRFToken  string
+	ExpiresAt  time.Time
+	RememberMe bool
+	// AbsoluteExpiresAt is nil for sessions created before absolute timeouts existed.
+	AbsoluteExpiresAt *time.Time
+	// Verified reports whether the owner confirmed their email address.
+	Verified bool
+}
+
+// FetchSessionAuth returns the session matching both cookies, expired or not.
+// Sessions of disabled users are not found.
+func (q *Query) FetchSessionAuth(sessionToken, csrfToken string) (SessionAuth, error) {
+	var auth SessionAuth
+	var absoluteExpiry sql.NullTime
+	err := q.db().QueryRow(q.Rebind(`
+		SELECT s.expires_at, s.absolute_expires_at, s.remember_me, s.csrf_token, u.verified_at IS NOT NULL, s.user_id
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.session_token = ? AND s.csrf_token = ? AND u.disabled_at IS NULL`),
+		sessionToken, csrfToken).Scan(&auth.ExpiresAt, &absoluteExpiry, &auth.RememberMe, &auth.CSRFToken, &auth.Verified, &auth.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return SessionAuth{}, ErrSessionNotFound
+		}
+		return SessionAuth{}, fmt.Errorf("FetchSessionAuth: %w", err)
+	}
+
+	if absoluteExpiry.Valid {
+		auth.AbsoluteExpiresAt = &absoluteExpiry.Time
+	}
+	return auth, nil
+}
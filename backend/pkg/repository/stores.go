@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"social/pkg/model"
+)
+
+// The interfaces below are what the handlers and websocket processors use to
+// reach the database. Query implements all of them on top of SQL, and the
+// memory package implements them in memory for tests.
+
+// RowStore is the generic row access used for simple inserts, updates and checks.
+type RowStore interface {
+	InsertData(table string, columns []string, values []any) error
+	UpdateData(table string, whereColumns []string, whereValues []any, columns []string, values []any) error
+	CheckRow(table string, whereColumns []string, whereValues []any) (bool, error)
+	DeleteData(table string, whereColumns []string, whereValues []any) error
+}
+
+// UserStore holds user profiles, credentials, follows and account lifecycle.
+type UserStore interface {
+	FetchUserInfo(userid string, user *model.UserData) error
+	FetchUserData(userid string) (model.UserData, error)
+	FetchAllUsers(userID string) (model.AllUsers, error)
+	FollowExists(followerID, followingID string) (exists bool, status string, err error)
+	CheckUserIsPublic(userID string) (bool, error)
+	UpdateUser(userid string, table string, columns []string, values []any) error
+	IsAdmin(userID string) (bool, error)
+	IsUserDisabled(userID string) (bool, error)
+
+	GetUserCredentials(identifier string) (userID, password string, err error)
+	FetchPasswordHash(userID string) (string, error)
+	UpdatePassword(userID, hashedPassword string) error
+	FetchUserIDByEmail(email string) (string, error)
+	FetchUserEmail(userID string) (string, error)
+	CreatePasswordReset(userID, tokenHash string, expiresAt time.Time) error
+	ConsumePasswordReset(tokenHash string) (string, error)
+
+	CreateEmailVerification(userID, email, tokenHash string, expiresAt time.Time) error
+	VerifyEmail(tokenHash string) (string, error)
+	IsEmailVerified(userID string) (bool, error)
+
+	FetchTOTP(userID string) (secret string, enabled bool, err error)
+	SetPendingTOTPSecret(userID, secret string) error
+	EnableTOTP(userID string) error
+	DisableTOTP(userID string) error
+	UseTOTPStep(userID string, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID string, codeHashes []string) error
+	UseRecoveryCode(userID, codeHash string) (bool, error)
+
+	FetchIdentityUser(issuer, subject string) (string, error)
+	LinkIdentity(userID, issuer, subject, email string) error
+	CreateIdentityUser(user model.User, verifiedAt *time.Time, issuer, subject string) error
+
+	ScheduleAccountDeletion(userID string, at time.Time) error
+	CancelAccountDeletion(userID string) (bool, error)
+	FetchAccountsDueForDeletion(now time.Time) ([]string, error)
+	DeleteAccount(userID string) ([]string, error)
+
+	CreateDataExport(userID string) (string, error)
+	CompleteDataExport(exportID, filePath string, expiresAt time.Time) error
+	FailDataExport(exportID string) error
+	FailPendingExports() error
+	FetchDataExports(userID string) ([]model.DataExport, error)
+	FetchDataExport(userID, exportID string) (model.DataExport, error)
+	DeleteFinishedExports(userID string) ([]string, error)
+	DeleteExpiredExports(now time.Time) ([]string, error)
+	FetchUserMediaFiles(userID string) ([]string, error)
+}
+
+// PostStore holds posts and their comments.
+type PostStore interface {
+	FetchAllPosts(userID string) ([]model.Post, error)
+	FetchPost(postID, userID string) (model.Post, error)
+}
+
+// GroupStore holds groups, their members and events.
+type GroupStore interface {
+	FetchAllGroups(userid string) ([]model.Groups, error)
+	FetchGroupData(groupid string, userID string) (model.GroupData, error)
+	FetchGroupId(title string) (string, error)
+	FetchGroupTitle(groupID string) (string, error)
+	FetchGroupAdmin(groupID string) (string, error)
+	FetchGroupJoinRequest(groupID string, group *model.GroupData) error
+	FetchAllGroupMembersId(groupID string) ([]string, error)
+	GetUserGroupIDs(userID string) ([]string, error)
+	FetchGroupMemberships(userID string) ([]model.GroupMembership, error)
+	DeleteGroup(groupName, userId string) error
+
+	CheckForRsvp(eventID, userID string) (bool, error)
+	FetchAttendingMembersCount(eventID string) (int, error)
+	FetchEventResponses(userID string) ([]model.EventResponse, error)
+}
+
+// MessageStore holds private and group chat messages.
+type MessageStore interface {
+	GetMessagesBetweenUsers(userAID, userBID string) ([]model.PrivateMessage, error)
+	GetGroupMessages(groupID string) ([]model.GroupMessage, error)
+	FetchConversationPartners(userID string) ([]string, error)
+	FetchSentGroupMessages(userID string) ([]model.GroupMessage, error)
+}
+
+// NotificationStore holds the notifications shown to users.
+type NotificationStore interface {
+	GetUserNotifications(userID string) ([]model.UserNotification, error)
+}
+
+// SessionStore holds browser sessions, pending logins, API tokens and login throttling.
+type SessionStore interface {
+	FetchSessionAuth(sessionToken, csrfToken string) (SessionAuth, error)
+	FetchSessionUser(sessionID string) (string, error)
+	FetchSessionID(sessionToken string) (string, error)
+	ConnectionActive(ownerID string) (bool, error)
+	FetchUserSessions(userID, currentToken string) ([]model.Session, error)
+	RenewSession(sessionToken string, expiresAt time.Time) error
+	DeleteSession(sessionToken string) error
+	DeleteUserSession(userID, sessionID string) error
+	DeleteOtherSessions(userID, currentToken string) ([]string, error)
+	DeleteAllUserSessions(userID string) ([]string, error)
+	DeleteExpiredSessions() ([]string, error)
+
+	CreateMFAChallenge(userID, tokenHash string, rememberMe bool, expiresAt time.Time) error
+	AttemptMFAChallenge(tokenHash string) (userID string, rememberMe bool, err error)
+	DeleteMFAChallenge(tokenHash string) error
+
+	CreateOIDCLogin(stateHash string, login OIDCLogin, expiresAt time.Time) error
+	ConsumeOIDCLogin(stateHash string) (OIDCLogin, error)
+
+	CreateAPIToken(userID, name, tokenHash string, scopes []string, expiresAt *time.Time) (string, error)
+	FetchUserAPITokens(userID string) ([]model.APIToken, error)
+	AuthenticateAPIToken(tokenHash string) (tokenID, userID string, scopes []string, err error)
+	FetchAPITokenUser(tokenHash string) (string, error)
+	DeleteAPIToken(userID, tokenID string) error
+	DeleteAllAPITokens(userID string) ([]string, error)
+
+	FetchLoginLock(key string) (time.Time, error)
+	RecordLoginFailure(key string, now, windowStart time.Time) (int, error)
+	LockLogin(key string, until time.Time) error
+	ClearLoginFailures(keys ...string) error
+}
+
+// Store is everything the application needs from its storage.
+type Store interface {
+	RowStore
+	UserStore
+	PostStore
+	GroupStore
+	MessageStore
+	NotificationStore
+	SessionStore
+
+	// WithTx runs fn in a transaction, committed when fn returns nil and
+	// rolled back otherwise.
+	WithTx(ctx context.Context, fn func(tx Store) error) error
+	// Ping checks that the storage can be reached.
+	Ping(ctx context.Context) error
+	// SchemaVersion returns the migration version of the storage and whether a
+	// migration failed halfway through.
+	SchemaVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+var _ Store = (*Query)(nil)
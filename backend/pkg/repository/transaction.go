@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// DBTX is the part of *sql.DB and *sql.Tx the queries run on, so the same
+// methods work inside and outside of a transaction.
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var (
+	_ DBTX = (*sql.DB)(nil)
+	_ DBTX = (*sql.Tx)(nil)
+)
+
+// db returns the transaction the query runs in, or the database outside of one.
+func (q *Query) db() DBTX {
+	if q.tx != nil {
+		return q.tx
+	}
+	if q.Reader != nil {
+		return pools{write: q.Db, read: q.Reader}
+	}
+	return q.Db
+}
+
+// pools runs the queries that only read on the read pool and the others, which
+// include the INSERT ... RETURNING run with Query, on the write pool.
+type pools struct {
+	write, read *sql.DB
+}
+
+func (p pools) pool(query string) *sql.DB {
+	if isSelect(query) {
+		return p.read
+	}
+	return p.write
+}
+
+func (p pools) Exec(query string, args ...any) (sql.Result, error) {
+	return p.write.Exec(query, args...)
+}
+
+func (p pools) Query(query string, args ...any) (*sql.Rows, error) {
+	return p.pool(query).Query(query, args...)
+}
+
+func (p pools) QueryRow(query string, args ...any) *sql.Row {
+	return p.pool(query).QueryRow(query, args...)
+}
+
+func (p pools) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return p.write.ExecContext(ctx, query, args...)
+}
+
+func (p pools) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.pool(query).QueryContext(ctx, query, args...)
+}
+
+func (p pools) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.pool(query).QueryRowContext(ctx, query, args...)
+}
+
+// isSelect reports whether query is a SELECT, which only reads. Queries
+// starting with WITH are not, as a common table expression can precede a write.
+func isSelect(query string) bool {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	return len(query) >= 6 && strings.EqualFold(query[:6], "SELECT")
+}
+
+// WithTx runs fn in a transaction. Every method called on the Store passed to
+// fn runs in it; the transaction is committed when fn returns nil and rolled
+// back when it returns an error or panics.
+//
+// Called on a Store that is already in a transaction, WithTx runs fn in that
+// same transaction, so the outermost call decides whether it is committed.
+func (q *Query) WithTx(ctx context.Context, fn func(tx Store) error) error {
+	return q.withTx(ctx, func(tx *Query) error { return fn(tx) })
+}
+
+func (q *Query) withTx(ctx context.Context, fn func(tx *Query) error) error {
+	if q.tx != nil {
+		return fn(q)
+	}
+
+	tx, err := q.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("WithTx: %w", err)
+	}
+	// Rolling back a committed transaction is a no-op.
+	defer tx.Rollback()
+
+	txQuery := &Query{Db: q.Db, Reader: q.Reader, Dialect: q.Dialect, tx: tx, stmts: q.statements()}
+	if err := fn(txQuery); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("WithTx: %w", err)
+	}
+	return nil
+}
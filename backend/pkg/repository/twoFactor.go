@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"social/pkg/util"
+)
+
+var ErrInvalidMFAToken = errors.New("invalid or expired two-factor login token")
+
+// MaxMFAAttempts is the number of codes that can be tried against one login challenge.
+const MaxMFAAttempts = 5
+
+// FetchTOTP returns the TOTP secret of a user and whether two-factor authentication
+// is enabled. The secret is set but not enabled while enrollment is pending.
+func (q *Query) FetchTOTP(userID string) (secret string, enabled bool, err error) {
+	var nullSecret sql.NullString
+	err = q.db().QueryRow(q.Rebind(`
+		SELECT totp_secret, totp_enabled_at IS NOT NULL
+		FROM users
+		WHERE id = ?
+	`), userID).Scan(&nullSecret, &enabled)
+	if err != nil {
+		return "", false, fmt.Errorf("FetchTOTP: %w", err)
+	}
+	return nullSecret.String, enabled, nil
+}
+
+// SetPendingTOTPSecret stores a new secret that becomes active once EnableTOTP is called.
+func (q *Query) SetPendingTOTPSecret(userID, secret string) error {
+	return q.UpdateData("users", []string{"id"}, []any{userID},
+		[]string{"totp_secret", "totp_enabled_at", "totp_last_step"}, []any{secret, nil, nil})
+}
+
+// EnableTOTP activates the pending secret of a user.
+func (q *Query) EnableTOTP(userID string) error {
+	return q.UpdateData("users", []string{"id"}, []any{userID},
+		[]string{"totp_enabled_at"}, []any{time.Now()})
+}
+
+// DisableTOTP removes the secret and every recovery code of a user.
+func (q *Query) DisableTOTP(userID string) error {
+	err := q.UpdateData("users", []string{"id"}, []any{userID},
+		[]string{"totp_secret", "totp_enabled_at", "totp_last_step"}, []any{nil, nil, nil})
+	if err != nil {
+		return err
+	}
+	return q.DeleteData("recovery_codes", []string{"user_id"}, []any{userID})
+}
+
+// UseTOTPStep records the time step of an accepted code. It returns false when a
+// code of the same or a later step was already used, which blocks replays.
+func (q *Query) UseTOTPStep(userID string, step int64) (bool, error) {
+	res, err := q.db().Exec(q.Rebind(`
+		UPDATE users
+		SET totp_last_step = ?
+		WHERE id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)
+	`), step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("UseTOTPStep: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("UseTOTPStep: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// ReplaceRecoveryCodes discards the recovery codes of a user and stores the given hashes.
+func (q *Query) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	if err := q.DeleteData("recovery_codes", []string{"user_id"}, []any{userID}); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		err := q.InsertData("recovery_codes", []string{
+			"id",
+			"user_id",
+			"code_hash",
+		}, []any{
+			util.UUIDGen(),
+			userID,
+			hash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It returns false when
+// the code does not exist or was used before.
+func (q *Query) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	res, err := q.db().Exec(q.Rebind(`
+		UPDATE recovery_codes
+		SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`), time.Now(), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("UseRecoveryCode: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("UseRecoveryCode: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// CreateMFAChallenge stores a pending two-factor login, along with the
+// "remember me" choice made when the password was entered.
+func (q *Query) CreateMFAChallenge(userID, tokenHash string, rememberMe bool, expiresAt time.Time) error {
+	return q.InsertData("mfa_challenges", []string{
+		"id",
+		"user_id",
+		"token_hash",
+		"remember_me",
+		"expires_at",
+	}, []any{
+		util.UUIDGen(),
+		userID,
+		tokenHash,
+		rememberMe,
+		expiresAt,
+	})
+}
+
+// AttemptMFAChallenge counts an attempt against a pending login and returns the
+// user it belongs to. Expired challenges and challenges with too many attempts are rejected.
+func (q *Query) AttemptMFAChallenge(tokenHash string) (userID string, rememberMe bool, err error) {
+	err = q.db().QueryRow(q.Rebind(`
+		UPDATE mfa_challenges
+		SET attempts = attempts + 1
+		WHERE token_hash = ? AND expires_at > ? AND attempts < ?
+		RETURNING user_id, remember_me
+	`), tokenHash, time.Now(), MaxMFAAttempts).Scan(&userID, &rememberMe)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, ErrInvalidMFAToken
+		}
+		return "", false, fmt.Errorf("AttemptMFAChallenge: %w", err)
+	}
+	return userID, rememberMe, nil
+}
+
+// DeleteMFAChallenge removes a pending login once it completed, along with any expired ones.
+func (q *Query) DeleteMFAChallenge(tokenHash string) error {
+	_, err := q.db().Exec(q.Rebind("DELETE FROM mfa_challenges WHERE token_hash = ? OR expires_at < ?"), tokenHash, time.Now())
+	return err
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"fmt"
-	"strings"
 )
 
 // UpdateData performs a conditional UPDATE operation on any table with dynamic WHERE and SET clauses.
@@ -30,28 +29,7 @@ func (q *Query) UpdateData(table string, whereColumns []string, whereValues []an
 		return fmt.Errorf("UpdateData: whereColumns and whereValues length mismatch")
 	}
 
-	// Build SET clause: col1 = ?, col2 = ?
-	setClauses := make([]string, len(columns))
-	for i, col := range columns {
-		setClauses[i] = fmt.Sprintf("%s = ?", col)
-	}
-
-	// Build WHERE clause: col1 = ? AND col2 = ?
-	whereClauses := make([]string, len(whereColumns))
-	for i, col := range whereColumns {
-		whereClauses[i] = fmt.Sprintf("%s = ?", col)
-	}
-
-	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE %s",
-		table,
-		strings.Join(setClauses, ", "),
-		strings.Join(whereClauses, " AND "),
-	)
-
-	args := append(values, whereValues...)
-
-	_, err := q.Db.Exec(query, args...)
+	_, err := q.Table(table).whereEqual(whereColumns, whereValues).Update(columns, values).Exec()
 	if err != nil {
 		return fmt.Errorf("UpdateData failed: %w", err)
 	}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"social/pkg/model"
+	"social/pkg/util"
+)
+
+var (
+	ErrInvalidOIDCState = errors.New("invalid or expired sign-in request")
+	ErrIdentityNotFound = errors.New("identity not linked to any account")
+)
+
+// OIDCLogin is a sign-in started with an external provider, kept until the
+// provider redirects back with the matching state.
+type OIDCLogin struct {
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	RememberMe   bool
+}
+
+// CreateOIDCLogin stores a pending sign-in under the hash of its state parameter.
+func (q *Query) CreateOIDCLogin(stateHash string, login OIDCLogin, expiresAt time.Time) error {
+	return q.InsertData("oidc_logins", []string{
+		"id",
+		"state_hash",
+		"provider",
+		"code_verifier",
+		"nonce",
+		"remember_me",
+		"expires_at",
+	}, []any{
+		util.UUIDGen(),
+		stateHash,
+		login.Provider,
+		login.CodeVerifier,
+		login.Nonce,
+		login.RememberMe,
+		expiresAt,
+	})
+}
+
+// ConsumeOIDCLogin deletes the pending sign-in with the given state hash and
+// returns it. Each state can only be used once and only before it expires.
+func (q *Query) ConsumeOIDCLogin(stateHash string) (OIDCLogin, error) {
+	var login OIDCLogin
+	err := q.db().QueryRow(q.Rebind(`
+		DELETE FROM oidc_logins
+		WHERE state_hash = ? AND expires_at > ?
+		RETURNING provider, code_verifier, nonce, remember_me
+	`), stateHash, time.Now()).Scan(&login.Provider, &login.CodeVerifier, &login.Nonce, &login.RememberMe)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return OIDCLogin{}, ErrInvalidOIDCState
+		}
+		return OIDCLogin{}, fmt.Errorf("ConsumeOIDCLogin: %w", err)
+	}
+	return login, nil
+}
+
+// FetchIdentityUser returns the user linked to the provider account issuer+subject.
+func (q *Query) FetchIdentityUser(issuer, subject string) (string, error) {
+	var userID string
+	err := q.db().QueryRow(q.Rebind("SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?"), issuer, subject).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrIdentityNotFound
+		}
+		return "", fmt.Errorf("FetchIdentityUser: %w", err)
+	}
+	return userID, nil
+}
+
+// LinkIdentity links the provider account issuer+subject to an existing user.
+func (q *Query) LinkIdentity(userID, issuer, subject, email string) error {
+	return q.InsertData("user_identities", []string{
+		"id",
+		"user_id",
+		"issuer",
+		"subject",
+		"email",
+	}, []any{
+		util.UUIDGen(),
+		userID,
+		issuer,
+		subject,
+		email,
+	})
+}
+
+// CreateIdentityUser creates a user for a first sign-in with a provider and links
+// the provider account to it. verifiedAt is nil when the provider did not vouch
+// for the email address.
+func (q *Query) CreateIdentityUser(user model.User, verifiedAt *time.Time, issuer, subject string) error {
+	return q.withTx(context.Background(), func(tx *Query) error {
+		_, err := tx.db().Exec(q.Rebind(`
+			INSERT INTO users (id, email, password, first_name, last_name, date_of_birth, avatar, nickname, about_me, is_public, verified_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`), user.ID, user.Email, user.Password, user.FirstName, user.LastName, user.DateOfBirth, user.Avatar, user.Nickname, user.AboutMe, user.IsPublic, verifiedAt)
+		if err != nil {
+			return fmt.Errorf("CreateIdentityUser: failed to insert user: %w", err)
+		}
+
+		_, err = tx.db().Exec(q.Rebind(`
+			INSERT INTO user_identities (id, user_id, issuer, subject, email)
+			VALUES (?, ?, ?, ?, ?)
+		`), util.UUIDGen(), user.ID, issuer, subject, user.Email)
+		if err != nil {
+			return fmt.Errorf("CreateIdentityUser: failed to link identity: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the address of the client that issued the request.
+// When the backend runs behind the Caddy reverse proxy the original address
+// is taken from the first entry of X-Forwarded-For, otherwise RemoteAddr is used.
+// The header is only trusted when the request comes from a loopback or private
+// address, so clients reaching the backend directly cannot pick their own address.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !fromProxy(host) {
+		return host
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		if ip := strings.TrimSpace(first); ip != "" {
+			return ip
+		}
+	}
+	return host
+}
+
+func fromProxy(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
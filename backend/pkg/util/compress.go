@@ -12,10 +12,8 @@ import (
 	"path/filepath"
 )
 
-const mediaPath = "pkg/db/media"
-
 // CompressJPEG reduces JPEG quality (1-100, lower = smaller size).
-// Returns the path to the compressed file.
+// Returns the path the compressed file is recorded under.
 func CompressJPEG(path string, quality int) (string, error) {
 	if quality < 1 || quality > 100 {
 		return "", errors.New("quality must be between 1 and 100")
@@ -32,16 +30,7 @@ func CompressJPEG(path string, quality int) (string, error) {
 		return "", err
 	}
 
-	// Ensure output directory exists
-	if err := os.MkdirAll(mediaPath, 0o755); err != nil {
-		return "", err
-	}
-
-	id := UUIDGen()
-	filename := id + "_" + filepath.Base(path)
-
-	outPath := filepath.Join(mediaPath, "compressed_"+filename)
-	out, err := os.Create(outPath)
+	out, outPath, err := createMediaFile(path)
 	if err != nil {
 		return outPath, err
 	}
@@ -52,7 +41,7 @@ func CompressJPEG(path string, quality int) (string, error) {
 }
 
 // CompressPNG compresses PNG with the best available compression.
-// Returns the path to the compressed file.
+// Returns the path the compressed file is recorded under.
 func CompressPNG(path string) (string, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -65,16 +54,7 @@ func CompressPNG(path string) (string, error) {
 		return "", err
 	}
 
-	// Ensure output directory exists
-	if err := os.MkdirAll(mediaPath, 0o755); err != nil {
-		return "", err
-	}
-
-	id := UUIDGen()
-	filename := id + "_" + filepath.Base(path)
-
-	outPath := filepath.Join(mediaPath, "compressed_"+filename)
-	out, err := os.Create(outPath)
+	out, outPath, err := createMediaFile(path)
 	if err != nil {
 		return outPath, err
 	}
@@ -85,7 +65,7 @@ func CompressPNG(path string) (string, error) {
 }
 
 // CompressGIF reduces color depth using a basic palette.
-// Returns the path to the compressed file.
+// Returns the path the compressed file is recorded under.
 func CompressGIF(path string, useWebSafe bool) (string, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -113,16 +93,7 @@ func CompressGIF(path string, useWebSafe bool) (string, error) {
 		gifImg.Image[i] = palettedImg
 	}
 
-	// Ensure output directory exists
-	if err := os.MkdirAll(mediaPath, 0o755); err != nil {
-		return "", err
-	}
-
-	id := UUIDGen()
-	filename := id + "_" + filepath.Base(path)
-
-	outPath := filepath.Join(mediaPath, "compressed_"+filename)
-	out, err := os.Create(outPath)
+	out, outPath, err := createMediaFile(path)
 	if err != nil {
 		return outPath, err
 	}
@@ -131,6 +102,19 @@ func CompressGIF(path string, useWebSafe bool) (string, error) {
 	return outPath, gif.EncodeAll(out, gifImg)
 }
 
+// createMediaFile creates the upload directory if needed and a new file in it
+// named after path. It returns the file and the path to record it under.
+func createMediaFile(path string) (*os.File, string, error) {
+	if err := os.MkdirAll(MediaDir(), 0o755); err != nil {
+		return nil, "", err
+	}
+
+	filename := "compressed_" + UUIDGen() + "_" + filepath.Base(path)
+	outPath := MediaPath + "/" + filename
+	out, err := os.Create(filepath.Join(MediaDir(), filename))
+	return out, outPath, err
+}
+
 // drawImage copies an image onto a paletted image
 func drawImage(dst *image.Paletted, src image.Image) {
 	b := src.Bounds()
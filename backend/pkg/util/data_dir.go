@@ -0,0 +1,40 @@
+package util
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// MediaPath is the path uploaded files are recorded under in the database and
+// served from. The files themselves are in the media directory of the data
+// directory, see MediaFile.
+const MediaPath = "pkg/db/media"
+
+// dataDir is the directory the server keeps its files in, set by SetDataDir.
+var dataDir = "pkg/db"
+
+// SetDataDir sets the directory the server keeps its files in. It is called
+// once at startup, before any file is read or written.
+func SetDataDir(dir string) {
+	dataDir = dir
+}
+
+// DataPath joins elem to the data directory.
+func DataPath(elem ...string) string {
+	return filepath.Join(append([]string{dataDir}, elem...)...)
+}
+
+// MediaDir returns the directory uploaded files are stored in.
+func MediaDir() string {
+	return DataPath("media")
+}
+
+// MediaFile returns where the upload recorded as path, MediaPath followed by
+// the file name, is on disk. Other paths are returned unchanged.
+func MediaFile(path string) string {
+	name, ok := strings.CutPrefix(path, MediaPath+"/")
+	if !ok {
+		return path
+	}
+	return filepath.Join(MediaDir(), name)
+}
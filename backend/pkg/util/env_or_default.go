@@ -0,0 +1,15 @@
+package util
+
+import "os"
+
+// EnvOrDefault looks a setting up in the process environment first, then in
+// the .env file, and falls back to the given default when neither defines it.
+func EnvOrDefault(key, fallback string) string {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		return val
+	}
+	if val, err := GetEnvVal(key); err == nil && val != "" {
+		return val
+	}
+	return fallback
+}
@@ -6,21 +6,46 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
+var (
+	envFileOnce   sync.Once
+	envFileValues map[string]string
+	envFileErr    error
+)
+
+// GetEnvVal returns a value from the .env file in the working directory. The
+// file is read once, on the first call.
 func GetEnvVal(s string) (string, error) {
+	envFileOnce.Do(func() {
+		envFileValues, envFileErr = readEnvFile()
+	})
+	if envFileErr != nil {
+		return "", envFileErr
+	}
+
+	value, ok := envFileValues[s]
+	if !ok {
+		return "", fmt.Errorf("environment variable with that value is not yet set")
+	}
+	return value, nil
+}
+
+func readEnvFile() (map[string]string, error) {
 	currentDir, err := os.Getwd()
 	if err != nil {
-		return "nil", err
+		return nil, err
 	}
 
 	environment_file := filepath.Join(currentDir, ".env")
 	file, err := os.Open(environment_file)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer file.Close()
 
+	values := make(map[string]string)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -30,14 +55,14 @@ func GetEnvVal(s string) (string, error) {
 
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
-			return "", fmt.Errorf("invalid line: %s", line)
+			return nil, fmt.Errorf("invalid line: %s", line)
 		}
 
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
-		if key == s {
-			return value, nil
+		if _, ok := values[key]; !ok {
+			values[key] = value
 		}
 	}
-	return "", fmt.Errorf("environment variable with that value is not yet set")
+	return values, scanner.Err()
 }
@@ -7,6 +7,13 @@ import (
 	"time"
 )
 
+// CookieOptions are the attributes shared by the session and CSRF token cookies.
+type CookieOptions struct {
+	Secure   bool
+	Domain   string
+	SameSite http.SameSite
+}
+
 // GenerateCSRFToken creates a random, base64-encoded CSRF token
 func GenerateCSRFToken() (string, error) {
 	b := make([]byte, 32)
@@ -17,17 +24,33 @@ func GenerateCSRFToken() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-// SetSessionCookie sets the session cookie and a CSRF token cookie
-func SetSessionCookie(w http.ResponseWriter, sessionID, csrfToken string) {
+// SetSessionCookie sets the session cookie and a CSRF token cookie.
+// A new CSRF token is generated on every call, so each login rotates it; the
+// token is returned so it can be stored with the session.
+// A zero expires makes both cookies last until the browser is closed.
+func SetSessionCookie(w http.ResponseWriter, opts CookieOptions, sessionID string, expires time.Time) (string, error) {
+	csrfToken, err := GenerateCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	RefreshSessionCookie(w, opts, sessionID, csrfToken, expires)
+	return csrfToken, nil
+}
+
+// RefreshSessionCookie sets the session and CSRF token cookies again with a new
+// expiry, keeping their values. It is used when a session is renewed.
+func RefreshSessionCookie(w http.ResponseWriter, opts CookieOptions, sessionID, csrfToken string, expires time.Time) {
 	// Set the session cookie
 	sessionCookie := http.Cookie{
 		Name:     "session_id",
 		Value:    sessionID,
 		Path:     "/",
+		Domain:   opts.Domain,
 		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(24 * time.Hour),
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
+		Expires:  expires,
 	}
 	http.SetCookie(w, &sessionCookie)
 
@@ -36,23 +59,26 @@ func SetSessionCookie(w http.ResponseWriter, sessionID, csrfToken string) {
 		Name:     "csrf_token",
 		Value:    csrfToken,
 		Path:     "/",
+		Domain:   opts.Domain,
 		HttpOnly: false,
-		Secure:   false,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(24 * time.Hour),
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
+		Expires:  expires,
 	}
 	http.SetCookie(w, &csrfCookie)
 }
 
-// ExpireSessionCookie expires the session and CSRF token cookies
-func ExpireSessionCookie(w http.ResponseWriter) {
+// ExpireSessionCookie expires the session and CSRF token cookies. The domain
+// must match the one they were set with for the browser to remove them.
+func ExpireSessionCookie(w http.ResponseWriter, opts CookieOptions) {
 	sessionCookie := http.Cookie{
 		Name:     "session_id",
 		Value:    "",
 		Path:     "/",
+		Domain:   opts.Domain,
 		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
 		Expires:  time.Unix(0, 0),
 	}
 
@@ -60,9 +86,10 @@ func ExpireSessionCookie(w http.ResponseWriter) {
 		Name:     "csrf_token",
 		Value:    "",
 		Path:     "/",
+		Domain:   opts.Domain,
 		HttpOnly: false,
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
 		Expires:  time.Unix(0, 0),
 	}
 
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"social/pkg/config"
+	"social/pkg/handler"
+	"social/pkg/util"
+	"social/pkg/websocket"
+)
+
+func TestAccountDeletionCancelledByLogin(t *testing.T) {
+	app, store := throttledLoginApp(t)
+	hub := websocket.NewHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+	mailer := &recordingMailer{}
+	app.Hub, app.Mailer = hub, mailer
+	app.Config.AccountDeletion.GracePeriod = config.Duration{Duration: 72 * time.Hour}
+
+	email := insertLoginUser(t, store)
+	userID, _, err := store.GetUserCredentials(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := insertMemorySession(t, store, userID)
+	if _, err := store.CreateAPIToken(userID, "ci", util.HashToken("deleted-token"), []string{handler.ScopePostsRead}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/me", strings.NewReader(`{"password": "`+throttleTestPassword+`"}`))
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: token})
+	rec := httptest.NewRecorder()
+	app.DeleteAccount(rec, req)
+	expectStatus(t, rec, http.StatusOK)
+
+	var response struct {
+		Data struct {
+			DeleteAt time.Time `json:"delete_at"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if !within(response.Data.DeleteAt, time.Now().Add(72*time.Hour)) {
+		t.Errorf("Expected the deletion after the 72h grace period, got %v", response.Data.DeleteAt)
+	}
+
+	// The user is logged out everywhere and told how to cancel.
+	if _, err := store.FetchSessionID(token); err == nil {
+		t.Error("Expected the session to be deleted")
+	}
+	if _, err := store.FetchAPITokenUser(util.HashToken("deleted-token")); err == nil {
+		t.Error("Expected the API token to be revoked")
+	}
+	if err := app.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sent := mailer.messages(); len(sent) != 1 || sent[0].To != email {
+		t.Errorf("Expected one email to %s, got %+v", email, sent)
+	}
+
+	due := func(at time.Time) bool {
+		ids, err := store.FetchAccountsDueForDeletion(at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return slices.Contains(ids, userID)
+	}
+	if due(time.Now()) || !due(time.Now().Add(73*time.Hour)) {
+		t.Fatal("Expected the account to be due only after the grace period")
+	}
+
+	expectStatus(t, login(app, "192.0.2.1:1234", email, throttleTestPassword), http.StatusOK)
+	if due(time.Now().Add(73 * time.Hour)) {
+		t.Error("Expected logging in to cancel the deletion")
+	}
+}
+
+func TestDeleteScheduledAccounts(t *testing.T) {
+	app, store := throttledLoginApp(t)
+	app.Config.AccountDeletion.SweepInterval = config.Duration{Duration: 10 * time.Millisecond}
+
+	due, pending := insertMemoryUser(t, store, true), insertMemoryUser(t, store, true)
+	if err := store.ScheduleAccountDeletion(due, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ScheduleAccountDeletion(pending, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	app.DeleteScheduledAccounts(ctx)
+
+	if _, err := store.FetchUserEmail(due); err == nil {
+		t.Error("Expected the account past its grace period to be deleted")
+	}
+	if _, err := store.FetchUserEmail(pending); err != nil {
+		t.Errorf("Expected the account within its grace period to be kept, got %v", err)
+	}
+}
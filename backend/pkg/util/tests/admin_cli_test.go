@@ -0,0 +1,166 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"social/pkg/cli"
+	"social/pkg/config"
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+func TestDisableUser(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		userID := insertTestUser(t, q)
+		email := userID + "@example.com"
+
+		sessionToken, csrfToken := util.UUIDGen(), util.UUIDGen()
+		err := q.InsertData("sessions",
+			[]string{"id", "user_id", "session_token", "csrf_token", "expires_at"},
+			[]any{util.UUIDGen(), userID, sessionToken, csrfToken, time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := q.CreateAPIToken(userID, "ci", "token-hash", []string{"read"}, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		sessions, disabled, err := q.DisableUser(userID)
+		if err != nil || !disabled || len(sessions) != 1 {
+			t.Fatalf("DisableUser() = %v, %v, %v, want one session signed out", sessions, disabled, err)
+		}
+		if _, _, err := q.GetUserCredentials(email); err == nil {
+			t.Error("Expected a disabled user not to be found at login")
+		}
+		if _, err := q.FetchSessionAuth(sessionToken, csrfToken); !errors.Is(err, repository.ErrSessionNotFound) {
+			t.Errorf("Expected the session to be deleted, got %v", err)
+		}
+		if _, _, _, err := q.AuthenticateAPIToken("token-hash"); !errors.Is(err, repository.ErrAPITokenNotFound) {
+			t.Errorf("Expected the API token to be deleted, got %v", err)
+		}
+
+		if _, disabled, err := q.DisableUser(userID); err != nil || disabled {
+			t.Errorf("Expected a second DisableUser() to report no change, got %v, %v", disabled, err)
+		}
+
+		enabled, err := q.EnableUser(userID)
+		if err != nil || !enabled {
+			t.Fatalf("EnableUser() = %v, %v", enabled, err)
+		}
+		if id, _, err := q.GetUserCredentials(email); err != nil || id != userID {
+			t.Errorf("Expected an enabled user to log in again, got %q, %v", id, err)
+		}
+	})
+}
+
+func TestCLIBackupRestore(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database = config.Database{
+		Driver: config.DriverSQLite,
+		Path:   sqliteTestDatabase(t),
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := cli.Run(context.Background(), cfg, args, strings.NewReader(""), &out); err != nil {
+			t.Fatalf("%s: %v\n%s", strings.Join(args, " "), err, out.String())
+		}
+		return out.String()
+	}
+	userExists := func(email string) bool {
+		t.Helper()
+		q := openTestDatabase(t, cfg.Database)
+		exists, err := q.CheckRow("users", []string{"email"}, []any{email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return exists
+	}
+
+	run("user", "create", "-email", "kept@example.com", "-first-name", "Kept", "-last-name", "User", "-dob", "1990-01-02")
+	q := openTestDatabase(t, cfg.Database)
+	userID, err := q.FetchUserIDByEmail("kept@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.FetchUserData(userID); err != nil {
+		t.Errorf("Expected the data of a user created by the CLI to load: %v", err)
+	}
+
+	backup := filepath.Join(t.TempDir(), "backup.db")
+	run("backup", backup)
+	run("user", "create", "-email", "lost@example.com", "-first-name", "Lost", "-last-name", "User", "-dob", "1990-01-02")
+
+	var out bytes.Buffer
+	if err := cli.Run(context.Background(), cfg, []string{"backup", backup}, strings.NewReader(""), &out); err == nil {
+		t.Error("Expected backup to refuse overwriting a file")
+	}
+
+	run("restore", backup)
+	if !userExists("kept@example.com") || userExists("lost@example.com") {
+		t.Error("Expected the database to be restored to the backup")
+	}
+
+	if got := run("migrate", "version"); !strings.HasPrefix(got, "version ") {
+		t.Errorf("migrate version printed %q", got)
+	}
+	if err := cli.Run(context.Background(), cfg, []string{"migrate", "down", "0"}, strings.NewReader(""), &out); !errors.Is(err, cli.ErrUsage) {
+		t.Errorf("Expected migrate down 0 to be a usage error, got %v", err)
+	}
+}
+
+func TestCLIResetPassword(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database = config.Database{
+		Driver: config.DriverSQLite,
+		Path:   sqliteTestDatabase(t),
+	}
+	q := openTestDatabase(t, cfg.Database)
+	userID := insertTestUser(t, q)
+	email := userID + "@example.com"
+
+	sessionToken, csrfToken := util.UUIDGen(), util.UUIDGen()
+	err := q.InsertData("sessions",
+		[]string{"id", "user_id", "session_token", "csrf_token", "expires_at"},
+		[]any{util.UUIDGen(), userID, sessionToken, csrfToken, time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.CreateAPIToken(userID, "ci", "token-hash", []string{"read"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	args := []string{"user", "reset-password", "-email", email, "-password-stdin"}
+	if err := cli.Run(context.Background(), cfg, args, strings.NewReader("weak\n"), &out); err == nil {
+		t.Error("Expected a weak password to be refused")
+	}
+
+	out.Reset()
+	if err := cli.Run(context.Background(), cfg, args, strings.NewReader("N3w!Passw0rd!long\n"), &out); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	if got := out.String(); !strings.Contains(got, "1 sessions signed out, 1 API tokens revoked") {
+		t.Errorf("reset-password printed %q", got)
+	}
+
+	_, hash, err := q.GetUserCredentials(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := util.ValidatePassword("N3w!Passw0rd!long", hash); err != nil {
+		t.Errorf("Expected the password read from stdin to be set: %v", err)
+	}
+	if _, err := q.FetchSessionAuth(sessionToken, csrfToken); !errors.Is(err, repository.ErrSessionNotFound) {
+		t.Errorf("Expected the session to be deleted, got %v", err)
+	}
+	if _, _, _, err := q.AuthenticateAPIToken("token-hash"); !errors.Is(err, repository.ErrAPITokenNotFound) {
+		t.Errorf("Expected the API token to be revoked, got %v", err)
+	}
+}
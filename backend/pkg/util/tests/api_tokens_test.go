@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"social/pkg/config"
+	"social/pkg/handler"
+	"social/pkg/repository/memory"
+	"social/pkg/util"
+	"social/pkg/websocket"
+)
+
+func TestTokenScopesPerRoute(t *testing.T) {
+	store := memory.New()
+	hub := websocket.NewHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+	app := &handler.App{Config: config.Default(), Queries: store, Hub: hub}
+	routes := app.Routes()
+
+	userID := insertMemoryUser(t, store, true)
+	scopes := []string{handler.ScopePostsRead, handler.ScopePostsWrite, handler.ScopeGroups, handler.ScopeMessages}
+	for _, scope := range scopes {
+		if _, err := store.CreateAPIToken(userID, scope, util.HashToken(scope+"-token"), []string{scope}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		method, path string
+		scope        string
+	}{
+		{http.MethodGet, "/api/v1/posts", handler.ScopePostsRead},
+		{http.MethodGet, "/api/v1/posts/" + util.UUIDGen(), handler.ScopePostsRead},
+		{http.MethodGet, "/api/v1/me", handler.ScopePostsRead},
+		{http.MethodGet, "/api/v1/users", handler.ScopePostsRead},
+		{http.MethodGet, "/api/v1/users/" + userID, handler.ScopePostsRead},
+		{http.MethodPost, "/api/v1/posts", handler.ScopePostsWrite},
+		{http.MethodPost, "/api/v1/posts/" + util.UUIDGen() + "/comments", handler.ScopePostsWrite},
+		{http.MethodPost, "/api/v1/posts/" + util.UUIDGen() + "/like", handler.ScopePostsWrite},
+		{http.MethodPost, "/api/v1/comments/" + util.UUIDGen() + "/like", handler.ScopePostsWrite},
+		{http.MethodGet, "/api/v1/groups", handler.ScopeGroups},
+		{http.MethodPost, "/api/v1/groups", handler.ScopeGroups},
+		{http.MethodGet, "/api/v1/groups/" + util.UUIDGen(), handler.ScopeGroups},
+		{http.MethodDelete, "/api/v1/groups/" + util.UUIDGen(), handler.ScopeGroups},
+		{http.MethodPost, "/api/v1/events/" + util.UUIDGen() + "/rsvp", handler.ScopeGroups},
+		{http.MethodGet, "/api/v1/ws", handler.ScopeMessages},
+		// Legacy routes need the scope of their successor.
+		{http.MethodGet, "/api/getPosts", handler.ScopePostsRead},
+		{http.MethodPost, "/api/addPost", handler.ScopePostsWrite},
+	}
+
+	for _, tt := range tests {
+		for _, scope := range scopes {
+			rec := request(tt.method, tt.path, scope+"-token")
+			refused := rec.Code == http.StatusForbidden && strings.Contains(rec.Body.String(), "lacks the "+tt.scope+" scope")
+			if scope == tt.scope && rec.Code == http.StatusForbidden {
+				t.Errorf("%s %s: expected a %s token to be let through, got %d: %s", tt.method, tt.path, scope, rec.Code, rec.Body.String())
+			}
+			if scope != tt.scope && !refused {
+				t.Errorf("%s %s: expected a %s token to lack the %s scope, got %d: %s", tt.method, tt.path, scope, tt.scope, rec.Code, rec.Body.String())
+			}
+		}
+	}
+
+	// Account management stays limited to browsers, whatever the scopes.
+	if _, err := store.CreateAPIToken(userID, "all", util.HashToken("all-token"), scopes, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/me/sessions"},
+		{http.MethodPatch, "/api/v1/me/password"},
+		{http.MethodDelete, "/api/v1/me"},
+		{http.MethodPost, "/api/v1/me/tokens"},
+		{http.MethodDelete, "/api/v1/me/2fa"},
+		{http.MethodPost, "/api/v1/me/exports"},
+	} {
+		rec := request(route.method, route.path, "all-token")
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "not available to API tokens") {
+			t.Errorf("%s %s: expected the route to refuse tokens, got %d: %s", route.method, route.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Expired and revoked tokens authenticate nothing.
+	expired := time.Now().Add(-time.Minute)
+	if _, err := store.CreateAPIToken(userID, "expired", util.HashToken("expired-token"), scopes, &expired); err != nil {
+		t.Fatal(err)
+	}
+	if rec := request(http.MethodGet, "/api/v1/posts", "expired-token"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an expired token to be refused, got %d", rec.Code)
+	}
+	if _, err := store.DeleteAllAPITokens(userID); err != nil {
+		t.Fatal(err)
+	}
+	if rec := request(http.MethodGet, "/api/v1/posts", handler.ScopePostsRead+"-token"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked token to be refused, got %d", rec.Code)
+	}
+}
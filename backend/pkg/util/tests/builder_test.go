@@ -0,0 +1,150 @@
+package test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"social/pkg/repository"
+	"social/pkg/util"
+)
+
+func TestSchemaMatchesMigrations(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		query := "SELECT m.name, p.name FROM sqlite_master m JOIN pragma_table_info(m.name) p WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'"
+		if q.Dialect == repository.Postgres {
+			query = "SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = current_schema()"
+		}
+		rows, err := q.Db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		migrated := make(map[string][]string)
+		for rows.Next() {
+			var table, column string
+			if err := rows.Scan(&table, &column); err != nil {
+				t.Fatal(err)
+			}
+			if table != "schema_migrations" {
+				migrated[table] = append(migrated[table], column)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		for table, columns := range migrated {
+			for _, column := range columns {
+				if _, err := repository.Column(table, column); err != nil {
+					t.Errorf("Schema is missing a migrated column: %v", err)
+				}
+			}
+		}
+		for table, columns := range repository.Schema {
+			for column := range columns {
+				if !slices.Contains(migrated[table], column) {
+					t.Errorf("Schema has %s.%s, which no migration creates", table, column)
+				}
+			}
+		}
+	})
+}
+
+func TestBuilder(t *testing.T) {
+	q := &repository.Query{Dialect: repository.Postgres}
+
+	stmt := q.Table("posts").
+		Where("user_id", "=", "u1").
+		WhereIn("privacy", "public", "almost_private").
+		OrderBy("created_at", true).
+		Limit(10).
+		Select("id", "content")
+	wantSQL := "SELECT id, content FROM posts WHERE user_id = $1 AND privacy IN ($2, $3) ORDER BY created_at DESC LIMIT 10"
+	if stmt.Err() != nil || stmt.SQL != wantSQL {
+		t.Errorf("Select() = %q, %v, want %q", stmt.SQL, stmt.Err(), wantSQL)
+	}
+	if want := []any{"u1", "public", "almost_private"}; !reflect.DeepEqual(stmt.Args, want) {
+		t.Errorf("Select() args = %v, want %v", stmt.Args, want)
+	}
+
+	stmt = q.Table("users").Where("id", "=", "u1").Returning("email").Update([]string{"nickname"}, []any{"nick"})
+	wantSQL = "UPDATE users SET nickname = $1 WHERE id = $2 RETURNING email"
+	if stmt.SQL != wantSQL || !reflect.DeepEqual(stmt.Args, []any{"nick", "u1"}) {
+		t.Errorf("Update() = %q %v, want %q", stmt.SQL, stmt.Args, wantSQL)
+	}
+
+	tests := []struct {
+		name string
+		stmt *repository.Statement
+		err  error
+	}{
+		{"unknown table", q.Table("users; DROP TABLE users").Select("id"), repository.ErrUnknownTable},
+		{"unknown column", q.Table("users").Where("id = id OR 1", "=", 1).Select("id"), repository.ErrUnknownColumn},
+		{"unknown selected column", q.Table("users").Select("password AS id"), repository.ErrUnknownColumn},
+		{"text for a boolean", q.Table("users").Where("id", "=", "u1").Update([]string{"is_public"}, []any{"yes"}), repository.ErrInvalidValue},
+		{"number for text", q.Table("users").Where("id", "=", 1).Select("id"), repository.ErrInvalidValue},
+		{"malformed timestamp", q.Table("sessions").Where("expires_at", "<", "tomorrow").Delete(), repository.ErrInvalidValue},
+		{"invalid IN value", q.Table("posts").WhereIn("likes_count", 1, "two").Select("id"), repository.ErrInvalidValue},
+		{"update without condition", q.Table("users").Update([]string{"nickname"}, []any{"nick"}), nil},
+		{"delete without condition", q.Table("users").Delete(), nil},
+		{"limit on delete", q.Table("users").Where("id", "=", "u1").Limit(1).Delete(), nil},
+		{"unsupported operator", q.Table("users").Where("id", "LIKE", "%").Select("id"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stmt.Err() == nil {
+				t.Fatalf("Expected an error, got %q", tt.stmt.SQL)
+			}
+			if tt.err != nil && !errors.Is(tt.stmt.Err(), tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, tt.stmt.Err())
+			}
+		})
+	}
+}
+
+func TestBuilderQueries(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		userID := insertTestUser(t, q)
+		start := time.Now().Add(-time.Hour)
+
+		var ids []string
+		for i := range 3 {
+			var id string
+			err := q.Table("posts").
+				Returning("id").
+				Insert([]string{"id", "user_id", "content", "created_at"}, []any{util.UUIDGen(), userID, "post", start.Add(time.Duration(i) * time.Minute)}).
+				Scan(&id)
+			if err != nil {
+				t.Fatalf("Insert() error: %v", err)
+			}
+			ids = append(ids, id)
+		}
+
+		got, err := q.Table("posts").
+			WhereIn("id", ids[0], ids[2]).
+			OrderBy("created_at", true).
+			Limit(5).
+			Select("id").
+			Strings()
+		if err != nil {
+			t.Fatalf("Select() error: %v", err)
+		}
+		if want := []string{ids[2], ids[0]}; !slices.Equal(got, want) {
+			t.Errorf("Select() = %v, want %v", got, want)
+		}
+
+		deleted, err := q.Table("posts").Where("user_id", "=", userID).Returning("id").Delete().Strings()
+		if err != nil {
+			t.Fatalf("Delete() error: %v", err)
+		}
+		slices.Sort(deleted)
+		slices.Sort(ids)
+		if !slices.Equal(deleted, ids) {
+			t.Errorf("Delete() returned %v, want %v", deleted, ids)
+		}
+	})
+}
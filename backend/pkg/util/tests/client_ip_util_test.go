@@ -0,0 +1,37 @@
+package test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"social/pkg/util"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5123", "", "203.0.113.7"},
+		{"spoofed header from public address", "203.0.113.7:5123", "198.51.100.1", "203.0.113.7"},
+		{"local proxy", "127.0.0.1:40000", "198.51.100.1, 10.0.0.2", "198.51.100.1"},
+		{"docker network proxy", "172.18.0.3:40000", "198.51.100.1", "198.51.100.1"},
+		{"proxy without header", "172.18.0.3:40000", "", "172.18.0.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			if got := util.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"social/pkg/config"
+)
+
+func TestConfigDefaults(t *testing.T) {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Expected the defaults to be valid, got %v", err)
+	}
+	if cfg.Server.Addr != ":8000" || cfg.Cookies.Secure {
+		t.Errorf("Unexpected defaults %+v", cfg)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(file, []byte(`{
+		"server": {"addr": ":9000", "frontend_url": "https://social.example.com"},
+		"cors": {"allowed_origins": ["https://social.example.com"]},
+		"cookies": {"secure": true, "domain": "example.com"}
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SERVER_ADDR", ":9100")
+	t.Setenv("COOKIE_DOMAIN", "social.example.com")
+
+	cfg, err := config.Load([]string{"-config", file, "-addr", "127.0.0.1:9200"})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if cfg.Server.Addr != "127.0.0.1:9200" {
+		t.Errorf("Expected the flag to win, got addr %q", cfg.Server.Addr)
+	}
+	if cfg.Cookies.Domain != "social.example.com" {
+		t.Errorf("Expected the environment to override the file, got domain %q", cfg.Cookies.Domain)
+	}
+	if !cfg.Cookies.Secure || cfg.Server.FrontendURL != "https://social.example.com" {
+		t.Errorf("Expected the file to override the defaults, got %+v", cfg)
+	}
+	if cfg.Database.Path != "pkg/db/backend.db" {
+		t.Errorf("Expected unset values to keep their default, got %q", cfg.Database.Path)
+	}
+
+	opts := cfg.Cookies.Options()
+	if !opts.Secure || opts.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Unexpected cookie options %+v", opts)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"bad address", []string{"-addr", "8000"}, "server addr"},
+		{"origin with path", []string{"-cors-origins", "https://example.com/app"}, "cors origin"},
+		{"samesite none without secure", []string{"-cookie-samesite", "none"}, "requires secure"},
+		{"unknown samesite", []string{"-cookie-samesite", "loose"}, "same_site"},
+		{"unknown database driver", []string{"-db-driver", "mysql"}, "database driver"},
+		{"postgres without url", []string{"-db-driver", "postgres"}, "database url"},
+		{"invalid duration", []string{"-session-idle-timeout", "forever"}, "session-idle-timeout"},
+		{"negative duration", []string{"-login-lockout-duration", "-1m"}, "login lockout duration"},
+		{"invalid number", []string{"-login-max-account-failures", "five"}, "login-max-account-failures"},
+		{"no failures allowed", []string{"-login-max-ip-failures", "0"}, "login max ip failures"},
+		{"unknown unverified policy", []string{"-unverified-login-policy", "allow"}, "unverified policy"},
+		{"invalid sender", []string{"-mail-from", "nobody"}, "mail from"},
+		{"invalid smtp port", []string{"-smtp-port", "70000"}, "smtp port"},
+		{"issuer with a colon", []string{"-totp-issuer", "Social:Network"}, "totp issuer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Load(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error about %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := config.Load([]string{"-cookie-secure", "-cookie-samesite", "none"}); err != nil {
+		t.Errorf("Expected secure SameSite=None cookies to be valid, got %v", err)
+	}
+
+	cfg, err := config.Load([]string{"-db-driver", "postgres", "-db-url", "postgres://localhost/social"})
+	if err != nil {
+		t.Fatalf("Expected a postgres configuration to be valid, got %v", err)
+	}
+	if cfg.Database.MigrationsPath != "" {
+		t.Errorf("Expected the embedded migrations by default, got %q", cfg.Database.MigrationsPath)
+	}
+
+	cfg, err = config.Load([]string{"-data-dir", "/srv/social"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Path != "/srv/social/backend.db" {
+		t.Errorf("Expected the database in the data directory, got %q", cfg.Database.Path)
+	}
+	cfg, err = config.Load([]string{"-data-dir", "/srv/social", "-db", "/var/lib/social.db"})
+	if err != nil || cfg.Database.Path != "/var/lib/social.db" {
+		t.Errorf("Expected an explicit database path to be kept, got %q, %v", cfg.Database.Path, err)
+	}
+}
+
+func TestConfigFromEnvironment(t *testing.T) {
+	t.Setenv("SESSION_ABSOLUTE_TIMEOUT", "72h")
+	t.Setenv("LOGIN_MAX_ACCOUNT_FAILURES", "3")
+	t.Setenv("OIDC_PROVIDERS", "Example")
+	t.Setenv("OIDC_EXAMPLE_ISSUER", "https://id.example.com")
+	t.Setenv("OIDC_EXAMPLE_CLIENT_ID", "social")
+	t.Setenv("OIDC_EXAMPLE_SCOPES", "groups offline_access")
+
+	cfg, err := config.Load([]string{"-data-dir", "/srv/social"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Sessions.AbsoluteTimeout.Hours() != 72 || cfg.Login.MaxAccountFailures != 3 {
+		t.Errorf("Expected the environment to set the sessions and login sections, got %+v %+v", cfg.Sessions, cfg.Login)
+	}
+	if cfg.Mail.OutboxDir != "/srv/social/outbox" {
+		t.Errorf("Expected the outbox in the data directory, got %q", cfg.Mail.OutboxDir)
+	}
+	if len(cfg.OIDC.Providers) != 1 {
+		t.Fatalf("Expected one OIDC provider, got %+v", cfg.OIDC.Providers)
+	}
+	if p := cfg.OIDC.Providers[0]; p.Name != "example" || p.Issuer != "https://id.example.com" || len(p.Scopes) != 2 {
+		t.Errorf("Unexpected OIDC provider %+v", p)
+	}
+
+	t.Setenv("LOGIN_BACKOFF_BASE", "1")
+	if _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "LOGIN_BACKOFF_BASE") {
+		t.Errorf("Expected an invalid environment value to fail, got %v", err)
+	}
+	t.Setenv("LOGIN_BACKOFF_BASE", "")
+
+	t.Setenv("OIDC_EXAMPLE_CLIENT_ID", "")
+	if _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "client id") {
+		t.Errorf("Expected a provider without a client id to fail, got %v", err)
+	}
+}
+
+func TestConfigFileSections(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(file, []byte(`{
+		"mail": {"from": "social@example.com", "smtp": {"host": "smtp.example.com", "port": 465}},
+		"oidc": {
+			"redirect_url": "https://api.example.com/api/v1/oidc/callback",
+			"providers": [{"name": "example", "issuer": "https://id.example.com", "client_id": "social"}]
+		},
+		"sessions": {"idle_timeout": "2h"},
+		"login": {"unverified_policy": "block", "totp_issuer": "Example"},
+		"account_deletion": {"grace_period": "168h"}
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Load([]string{"-config", file})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Mail.SMTP.Host != "smtp.example.com" || cfg.Mail.SMTP.Port != 465 || cfg.Mail.From != "social@example.com" {
+		t.Errorf("Unexpected mail section %+v", cfg.Mail)
+	}
+	if len(cfg.OIDC.Providers) != 1 || cfg.OIDC.Providers[0].ClientID != "social" {
+		t.Errorf("Unexpected oidc section %+v", cfg.OIDC)
+	}
+	if cfg.Sessions.IdleTimeout.Hours() != 2 || cfg.Sessions.AbsoluteTimeout.Hours() != 30*24 {
+		t.Errorf("Expected the file to override only the idle timeout, got %+v", cfg.Sessions)
+	}
+	if cfg.Login.UnverifiedPolicy != config.UnverifiedBlock || cfg.Login.TOTPIssuer != "Example" || cfg.Login.MaxIPFailures != 20 {
+		t.Errorf("Unexpected login section %+v", cfg.Login)
+	}
+	if cfg.AccountDeletion.GracePeriod.Hours() != 168 {
+		t.Errorf("Unexpected account deletion section %+v", cfg.AccountDeletion)
+	}
+}
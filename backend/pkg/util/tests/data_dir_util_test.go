@@ -0,0 +1,42 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"social/pkg/config"
+	db "social/pkg/db"
+	"social/pkg/util"
+)
+
+func TestMediaFile(t *testing.T) {
+	util.SetDataDir("/srv/social")
+	t.Cleanup(func() { util.SetDataDir("pkg/db") })
+
+	tests := map[string]string{
+		util.MediaPath + "/compressed_a.png": filepath.FromSlash("/srv/social/media/compressed_a.png"),
+		"https://example.com/avatar.png":     "https://example.com/avatar.png",
+		"/srv/social/exports/u/e.zip":        "/srv/social/exports/u/e.zip",
+	}
+	for path, want := range tests {
+		if got := util.MediaFile(path); got != want {
+			t.Errorf("MediaFile(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, driver := range []string{config.DriverSQLite, config.DriverPostgres} {
+		embedded, err := db.LatestSchemaVersion(config.Database{Driver: driver})
+		if err != nil {
+			t.Fatal(err)
+		}
+		onDisk, err := db.LatestSchemaVersion(config.Database{Driver: driver, MigrationsPath: "../../db/" + driver})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if embedded == 0 || embedded != onDisk {
+			t.Errorf("%s: embedded migrations are at version %d, the directory at %d", driver, embedded, onDisk)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"social/pkg/config"
+	"social/pkg/handler"
+	"social/pkg/mail"
+	"social/pkg/repository/memory"
+	"social/pkg/util"
+)
+
+// recordingMailer keeps the emails it is asked to send.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *recordingMailer) messages() []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mail.Message(nil), m.sent...)
+}
+
+var linkToken = regexp.MustCompile(`token=(\S+)`)
+
+// tokenFrom returns the token of the link in an email.
+func tokenFrom(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	match := linkToken.FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("Expected a link with a token in %q", msg.Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestEmailVerificationFlow(t *testing.T) {
+	cfg := config.Default()
+	cfg.Login.UnverifiedPolicy = config.UnverifiedBlock
+	store := memory.New()
+	mailer := &recordingMailer{}
+	app := &handler.App{Config: cfg, Queries: store, Mailer: mailer}
+	routes := app.Routes()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	userID := insertMemoryUser(t, store, false)
+	email := userID + "@example.com"
+	hash, err := util.EncryptPassword(throttleTestPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateData("users", []string{"id"}, []any{userID}, []string{"password"}, []any{hash}); err != nil {
+		t.Fatal(err)
+	}
+
+	// With the block policy, unverified accounts cannot log in.
+	expectStatus(t, login(app, "192.0.2.1:1234", email, throttleTestPassword), http.StatusForbidden)
+
+	expectStatus(t, post("/api/v1/email-verifications", `{"email": "`+email+`"}`), http.StatusOK)
+	if err := app.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	sent := mailer.messages()
+	if len(sent) != 1 || sent[0].To != email {
+		t.Fatalf("Expected one verification email to %s, got %+v", email, sent)
+	}
+	token := tokenFrom(t, sent[0])
+
+	expectStatus(t, post("/api/v1/email-verifications/confirm", `{"token": "wrong"}`), http.StatusBadRequest)
+	expectStatus(t, post("/api/v1/email-verifications/confirm", `{"token": "`+token+`"}`), http.StatusOK)
+	if verified, err := store.IsEmailVerified(userID); err != nil || !verified {
+		t.Fatalf("Expected the email to be verified, got %v, %v", verified, err)
+	}
+	expectStatus(t, login(app, "192.0.2.1:1234", email, throttleTestPassword), http.StatusOK)
+
+	// Links work once, and verified accounts are not sent new ones.
+	expectStatus(t, post("/api/v1/email-verifications/confirm", `{"token": "`+token+`"}`), http.StatusBadRequest)
+	expectStatus(t, post("/api/v1/email-verifications", `{"email": "`+email+`"}`), http.StatusOK)
+	if err := app.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sent := mailer.messages(); len(sent) != 1 {
+		t.Errorf("Expected no email for a verified account, got %+v", sent[1:])
+	}
+
+	// Unknown addresses get the same answer, without an email.
+	expectStatus(t, post("/api/v1/email-verifications", `{"email": "nobody@example.com"}`), http.StatusOK)
+	if err := app.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sent := mailer.messages(); len(sent) != 1 {
+		t.Errorf("Expected no email for an unknown address, got %+v", sent[1:])
+	}
+}
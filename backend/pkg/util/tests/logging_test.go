@@ -0,0 +1,98 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"social/pkg/handler"
+	"social/pkg/logging"
+)
+
+func TestTraceID(t *testing.T) {
+	tests := []struct {
+		traceparent string
+		want        string
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", ""},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ""},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ""},
+		{"not a trace", ""},
+	}
+
+	for _, tt := range tests {
+		if got := logging.TraceID(tt.traceparent); got != tt.want {
+			t.Errorf("TraceID(%q) = %q, want %q", tt.traceparent, got, tt.want)
+		}
+	}
+}
+
+// captureLogs sends the default logger's JSON output to a buffer for the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, "json", slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestRequestLogger(t *testing.T) {
+	logs := captureLogs(t)
+	app := &handler.App{}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.SetUserID(r.Context(), "user-1")
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	r := httptest.NewRequest("GET", "/api/profile?token=secret", nil)
+	r.Header.Set("X-Request-ID", "req-123")
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	app.RequestLogger(next).ServeHTTP(w, r)
+
+	if got := w.Header().Get("X-Request-ID"); got != "req-123" {
+		t.Errorf("Expected the request id to be echoed, got %q", got)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON log entry, got %q: %v", logs.String(), err)
+	}
+	want := map[string]any{
+		"msg":        "request",
+		"method":     "GET",
+		"path":       "/api/profile",
+		"status":     float64(http.StatusTeapot),
+		"request_id": "req-123",
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"user_id":    "user-1",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("Expected %s=%v in the log entry, got %v", key, value, entry[key])
+		}
+	}
+	if bytes.Contains(logs.Bytes(), []byte("secret")) {
+		t.Error("Expected the query string to be left out of the logs")
+	}
+}
+
+func TestRequestLoggerReplacesInvalidRequestID(t *testing.T) {
+	captureLogs(t)
+	app := &handler.App{}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-ID", "bad id\nwith newline")
+	w := httptest.NewRecorder()
+	app.RequestLogger(http.NotFoundHandler()).ServeHTTP(w, r)
+
+	got := w.Header().Get("X-Request-ID")
+	if got == "" || got == r.Header.Get("X-Request-ID") || !logging.ValidID(got) {
+		t.Errorf("Expected a generated request id, got %q", got)
+	}
+}
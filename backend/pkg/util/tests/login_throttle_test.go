@@ -0,0 +1,201 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"social/pkg/config"
+	"social/pkg/handler"
+	"social/pkg/repository/memory"
+	"social/pkg/util"
+)
+
+const throttleTestPassword = "Passw0rd!long"
+
+// throttledLoginApp returns an app whose login backoff starts at 100ms, so the
+// tests can wait it out, and which locks accounts after three failures.
+func throttledLoginApp(t *testing.T) (*handler.App, *memory.Store) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Login.BackoffBase = config.Duration{Duration: 100 * time.Millisecond}
+	cfg.Login.LockoutDuration = config.Duration{Duration: time.Hour}
+	cfg.Login.MaxAccountFailures = 3
+	cfg.Login.MaxIPFailures = 100
+
+	store := memory.New()
+	return &handler.App{Config: cfg, Queries: store}, store
+}
+
+func insertLoginUser(t *testing.T, store *memory.Store) string {
+	t.Helper()
+	id := insertMemoryUser(t, store, true)
+	hash, err := util.EncryptPassword(throttleTestPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateData("users", []string{"id"}, []any{id}, []string{"password"}, []any{hash}); err != nil {
+		t.Fatal(err)
+	}
+	return id + "@example.com"
+}
+
+// login posts the credentials from addr and returns the response.
+func login(app *handler.App, addr, email, password string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", nil)
+	req.RemoteAddr = addr
+	req.SetBasicAuth(email, password)
+
+	rec := httptest.NewRecorder()
+	app.Login(rec, req)
+	return rec
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("Expected status %d, got %d: %s", status, rec.Code, rec.Body.String())
+	}
+}
+
+func retryAfter(t *testing.T, rec *httptest.ResponseRecorder) int {
+	t.Helper()
+	seconds, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("Expected a Retry-After header in seconds, got %q", rec.Header().Get("Retry-After"))
+	}
+	return seconds
+}
+
+func TestLoginBackoff(t *testing.T) {
+	app, store := throttledLoginApp(t)
+	email := insertLoginUser(t, store)
+	addr := "192.0.2.1:1234"
+
+	expectStatus(t, login(app, addr, email, "wrong"), http.StatusUnauthorized)
+
+	// Even the right password is refused until the delay is over.
+	rec := login(app, addr, email, throttleTestPassword)
+	expectStatus(t, rec, http.StatusTooManyRequests)
+	if seconds := retryAfter(t, rec); seconds != 1 {
+		t.Errorf("Expected to retry after 1 second, got %d", seconds)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	expectStatus(t, login(app, addr, email, "wrong"), http.StatusUnauthorized)
+
+	// The second failure doubles the delay to 200ms.
+	time.Sleep(150 * time.Millisecond)
+	expectStatus(t, login(app, addr, email, "wrong"), http.StatusTooManyRequests)
+	time.Sleep(60 * time.Millisecond)
+	expectStatus(t, login(app, addr, email, throttleTestPassword), http.StatusOK)
+}
+
+func TestLoginLockout(t *testing.T) {
+	app, store := throttledLoginApp(t)
+	email := insertLoginUser(t, store)
+
+	// Each attempt comes from another address, so only the account is locked.
+	for i := range 3 {
+		addr := "192.0.2." + strconv.Itoa(i+1) + ":1234"
+		expectStatus(t, login(app, addr, email, "wrong"), http.StatusUnauthorized)
+		time.Sleep(time.Duration(110<<i) * time.Millisecond)
+	}
+
+	rec := login(app, "198.51.100.1:1234", email, throttleTestPassword)
+	expectStatus(t, rec, http.StatusTooManyRequests)
+	if seconds := retryAfter(t, rec); seconds < 3590 || seconds > 3600 {
+		t.Errorf("Expected the account to be locked for an hour, got Retry-After %d", seconds)
+	}
+
+	// Other accounts can still log in from the same address.
+	expectStatus(t, login(app, "198.51.100.1:1234", insertLoginUser(t, store), throttleTestPassword), http.StatusOK)
+}
+
+func TestLoginSuccessResetsFailures(t *testing.T) {
+	app, store := throttledLoginApp(t)
+	email := insertLoginUser(t, store)
+
+	// Two failures, then a success. Without the reset, the next failure would
+	// be the third in a row and lock the account for an hour.
+	for i := range 2 {
+		expectStatus(t, login(app, "192.0.2.1:1234", email, "wrong"), http.StatusUnauthorized)
+		time.Sleep(time.Duration(110<<i) * time.Millisecond)
+	}
+	expectStatus(t, login(app, "192.0.2.2:1234", email, throttleTestPassword), http.StatusOK)
+
+	expectStatus(t, login(app, "192.0.2.3:1234", email, "wrong"), http.StatusUnauthorized)
+	time.Sleep(110 * time.Millisecond)
+	expectStatus(t, login(app, "192.0.2.3:1234", email, throttleTestPassword), http.StatusOK)
+}
+
+// insertMemorySession logs the user in and returns the session token.
+func insertMemorySession(t *testing.T, store *memory.Store, userID string) string {
+	t.Helper()
+	token := util.UUIDGen()
+	expiresAt := time.Now().Add(time.Hour)
+	err := store.InsertData("sessions",
+		[]string{"id", "user_id", "session_token", "csrf_token", "expires_at", "absolute_expires_at"},
+		[]any{util.UUIDGen(), userID, token, util.UUIDGen(), expiresAt, expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// TestPasswordConfirmationThrottled checks that the handlers asking a logged in
+// user for their password count wrong passwords like failed logins.
+func TestPasswordConfirmationThrottled(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(app *handler.App) http.HandlerFunc
+		body    string
+	}{
+		{"change password", func(app *handler.App) http.HandlerFunc { return app.ChangePassword },
+			`{"current_password": "wrong", "new_password": "N3w!Passw0rd!long", "confirmed_password": "N3w!Passw0rd!long"}`},
+		{"delete account", func(app *handler.App) http.HandlerFunc { return app.DeleteAccount },
+			`{"password": "wrong"}`},
+		{"disable two-factor authentication", func(app *handler.App) http.HandlerFunc { return app.DisableTwoFactor },
+			`{"password": "wrong", "code": "000000"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, store := throttledLoginApp(t)
+			email := insertLoginUser(t, store)
+			userID, _, err := store.GetUserCredentials(email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token := insertMemorySession(t, store, userID)
+
+			confirm := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+				req.RemoteAddr = "192.0.2.1:1234"
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: token})
+				rec := httptest.NewRecorder()
+				tt.handler(app)(rec, req)
+				return rec
+			}
+
+			expectStatus(t, confirm(), http.StatusUnauthorized)
+			rec := confirm()
+			expectStatus(t, rec, http.StatusTooManyRequests)
+
+			// The failure counts towards the account lockout, which two failed
+			// logins now reach.
+			for i := range 2 {
+				time.Sleep(time.Duration(110<<i) * time.Millisecond)
+				expectStatus(t, login(app, "192.0.2.2:1234", email, "wrong"), http.StatusUnauthorized)
+			}
+			rec = login(app, "192.0.2.3:1234", email, throttleTestPassword)
+			expectStatus(t, rec, http.StatusTooManyRequests)
+			if seconds := retryAfter(t, rec); seconds < 3590 {
+				t.Errorf("Expected the account to be locked for an hour, got Retry-After %d", seconds)
+			}
+		})
+	}
+}
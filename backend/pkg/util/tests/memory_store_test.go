@@ -0,0 +1,373 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"social/pkg/config"
+	"social/pkg/handler"
+	"social/pkg/mail"
+	"social/pkg/repository"
+	"social/pkg/repository/memory"
+	"social/pkg/util"
+	"social/pkg/websocket"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+func insertMemoryUser(t *testing.T, store *memory.Store, verified bool) string {
+	t.Helper()
+	id := util.UUIDGen()
+	var verifiedAt *time.Time
+	if verified {
+		now := time.Now()
+		verifiedAt = &now
+	}
+	err := store.InsertData("users",
+		[]string{"id", "email", "password", "first_name", "last_name", "date_of_birth", "verified_at"},
+		[]any{id, id + "@example.com", "hash", "Test", "User", "2000-01-01", verifiedAt})
+	if err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	return id
+}
+
+func TestAuthMiddlewareWithMemoryStore(t *testing.T) {
+	store := memory.New()
+	app := &handler.App{Config: config.Default(), Queries: store}
+	protected := app.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	addSession := func(userID string, expiresAt time.Time) (token, csrf string) {
+		token, csrf = util.UUIDGen(), util.UUIDGen()
+		err := store.InsertData("sessions",
+			[]string{"id", "user_id", "session_token", "csrf_token", "expires_at", "absolute_expires_at"},
+			[]any{util.UUIDGen(), userID, token, csrf, expiresAt, expiresAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token, csrf
+	}
+
+	verified := insertMemoryUser(t, store, true)
+	unverified := insertMemoryUser(t, store, false)
+	validToken, validCSRF := addSession(verified, time.Now().Add(time.Hour))
+	expiredToken, expiredCSRF := addSession(verified, time.Now().Add(-time.Minute))
+	unverifiedToken, unverifiedCSRF := addSession(unverified, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name         string
+		method       string
+		token, csrf  string
+		expectedCode int
+	}{
+		{"valid session", http.MethodGet, validToken, validCSRF, http.StatusNoContent},
+		{"unknown session", http.MethodGet, util.UUIDGen(), validCSRF, http.StatusUnauthorized},
+		{"expired session", http.MethodGet, expiredToken, expiredCSRF, http.StatusUnauthorized},
+		{"unverified email may read", http.MethodGet, unverifiedToken, unverifiedCSRF, http.StatusNoContent},
+		{"unverified email may not write", http.MethodPost, unverifiedToken, unverifiedCSRF, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/addPost", nil)
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: tt.token})
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.csrf})
+			req.Header.Set("X-CSRF-Token", tt.csrf)
+
+			rec := httptest.NewRecorder()
+			protected.ServeHTTP(rec, req)
+			if rec.Code != tt.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUnfollowWithMemoryStore(t *testing.T) {
+	store := memory.New()
+	follower := insertMemoryUser(t, store, true)
+	followed := insertMemoryUser(t, store, true)
+	err := store.InsertData("user_follows",
+		[]string{"id", "follower_id", "following_id", "status"},
+		[]any{util.UUIDGen(), follower, followed, "accepted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &websocket.Client{UserID: follower, Send: make(chan []byte, 1)}
+	msg := map[string]any{"data": map[string]any{"recipient_Id": followed}}
+
+	client.Unfollow(msg, store)
+	select {
+	case reply := <-client.Send:
+		t.Fatalf("Expected no reply, got %s", reply)
+	default:
+	}
+	if exists, _, _ := store.FollowExists(follower, followed); exists {
+		t.Error("Expected the follow to be deleted")
+	}
+
+	client.Unfollow(msg, store)
+	select {
+	case <-client.Send:
+	default:
+		t.Error("Expected an error when unfollowing a user that is not followed")
+	}
+}
+
+func TestWebsocketWithToken(t *testing.T) {
+	store := memory.New()
+	hub := websocket.NewHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+	app := &handler.App{Config: config.Default(), Queries: store, Hub: hub}
+	server := httptest.NewServer(app.Routes())
+	defer server.Close()
+
+	userID := insertMemoryUser(t, store, true)
+	if _, err := store.CreateAPIToken(userID, "chat", util.HashToken("messages-token"), []string{handler.ScopeMessages}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	header := http.Header{"Authorization": {"Bearer messages-token"}}
+	conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/v1/ws", header)
+	if err != nil {
+		t.Fatalf("Failed to open the websocket: %v", err)
+	}
+	defer conn.Close()
+
+	invitation := map[string]any{"type": "group_invitation", "data": map[string]any{"group_id": util.UUIDGen(), "recipient_Id": util.UUIDGen()}}
+	if err := conn.WriteJSON(invitation); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var reply map[string]any
+		if err := conn.ReadJSON(&reply); err != nil {
+			t.Fatalf("Expected an error reply: %v", err)
+		}
+		if reply["type"] == "error" {
+			if message, _ := reply["message"].(string); !strings.Contains(message, "group_invitation") {
+				t.Errorf("Expected the scope to be reported, got %q", message)
+			}
+			break
+		}
+	}
+
+	// disabled by the admin CLI, which cannot reach the hub
+	if err := store.UpdateData("users", []string{"id"}, []any{userID}, []string{"disabled_at"}, []any{time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(map[string]any{"type": "load_private_messages"}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var reply map[string]any
+		err := conn.ReadJSON(&reply)
+		if gorilla.IsCloseError(err, gorilla.ClosePolicyViolation) {
+			return
+		} else if err != nil {
+			t.Fatalf("Expected the connection of a disabled user to be closed: %v", err)
+		}
+	}
+}
+
+func TestMemoryStoreDeleteAccount(t *testing.T) {
+	store := memory.New()
+	creator := insertMemoryUser(t, store, true)
+	member := insertMemoryUser(t, store, true)
+
+	for _, title := range []string{"kept", "deleted"} {
+		groupID := util.UUIDGen()
+		if err := store.InsertData("groups", []string{"id", "title", "creator_id"}, []any{groupID, title, creator}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.InsertData("group_members", []string{"id", "group_id", "user_id", "role"}, []any{util.UUIDGen(), groupID, creator, "admin"}); err != nil {
+			t.Fatal(err)
+		}
+		if title == "kept" {
+			if err := store.InsertData("group_members", []string{"id", "group_id", "user_id"}, []any{util.UUIDGen(), groupID, member}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if _, err := store.DeleteAccount(creator); err != nil {
+		t.Fatalf("DeleteAccount() error: %v", err)
+	}
+
+	groups, err := store.FetchAllGroups(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Title != "kept" {
+		t.Fatalf("Expected only the group with another member to remain, got %+v", groups)
+	}
+	if groups[0].Creator.ID != member || groups[0].UserRole != "admin" || groups[0].MembersCount != 1 {
+		t.Errorf("Expected the remaining member to take over the group, got %+v", groups[0])
+	}
+	if exists, _ := store.CheckRow("group_members", []string{"user_id"}, []any{creator}); exists {
+		t.Error("Expected the memberships of the deleted user to be removed")
+	}
+}
+
+func TestMemoryStoreWithTx(t *testing.T) {
+	store := memory.New()
+	userID := insertMemoryUser(t, store, true)
+
+	err := store.WithTx(context.Background(), func(tx repository.Store) error {
+		if err := tx.InsertData("groups", []string{"id", "title", "creator_id"}, []any{util.UUIDGen(), "taken", userID}); err != nil {
+			return err
+		}
+		return tx.UpdateData("users", []string{"id"}, []any{userID}, []string{"nickname"}, []any{"changed"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.WithTx(context.Background(), func(tx repository.Store) error {
+		if err := tx.UpdateData("users", []string{"id"}, []any{userID}, []string{"nickname"}, []any{"discarded"}); err != nil {
+			return err
+		}
+		return tx.InsertData("groups", []string{"id", "title", "creator_id"}, []any{util.UUIDGen(), "taken", userID})
+	})
+	if err == nil {
+		t.Fatal("Expected the duplicate group title to fail")
+	}
+	if exists, _ := store.CheckRow("users", []string{"id", "nickname"}, []any{userID, "changed"}); !exists {
+		t.Error("Expected the update made in the failed transaction to be rolled back")
+	}
+}
+
+func TestEnableTwoFactorRejectsUsedCode(t *testing.T) {
+	store := memory.New()
+	app := &handler.App{Config: config.Default(), Queries: store}
+	userID := insertMemoryUser(t, store, true)
+	token, csrf := util.UUIDGen(), util.UUIDGen()
+	expiresAt := time.Now().Add(time.Hour)
+	err := store.InsertData("sessions",
+		[]string{"id", "user_id", "session_token", "csrf_token", "expires_at", "absolute_expires_at"},
+		[]any{util.UUIDGen(), userID, token, csrf, expiresAt, expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetPendingTOTPSecret(userID, secret); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, err := util.TOTPCode(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, _ := util.ValidateTOTP(secret, code, now)
+	if _, err := store.UseTOTPStep(userID, step); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/me/2fa", strings.NewReader(`{"code":"`+code+`"}`))
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: token})
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrf})
+	req.Header.Set("X-CSRF-Token", csrf)
+	rec := httptest.NewRecorder()
+	app.Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a code already used, got %d: %s", http.StatusUnauthorized, rec.Code, rec.Body.String())
+	}
+	if _, enabled, _ := store.FetchTOTP(userID); enabled {
+		t.Error("Expected two-factor authentication to stay disabled")
+	}
+}
+
+// failingSessionStore fails to delete sessions, inside transactions as well.
+type failingSessionStore struct {
+	repository.Store
+}
+
+func (s failingSessionStore) DeleteAllUserSessions(userID string) ([]string, error) {
+	return nil, errors.New("sessions unavailable")
+}
+
+func (s failingSessionStore) WithTx(ctx context.Context, fn func(tx repository.Store) error) error {
+	return s.Store.WithTx(ctx, func(tx repository.Store) error {
+		return fn(failingSessionStore{tx})
+	})
+}
+
+func TestResetPasswordIsAtomic(t *testing.T) {
+	store := memory.New()
+	hub := websocket.NewHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+	app := &handler.App{Config: config.Default(), Queries: failingSessionStore{store}, Hub: hub}
+
+	userID := insertMemoryUser(t, store, true)
+	if err := store.CreatePasswordReset(userID, util.HashToken("reset-token"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	reset := func() *httptest.ResponseRecorder {
+		body := `{"token": "reset-token", "password": "N3w!Passw0rd!long", "confirmed_password": "N3w!Passw0rd!long"}`
+		rec := httptest.NewRecorder()
+		app.ResetPassword(rec, httptest.NewRequest(http.MethodPost, "/api/v1/password/reset", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := reset(); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected the reset to fail, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if hash, err := store.FetchPasswordHash(userID); err != nil || hash != "hash" {
+		t.Errorf("Expected the password to be kept, got %q, %v", hash, err)
+	}
+
+	// The token was not used up by the failed attempt.
+	app.Queries = store
+	if rec := reset(); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the reset to succeed with the same token, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if hash, err := store.FetchPasswordHash(userID); err != nil || util.ValidatePassword("N3w!Passw0rd!long", hash) != nil {
+		t.Errorf("Expected the new password to be stored, got %v", err)
+	}
+}
+
+func TestUpdateUserEmailTaken(t *testing.T) {
+	store := memory.New()
+	app := &handler.App{Config: config.Default(), Queries: store, Mailer: &mail.OutboxMailer{Dir: t.TempDir()}}
+	defer app.Wait(context.Background())
+
+	userID := insertMemoryUser(t, store, true)
+	otherID := insertMemoryUser(t, store, true)
+	token := insertMemorySession(t, store, userID)
+
+	update := func(email string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/users/me", strings.NewReader(`{"email": "`+email+`"}`))
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: token})
+		rec := httptest.NewRecorder()
+		app.UpdateUser(rec, req)
+		return rec.Code
+	}
+
+	if code := update(otherID + "@example.com"); code != http.StatusConflict {
+		t.Errorf("Expected another user's email to conflict, got %d", code)
+	}
+	if code := update(userID + "@example.com"); code != http.StatusOK {
+		t.Errorf("Expected the current email to be accepted, got %d", code)
+	}
+	if code := update("new-" + userID + "@example.com"); code != http.StatusOK {
+		t.Errorf("Expected a free email to be accepted, got %d", code)
+	}
+	if verified, _ := store.IsEmailVerified(userID); verified {
+		t.Error("Expected the new email to need verification")
+	}
+}
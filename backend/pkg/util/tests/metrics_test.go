@@ -0,0 +1,46 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"social/pkg/metrics"
+)
+
+func TestMetricsTextFormat(t *testing.T) {
+	registry := metrics.NewRegistry()
+	requests := registry.NewCounter("requests_total", "Requests served.", "route", "status")
+	duration := registry.NewHistogram("duration_seconds", "Time taken.", []float64{0.1, 1}, "route")
+	registry.NewGaugeFunc("clients", "Connected clients.", func() float64 { return 3 })
+
+	requests.Inc("/api/login", "200")
+	requests.Inc("/api/login", "200")
+	requests.Inc(`/a"b`, "500")
+	duration.Observe(0.05, "/api/login")
+	duration.Observe(0.5, "/api/login")
+	duration.Observe(2, "/api/login")
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP clients Connected clients.
+# TYPE clients gauge
+clients 3
+# HELP duration_seconds Time taken.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/api/login",le="0.1"} 1
+duration_seconds_bucket{route="/api/login",le="1"} 2
+duration_seconds_bucket{route="/api/login",le="+Inf"} 3
+duration_seconds_sum{route="/api/login"} 2.55
+duration_seconds_count{route="/api/login"} 3
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/a\"b",status="500"} 1
+requests_total{route="/api/login",status="200"} 2
+`
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,361 @@
+package test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"social/pkg/config"
+	"social/pkg/handler"
+	"social/pkg/oidc"
+	"social/pkg/repository/memory"
+	"social/pkg/util"
+)
+
+// mockIssuer is a minimal OpenID Connect provider. Authorization is simulated
+// by calling authorize with the URL the client would redirect the browser to.
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	// claims returns the ID token claims for a sign-in, given its nonce.
+	claims func(nonce string) map[string]any
+	// signer overrides the key the ID token is signed with.
+	signer *rsa.PrivateKey
+
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockIssuer{t: t, key: key}
+	mux := http.NewServeMux()
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		verifier := r.FormValue("code_verifier")
+		sum := sha256.Sum256([]byte(verifier))
+		if r.FormValue("code") != "test-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken()})
+	})
+
+	m.claims = func(nonce string) map[string]any {
+		return map[string]any{
+			"iss":            m.server.URL,
+			"sub":            "user-42",
+			"aud":            "client-id",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          nonce,
+			"email":          "jane@example.com",
+			"email_verified": true,
+			"given_name":     "Jane",
+			"family_name":    "Doe",
+		}
+	}
+	return m
+}
+
+func (m *mockIssuer) provider() *oidc.Provider {
+	return &oidc.Provider{
+		Name:        "mock",
+		Issuer:      m.server.URL,
+		ClientID:    "client-id",
+		RedirectURL: "http://localhost:8000/api/oidcCallback",
+	}
+}
+
+// authorize plays the part of the user approving the sign-in at the provider.
+func (m *mockIssuer) authorize(authURL string) (state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("response_type") != "code" {
+		m.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+	m.challenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+	return q.Get("state")
+}
+
+func (m *mockIssuer) idToken() string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test-key", "typ": "JWT"})
+	payload, _ := json.Marshal(m.claims(m.nonce))
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	signer := m.key
+	if m.signer != nil {
+		signer = m.signer
+	}
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, signer, crypto.SHA256, digest[:])
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// signIn runs the authorization code flow and returns the verified claims.
+func signIn(t *testing.T, m *mockIssuer, verifier string) (*oidc.Claims, error) {
+	ctx := context.Background()
+	provider := m.provider()
+
+	authURL, err := provider.AuthCodeURL(ctx, "test-state", "test-nonce", oidc.CodeChallenge("test-verifier"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error: %v", err)
+	}
+	if state := m.authorize(authURL); state != "test-state" {
+		t.Fatalf("Expected state to be passed through, got %q", state)
+	}
+
+	rawToken, err := provider.Exchange(ctx, "test-code", verifier)
+	if err != nil {
+		return nil, err
+	}
+	return provider.VerifyIDToken(ctx, rawToken, "test-nonce")
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	m := newMockIssuer(t)
+
+	claims, err := signIn(t, m, "test-verifier")
+	if err != nil {
+		t.Fatalf("Expected sign-in to succeed, got %v", err)
+	}
+	if claims.Subject != "user-42" || claims.Email != "jane@example.com" || !bool(claims.EmailVerified) {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	if claims.GivenName != "Jane" || claims.FamilyName != "Doe" {
+		t.Errorf("Expected name claims, got %q %q", claims.GivenName, claims.FamilyName)
+	}
+}
+
+func TestOIDCRejectsWrongCodeVerifier(t *testing.T) {
+	m := newMockIssuer(t)
+
+	if _, err := signIn(t, m, "other-verifier"); err == nil {
+		t.Error("Expected the token exchange to fail with a wrong PKCE verifier")
+	}
+}
+
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *mockIssuer, claims map[string]any)
+	}{
+		{"wrong nonce", func(m *mockIssuer, c map[string]any) { c["nonce"] = "replayed" }},
+		{"wrong audience", func(m *mockIssuer, c map[string]any) { c["aud"] = []string{"another-client"} }},
+		{"wrong issuer", func(m *mockIssuer, c map[string]any) { c["iss"] = "https://evil.example.com" }},
+		{"expired", func(m *mockIssuer, c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"foreign signature", func(m *mockIssuer, c map[string]any) { m.signer = otherKey }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockIssuer(t)
+			base := m.claims
+			m.claims = func(nonce string) map[string]any {
+				c := base(nonce)
+				tt.modify(m, c)
+				return c
+			}
+
+			_, err := signIn(t, m, "test-verifier")
+			if !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+// oidcBrowser drives the sign-in endpoints of an app against a mock issuer.
+type oidcBrowser struct {
+	t      *testing.T
+	routes http.Handler
+	issuer *mockIssuer
+}
+
+// start begins a sign-in and returns its state, after the user approved it at
+// the provider, and the state cookie the browser was given.
+func (b *oidcBrowser) start() (string, *http.Cookie) {
+	b.t.Helper()
+	rec := httptest.NewRecorder()
+	b.routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/oidc/mock/login", nil))
+	if rec.Code != http.StatusFound {
+		b.t.Fatalf("Expected a redirect to the provider, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	state := b.issuer.authorize(rec.Header().Get("Location"))
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "oidc_state" {
+			return state, c
+		}
+	}
+	b.t.Fatal("Expected the state cookie to be set")
+	return "", nil
+}
+
+// callback returns from the provider and returns the error code the browser is
+// sent back to the login page with, or "" along with the response on success.
+func (b *oidcBrowser) callback(query string, cookie *http.Cookie) (string, *httptest.ResponseRecorder) {
+	b.t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/oidc/callback?"+query, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	b.routes.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound {
+		b.t.Fatalf("Expected a redirect to the frontend, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	return location.Query().Get("error"), rec
+}
+
+// signIn runs a whole sign-in and returns the error code, "" on success.
+func (b *oidcBrowser) signIn() string {
+	b.t.Helper()
+	state, cookie := b.start()
+	code, _ := b.callback("code=test-code&state="+url.QueryEscape(state), cookie)
+	return code
+}
+
+func TestOIDCCallback(t *testing.T) {
+	store := memory.New()
+	m := newMockIssuer(t)
+	app := &handler.App{Config: config.Default(), Queries: store, OIDC: map[string]*oidc.Provider{"mock": m.provider()}}
+	b := &oidcBrowser{t: t, routes: app.Routes(), issuer: m}
+
+	state, cookie := b.start()
+	code, rec := b.callback("code=test-code&state="+url.QueryEscape(state), cookie)
+	if code != "" || sessionCookie(rec) == nil {
+		t.Fatalf("Expected the first sign-in to log a new user in, got error %q", code)
+	}
+	userID, err := store.FetchUserIDByEmail("jane@example.com")
+	if err != nil {
+		t.Fatalf("Expected a user to be created from the claims, got %v", err)
+	}
+
+	// The state can only be used once.
+	if code, _ := b.callback("code=test-code&state="+url.QueryEscape(state), cookie); code != "invalid_state" {
+		t.Errorf("Expected a replayed state to be refused, got %q", code)
+	}
+
+	t.Run("state of another browser", func(t *testing.T) {
+		state, _ := b.start()
+		_, otherCookie := b.start()
+		if code, _ := b.callback("code=test-code&state="+url.QueryEscape(state), otherCookie); code != "invalid_state" {
+			t.Errorf("Expected a state not matching the cookie to be refused, got %q", code)
+		}
+		if code, _ := b.callback("code=test-code&state="+url.QueryEscape(state), nil); code != "invalid_state" {
+			t.Errorf("Expected a callback without the cookie to be refused, got %q", code)
+		}
+	})
+
+	t.Run("declined at the provider", func(t *testing.T) {
+		state, cookie := b.start()
+		if code, _ := b.callback("error=access_denied&state="+url.QueryEscape(state), cookie); code != "access_denied" {
+			t.Errorf("Expected access_denied, got %q", code)
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		base := m.claims
+		defer func() { m.claims = base }()
+		m.claims = func(nonce string) map[string]any {
+			c := base(nonce)
+			c["nonce"] = "replayed"
+			return c
+		}
+		if code := b.signIn(); code != "provider_error" {
+			t.Errorf("Expected an ID token with another nonce to be refused, got %q", code)
+		}
+	})
+
+	t.Run("returning user", func(t *testing.T) {
+		if code := b.signIn(); code != "" {
+			t.Fatalf("Expected the second sign-in to succeed, got %q", code)
+		}
+		if id, err := store.FetchIdentityUser(m.server.URL, "user-42"); err != nil || id != userID {
+			t.Errorf("Expected the provider account to stay linked to %s, got %q, %v", userID, id, err)
+		}
+	})
+
+	t.Run("disabled user", func(t *testing.T) {
+		if err := store.UpdateData("users", []string{"id"}, []any{userID}, []string{"disabled_at"}, []any{time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		if code := b.signIn(); code != "account_disabled" {
+			t.Errorf("Expected a disabled user to be refused, got %q", code)
+		}
+	})
+}
+
+func TestOIDCCallbackEmailTaken(t *testing.T) {
+	store := memory.New()
+	m := newMockIssuer(t)
+	app := &handler.App{Config: config.Default(), Queries: store, OIDC: map[string]*oidc.Provider{"mock": m.provider()}}
+	b := &oidcBrowser{t: t, routes: app.Routes(), issuer: m}
+
+	// An unverified local account with the same email is not taken over.
+	err := store.InsertData("users",
+		[]string{"id", "email", "password", "first_name", "last_name", "date_of_birth"},
+		[]any{util.UUIDGen(), "jane@example.com", "hash", "Jane", "Doe", "2000-01-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := b.signIn(); code != "email_taken" {
+		t.Errorf("Expected email_taken, got %q", code)
+	}
+}
@@ -0,0 +1,603 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"social/pkg/config"
+	db "social/pkg/db"
+	"social/pkg/db/sqlite"
+	"social/pkg/repository"
+	"social/pkg/util"
+	"social/pkg/websocket"
+)
+
+// sqliteTemplate is migrated once and copied for every test, as applying the
+// migrations to a new file is slow on some file systems.
+var sqliteTemplate struct {
+	once sync.Once
+	dir  string
+	err  error
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if sqliteTemplate.dir != "" {
+		os.RemoveAll(sqliteTemplate.dir)
+	}
+	os.Exit(code)
+}
+
+func sqliteTestDatabase(t *testing.T) string {
+	sqliteTemplate.once.Do(func() {
+		sqliteTemplate.dir, sqliteTemplate.err = os.MkdirTemp("", "social-test-")
+		if sqliteTemplate.err != nil {
+			return
+		}
+		conn, err := db.DBInstance(config.Database{
+			Driver: config.DriverSQLite,
+			Path:   filepath.Join(sqliteTemplate.dir, "template.db"),
+		})
+		if err != nil {
+			sqliteTemplate.err = err
+			return
+		}
+		sqliteTemplate.err = conn.Close()
+	})
+	if sqliteTemplate.err != nil {
+		t.Fatalf("Failed to migrate the SQLite database: %v", sqliteTemplate.err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(sqliteTemplate.dir, "template.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// forEachDatabase runs fn against a freshly migrated SQLite database and, when
+// TEST_DATABASE_URL is set, a PostgreSQL schema created for the test.
+func forEachDatabase(t *testing.T, fn func(t *testing.T, q *repository.Query)) {
+	t.Run("sqlite", func(t *testing.T) {
+		fn(t, openTestDatabase(t, config.Database{
+			Driver: config.DriverSQLite,
+			Path:   sqliteTestDatabase(t),
+		}))
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_DATABASE_URL")
+		if dsn == "" {
+			t.Skip("TEST_DATABASE_URL is not set")
+		}
+		fn(t, openTestDatabase(t, config.Database{
+			Driver: config.DriverPostgres,
+			URL:    postgresTestSchema(t, dsn),
+		}))
+	})
+}
+
+func openTestDatabase(t *testing.T, cfg config.Database) *repository.Query {
+	conn, err := db.DBInstance(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open the %s database: %v", cfg.Driver, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	reader, err := db.OpenReader(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open the %s read pool: %v", cfg.Driver, err)
+	}
+	if reader != nil {
+		t.Cleanup(func() { reader.Close() })
+	}
+	return &repository.Query{Db: conn, Reader: reader, Dialect: repository.Dialect(cfg.Driver)}
+}
+
+// postgresTestSchema creates an empty schema, dropped when the test ends, and
+// returns dsn with its search path set to it.
+func postgresTestSchema(t *testing.T, dsn string) string {
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	schema := "test_" + strings.ReplaceAll(util.UUIDGen(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		cleanup, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return
+		}
+		defer cleanup.Close()
+		cleanup.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT '?', \"a?\" FROM t WHERE a = ? -- b = ?\nAND c IN (?, ?)"
+
+	sqlite := &repository.Query{Dialect: repository.SQLite}
+	if got := sqlite.Rebind(query); got != query {
+		t.Errorf("Expected SQLite queries to be left alone, got %q", got)
+	}
+
+	postgres := &repository.Query{Dialect: repository.Postgres}
+	want := "SELECT '?', \"a?\" FROM t WHERE a = $1 -- b = ?\nAND c IN ($2, $3)"
+	if got := postgres.Rebind(query); got != want {
+		t.Errorf("Rebind() = %q, want %q", got, want)
+	}
+}
+
+func insertTestUser(t *testing.T, q *repository.Query) string {
+	id := util.UUIDGen()
+	err := q.InsertData("users",
+		[]string{"id", "email", "password", "first_name", "last_name", "date_of_birth", "is_public"},
+		[]any{id, id + "@example.com", "hash", "Test", "User", "2000-01-01", true})
+	if err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	return id
+}
+
+func TestGenericHelpers(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		id := insertTestUser(t, q)
+
+		exists, err := q.CheckRow("users", []string{"id", "is_public"}, []any{id, true})
+		if err != nil || !exists {
+			t.Fatalf("Expected the inserted user to be found, got %v, %v", exists, err)
+		}
+
+		err = q.UpdateData("users", []string{"id"}, []any{id}, []string{"nickname", "is_public"}, []any{"tester", false})
+		if err != nil {
+			t.Fatalf("UpdateData() error: %v", err)
+		}
+		exists, err = q.CheckRow("users", []string{"id", "nickname", "is_public"}, []any{id, "tester", false})
+		if err != nil || !exists {
+			t.Errorf("Expected the update to be stored, got %v, %v", exists, err)
+		}
+
+		if err := q.DeleteData("users", []string{"id"}, []any{id}); err != nil {
+			t.Fatalf("DeleteData() error: %v", err)
+		}
+		exists, err = q.CheckRow("users", []string{"id"}, []any{id})
+		if err != nil || exists {
+			t.Errorf("Expected the user to be deleted, got %v, %v", exists, err)
+		}
+	})
+}
+
+func TestWithTx(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		userID := insertTestUser(t, q)
+		ctx := context.Background()
+
+		addGroup := func(tx repository.Store, title, memberID string) error {
+			groupID := util.UUIDGen()
+			if err := tx.InsertData("groups", []string{"id", "title", "creator_id"}, []any{groupID, title, userID}); err != nil {
+				return err
+			}
+			return tx.InsertData("group_members", []string{"id", "group_id", "user_id", "role"}, []any{memberID, groupID, userID, "admin"})
+		}
+		groupExists := func(title string) bool {
+			t.Helper()
+			exists, err := q.CheckRow("groups", []string{"title"}, []any{title})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return exists
+		}
+
+		memberID := util.UUIDGen()
+		err := q.WithTx(ctx, func(tx repository.Store) error { return addGroup(tx, "committed", memberID) })
+		if err != nil || !groupExists("committed") {
+			t.Fatalf("Expected the group to be committed, got %v", err)
+		}
+
+		// Reusing the membership id makes the second insert fail.
+		err = q.WithTx(ctx, func(tx repository.Store) error { return addGroup(tx, "rolled back", memberID) })
+		if err == nil {
+			t.Fatal("Expected the duplicate membership to fail")
+		}
+		if groupExists("rolled back") {
+			t.Error("Expected the group to be rolled back with its membership")
+		}
+
+		errAbort := errors.New("abort")
+		err = q.WithTx(ctx, func(tx repository.Store) error {
+			if err := tx.WithTx(ctx, func(tx repository.Store) error { return addGroup(tx, "nested", util.UUIDGen()) }); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("Expected the error of fn, got %v", err)
+		}
+		if groupExists("nested") {
+			t.Error("Expected a nested WithTx to be rolled back with the outer transaction")
+		}
+	})
+}
+
+func TestCounterTriggers(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		userID := insertTestUser(t, q)
+		postID := util.UUIDGen()
+		groupID := util.UUIDGen()
+		eventID := util.UUIDGen()
+
+		mustExec := func(query string, args ...any) {
+			t.Helper()
+			if _, err := q.Db.Exec(q.Rebind(query), args...); err != nil {
+				t.Fatalf("%s: %v", query, err)
+			}
+		}
+		expectCount := func(query, id string, want int) {
+			t.Helper()
+			var got int
+			if err := q.Db.QueryRow(q.Rebind(query), id).Scan(&got); err != nil {
+				t.Fatalf("%s: %v", query, err)
+			}
+			if got != want {
+				t.Errorf("%s = %d, want %d", query, got, want)
+			}
+		}
+
+		mustExec("INSERT INTO posts (id, user_id, content) VALUES (?, ?, ?)", postID, userID, "hello")
+		mustExec("INSERT INTO post_likes (id, post_id, user_id, is_like) VALUES (?, ?, ?, ?)", util.UUIDGen(), postID, userID, true)
+		expectCount("SELECT likes_count FROM posts WHERE id = ?", postID, 1)
+		mustExec("DELETE FROM post_likes WHERE post_id = ?", postID)
+		expectCount("SELECT likes_count FROM posts WHERE id = ?", postID, 0)
+
+		mustExec("INSERT INTO comments (id, post_id, user_id, content) VALUES (?, ?, ?, ?)", util.UUIDGen(), postID, userID, "hi")
+		expectCount("SELECT comments_count FROM posts WHERE id = ?", postID, 1)
+
+		mustExec("INSERT INTO groups (id, title, creator_id) VALUES (?, ?, ?)", groupID, "group "+groupID, userID)
+		mustExec("INSERT INTO group_members (id, group_id, user_id, role) VALUES (?, ?, ?, 'admin')", util.UUIDGen(), groupID, userID)
+		expectCount("SELECT members_count FROM groups WHERE id = ?", groupID, 1)
+
+		mustExec("INSERT INTO events (id, group_id, creator_id, title, event_time) VALUES (?, ?, ?, ?, ?)",
+			eventID, groupID, userID, "meetup", time.Now().Add(24*time.Hour))
+		mustExec("INSERT INTO event_attendance (id, event_id, user_id, status) VALUES (?, ?, ?, 'going')", util.UUIDGen(), eventID, userID)
+		expectCount("SELECT going_count FROM events WHERE id = ?", eventID, 1)
+		mustExec("UPDATE event_attendance SET status = 'not_going' WHERE event_id = ?", eventID)
+		expectCount("SELECT going_count FROM events WHERE id = ?", eventID, 0)
+		mustExec("UPDATE event_attendance SET status = 'going' WHERE event_id = ?", eventID)
+		expectCount("SELECT going_count FROM events WHERE id = ?", eventID, 1)
+	})
+}
+
+func TestRecordLoginFailure(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		key := fmt.Sprintf("user:%s", util.UUIDGen())
+		now := time.Now()
+
+		for want := 1; want <= 2; want++ {
+			got, err := q.RecordLoginFailure(key, now, now.Add(-time.Hour))
+			if err != nil || got != want {
+				t.Fatalf("RecordLoginFailure() = %d, %v, want %d", got, err, want)
+			}
+		}
+
+		// failures older than the window start over
+		got, err := q.RecordLoginFailure(key, now.Add(time.Minute), now.Add(time.Second))
+		if err != nil || got != 1 {
+			t.Errorf("Expected the count to restart after the window, got %d, %v", got, err)
+		}
+	})
+}
+
+func TestSQLiteConnections(t *testing.T) {
+	q := openTestDatabase(t, config.Database{
+		Driver: config.DriverSQLite,
+		Path:   sqliteTestDatabase(t),
+	})
+
+	for pragma, want := range map[string]string{
+		"journal_mode": "wal",
+		"foreign_keys": "1",
+		"busy_timeout": "5000",
+		"synchronous":  "1",
+	} {
+		var got string
+		if err := q.Db.QueryRow("PRAGMA " + pragma).Scan(&got); err != nil || got != want {
+			t.Errorf("PRAGMA %s = %q, %v, want %q", pragma, got, err, want)
+		}
+	}
+	if _, err := q.Reader.Exec("DELETE FROM users"); err == nil {
+		t.Error("Expected the read pool to refuse writes")
+	}
+
+	// The writes wait for the single writer instead of failing as locked, while
+	// the reads run alongside them, in and out of transactions.
+	userID := insertTestUser(t, q)
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- q.WithTx(context.Background(), func(tx repository.Store) error {
+				postID := util.UUIDGen()
+				if err := tx.InsertData("posts", []string{"id", "user_id", "content"}, []any{postID, userID, fmt.Sprint(i)}); err != nil {
+					return err
+				}
+				_, err := tx.CheckRow("posts", []string{"id"}, []any{postID})
+				return err
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := q.FetchPostWithMedia(userID)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	posts, err := q.FetchPostWithMedia(userID)
+	if err != nil || len(posts) != 20 {
+		t.Errorf("FetchPostWithMedia() returned %d posts, %v, want 20", len(posts), err)
+	}
+}
+
+func TestDeleteGroupWithPosts(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		userID := insertTestUser(t, q)
+		groupID, postID := util.UUIDGen(), util.UUIDGen()
+		if err := q.InsertData("groups", []string{"id", "title", "creator_id"}, []any{groupID, "Group " + groupID, userID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.InsertData("posts", []string{"id", "user_id", "group_id", "content"}, []any{postID, userID, groupID, "post"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.InsertData("comments", []string{"id", "post_id", "user_id", "content"}, []any{util.UUIDGen(), postID, userID, "comment"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := q.DeleteGroup("Group "+groupID, userID); err != nil {
+			t.Fatalf("DeleteGroup() error: %v", err)
+		}
+		// Foreign keys are enforced, so the comments go with the post.
+		exists, err := q.CheckRow("comments", []string{"post_id"}, []any{postID})
+		if err != nil || exists {
+			t.Errorf("Expected the group's posts and their comments to be deleted, got %v, %v", exists, err)
+		}
+	})
+}
+
+func TestCreateDataExport(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		userID := insertTestUser(t, q)
+
+		const requests = 4
+		errs := make(chan error, requests)
+		for range requests {
+			go func() {
+				_, err := q.CreateDataExport(userID)
+				errs <- err
+			}()
+		}
+		created := 0
+		for range requests {
+			err := <-errs
+			if err == nil {
+				created++
+			} else if !errors.Is(err, repository.ErrExportPending) {
+				t.Fatalf("CreateDataExport() error: %v", err)
+			}
+		}
+		if created != 1 {
+			t.Fatalf("Expected one pending export, got %d", created)
+		}
+
+		// exports left pending by a previous run do not block new ones
+		if err := q.FailPendingExports(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := q.CreateDataExport(userID); err != nil {
+			t.Errorf("Expected a new export after failing the pending one, got %v", err)
+		}
+	})
+}
+
+func TestFetchPost(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		author, member, stranger := insertTestUser(t, q), insertTestUser(t, q), insertTestUser(t, q)
+		groupID, groupPost, privatePost := util.UUIDGen(), util.UUIDGen(), util.UUIDGen()
+		if err := q.InsertData("groups", []string{"id", "title", "creator_id"}, []any{groupID, "Group " + groupID, author}); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.InsertData("group_members", []string{"id", "group_id", "user_id", "role"}, []any{util.UUIDGen(), groupID, member, "member"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.InsertData("posts", []string{"id", "user_id", "group_id", "content", "privacy"}, []any{groupPost, author, groupID, "group post", "public"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.InsertData("posts", []string{"id", "user_id", "content", "privacy"}, []any{privatePost, author, "private post", "private"}); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name           string
+			postID, viewer string
+			wantErr        error
+		}{
+			{"group post for a member", groupPost, member, nil},
+			{"group post for a non-member", groupPost, stranger, repository.ErrPostNotFound},
+			{"private post for its author", privatePost, author, nil},
+			{"private post for another user", privatePost, member, repository.ErrPostNotFound},
+			{"unknown post", util.UUIDGen(), author, repository.ErrPostNotFound},
+		}
+		for _, tt := range tests {
+			post, err := q.FetchPost(tt.postID, tt.viewer)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: FetchPost() error = %v, want %v", tt.name, err, tt.wantErr)
+			} else if err == nil && post.ID != tt.postID {
+				t.Errorf("%s: FetchPost() returned post %q", tt.name, post.ID)
+			}
+		}
+		if post, _ := q.FetchPost(groupPost, member); post.GroupID != groupID {
+			t.Errorf("Expected the group id of the post, got %q", post.GroupID)
+		}
+	})
+}
+
+func TestConnectionActive(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		userID := insertTestUser(t, q)
+		sessionID := util.UUIDGen()
+		expiresAt := time.Now().Add(time.Hour)
+		err := q.InsertData("sessions",
+			[]string{"id", "user_id", "session_token", "csrf_token", "expires_at"},
+			[]any{sessionID, userID, util.UUIDGen(), util.UUIDGen(), expiresAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokenID, err := q.CreateAPIToken(userID, "ci", util.HashToken(util.UUIDGen()), []string{"messages"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, id := range []string{sessionID, tokenID} {
+			if active, err := q.ConnectionActive(id); err != nil || !active {
+				t.Errorf("Expected %s to be active, got %v, %v", id, active, err)
+			}
+		}
+		if active, err := q.ConnectionActive(util.UUIDGen()); err != nil || active {
+			t.Errorf("Expected an unknown session to be inactive, got %v, %v", active, err)
+		}
+
+		if _, _, err := q.DisableUser(userID); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{sessionID, tokenID} {
+			if active, err := q.ConnectionActive(id); err != nil || active {
+				t.Errorf("Expected %s to be inactive once the user is disabled, got %v, %v", id, active, err)
+			}
+		}
+	})
+}
+
+func TestAcceptGroupInvitation(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		admin, invited := insertTestUser(t, q), insertTestUser(t, q)
+		groupID := util.UUIDGen()
+		if err := q.InsertData("groups", []string{"id", "title", "creator_id"}, []any{groupID, "Group " + groupID, admin}); err != nil {
+			t.Fatal(err)
+		}
+		err := q.InsertData("group_invitations", []string{"id", "group_id", "sender_id", "receiver_id", "status"},
+			[]any{util.UUIDGen(), groupID, admin, invited, "pending"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client := &websocket.Client{UserID: invited, Send: make(chan []byte, 1)}
+		client.RespondSendInvitation(map[string]any{"data": map[string]any{"group_id": groupID, "status": "accepted"}}, q)
+		if reply := string(<-client.Send); !strings.Contains(reply, "success") {
+			t.Fatalf("Expected the invitation to be accepted, got %s", reply)
+		}
+
+		// The notification references the user as its actor, which foreign keys require.
+		exists, err := q.CheckRow("notifications", []string{"recipient_id", "type"}, []any{invited, "group_join_success"})
+		if err != nil || !exists {
+			t.Errorf("Expected a group join notification, got %v, %v", exists, err)
+		}
+	})
+}
+
+func TestForeignKeyViolations(t *testing.T) {
+	cfg := config.Database{Driver: config.DriverSQLite, Path: sqliteTestDatabase(t)}
+	q := openTestDatabase(t, cfg)
+	if _, err := q.Db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatal(err)
+	}
+	err := q.InsertData("notifications", []string{"id", "recipient_id", "actor_id", "type"},
+		[]any{util.UUIDGen(), util.UUIDGen(), util.UUIDGen(), "orphan"})
+	if _, fkErr := q.Db.Exec("PRAGMA foreign_keys = ON"); fkErr != nil {
+		t.Fatal(fkErr)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations, err := sqlite.ForeignKeyViolations(q.Db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 2 || violations[0].Table != "notifications" || violations[0].Parent != "users" {
+		t.Errorf("Expected the orphaned notification to be reported twice, got %+v", violations)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, q *repository.Query) {
+		userID := insertTestUser(t, q)
+		email := userID + "@example.com"
+
+		if err := q.CreateEmailVerification(userID, email, "expired", time.Now().Add(-time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := q.VerifyEmail("expired"); !errors.Is(err, repository.ErrInvalidVerificationToken) {
+			t.Errorf("Expected an expired token to be refused, got %v", err)
+		}
+
+		// A new verification replaces the pending one.
+		if err := q.CreateEmailVerification(userID, email, "valid", time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := q.VerifyEmail("valid"); err != nil || got != userID {
+			t.Fatalf("Expected the token to verify %s, got %q, %v", userID, got, err)
+		}
+		if verified, err := q.IsEmailVerified(userID); err != nil || !verified {
+			t.Errorf("Expected the email to be verified, got %v, %v", verified, err)
+		}
+		if _, err := q.VerifyEmail("valid"); !errors.Is(err, repository.ErrInvalidVerificationToken) {
+			t.Errorf("Expected a used token to be refused, got %v", err)
+		}
+
+		// A link sent to an address the user has since replaced verifies nothing.
+		other := insertTestUser(t, q)
+		if err := q.CreateEmailVerification(other, other+"@example.com", "stale", time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.UpdateData("users", []string{"id"}, []any{other}, []string{"email"}, []any{"new-" + other + "@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := q.VerifyEmail("stale"); !errors.Is(err, repository.ErrInvalidVerificationToken) {
+			t.Errorf("Expected a token for a replaced address to be refused, got %v", err)
+		}
+		if verified, err := q.IsEmailVerified(other); err != nil || verified {
+			t.Errorf("Expected the new address to stay unverified, got %v, %v", verified, err)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"social/pkg/config"
+	"social/pkg/handler"
+	"social/pkg/repository"
+	"social/pkg/repository/memory"
+	"social/pkg/util"
+	"social/pkg/websocket"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+func TestRoutes(t *testing.T) {
+	store := memory.New()
+	app := &handler.App{Config: config.Default(), Queries: store}
+	routes := app.Routes()
+
+	type session struct{ token, csrf string }
+	addSession := func(userID string) *session {
+		token, csrf := util.UUIDGen(), util.UUIDGen()
+		expiresAt := time.Now().Add(time.Hour)
+		err := store.InsertData("sessions",
+			[]string{"id", "user_id", "session_token", "csrf_token", "expires_at", "absolute_expires_at"},
+			[]any{util.UUIDGen(), userID, token, csrf, expiresAt, expiresAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &session{token, csrf}
+	}
+	verified := addSession(insertMemoryUser(t, store, true))
+	unverifiedID := insertMemoryUser(t, store, false)
+	unverified := addSession(unverifiedID)
+
+	tokenUser := insertMemoryUser(t, store, true)
+	if _, err := store.CreateAPIToken(tokenUser, "ci", util.HashToken("read-token"), []string{handler.ScopePostsRead}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	memberID := insertMemoryUser(t, store, true)
+	member := addSession(memberID)
+	groupID, groupPostID := util.UUIDGen(), util.UUIDGen()
+	for _, insert := range []struct {
+		table   string
+		columns []string
+		values  []any
+	}{
+		{"groups", []string{"id", "title", "creator_id"}, []any{groupID, "Group", memberID}},
+		{"group_members", []string{"id", "group_id", "user_id", "role"}, []any{util.UUIDGen(), groupID, memberID, "admin"}},
+		{"posts", []string{"id", "user_id", "group_id", "content", "privacy"}, []any{groupPostID, memberID, groupID, "group post", "public"}},
+	} {
+		if err := store.InsertData(insert.table, insert.columns, insert.values); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		method, path   string
+		body           string
+		session        *session
+		bearer         string
+		wantStatus     int
+		wantDeprecated bool
+	}{
+		{"resource route", http.MethodGet, "/api/v1/posts", "", verified, "", http.StatusOK, false},
+		{"legacy alias", http.MethodGet, "/api/getPosts", "", verified, "", http.StatusOK, true},
+		{"unknown route", http.MethodGet, "/api/v1/nothing", "", verified, "", http.StatusNotFound, false},
+		{"method from the mux", http.MethodPut, "/api/v1/posts", "", verified, "", http.StatusMethodNotAllowed, false},
+		{"legacy alias keeps its method", http.MethodPost, "/api/getPosts", "", verified, "", http.StatusMethodNotAllowed, false},
+		{"path parameter", http.MethodGet, "/api/v1/posts/unknown", "", verified, "", http.StatusNotFound, false},
+		{"group post for a member", http.MethodGet, "/api/v1/posts/" + groupPostID, "", member, "", http.StatusOK, false},
+		{"group post for a non-member", http.MethodGet, "/api/v1/posts/" + groupPostID, "", verified, "", http.StatusNotFound, false},
+		{"unverified email may read", http.MethodGet, "/api/v1/users/" + unverifiedID, "", unverified, "", http.StatusOK, false},
+		{"unverified email may read with a legacy POST", http.MethodPost, "/api/getProfile", `{"user_id":"` + unverifiedID + `"}`, unverified, "", http.StatusOK, true},
+		{"unverified email may not write", http.MethodPost, "/api/v1/posts", "", unverified, "", http.StatusForbidden, false},
+		{"token scope of the successor", http.MethodGet, "/api/getPosts", "", nil, "read-token", http.StatusOK, true},
+		{"token scope missing", http.MethodPost, "/api/v1/posts", "", nil, "read-token", http.StatusForbidden, false},
+		{"route not available to tokens", http.MethodGet, "/api/v1/me/sessions", "", nil, "read-token", http.StatusForbidden, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.session != nil {
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: tt.session.token})
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.session.csrf})
+				req.Header.Set("X-CSRF-Token", tt.session.csrf)
+			}
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+
+			rec := httptest.NewRecorder()
+			routes.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Expected a JSON response, got %q", got)
+			}
+			if deprecated := rec.Header().Get("Deprecation") != ""; deprecated != tt.wantDeprecated {
+				t.Errorf("Expected deprecated %t, got headers %v", tt.wantDeprecated, rec.Header())
+			}
+			if rec.Code == http.StatusMethodNotAllowed && rec.Header().Get("Allow") == "" {
+				t.Error("Expected the Allow header on 405 responses")
+			}
+		})
+	}
+}
+
+func TestCSRFTokenInQuery(t *testing.T) {
+	store := memory.New()
+	hub := websocket.NewHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+	app := &handler.App{Config: config.Default(), Queries: store, Hub: hub}
+	server := httptest.NewServer(app.Routes())
+	defer server.Close()
+
+	token, csrf := util.UUIDGen(), util.UUIDGen()
+	expiresAt := time.Now().Add(time.Hour)
+	err := store.InsertData("sessions",
+		[]string{"id", "user_id", "session_token", "csrf_token", "expires_at", "absolute_expires_at"},
+		[]any{util.UUIDGen(), insertMemoryUser(t, store, true), token, csrf, expiresAt, expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := "session_id=" + token + "; csrf_token=" + csrf
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/posts?csrf_token="+csrf, nil)
+	req.Header.Set("Cookie", cookies)
+	rec := httptest.NewRecorder()
+	app.Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the query parameter to be refused outside websocket upgrades, got %d", rec.Code)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws?csrf_token=" + csrf
+	for origin, wantStatus := range map[string]int{
+		"http://localhost:3000": http.StatusSwitchingProtocols,
+		"https://evil.example":  http.StatusForbidden,
+	} {
+		conn, resp, err := gorilla.DefaultDialer.Dial(wsURL, http.Header{"Cookie": {cookies}, "Origin": {origin}})
+		if conn != nil {
+			conn.Close()
+		}
+		if resp == nil {
+			t.Fatalf("Failed to open the websocket from %s: %v", origin, err)
+		}
+		if resp.StatusCode != wantStatus {
+			t.Errorf("Expected status %d from %s, got %d", wantStatus, origin, resp.StatusCode)
+		}
+	}
+}
+
+func TestReadyzWithoutDatabase(t *testing.T) {
+	util.SetDataDir(t.TempDir())
+	t.Cleanup(func() { util.SetDataDir("pkg/db") })
+	hub := websocket.NewHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+	app := &handler.App{Config: config.Default(), Queries: &repository.Query{}, Hub: hub}
+
+	rec := httptest.NewRecorder()
+	app.Routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	for _, check := range []string{"database", "migrations"} {
+		if !strings.Contains(rec.Body.String(), `"`+check+`":{"status":"fail"}`) {
+			t.Errorf("Expected the %s check to fail, got %s", check, rec.Body.String())
+		}
+	}
+	// The checks are public, the reason they failed is only logged.
+	if strings.Contains(rec.Body.String(), "no database configured") {
+		t.Errorf("Expected no error details in the response, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"social/pkg/config"
+	"social/pkg/handler"
+	"social/pkg/repository/memory"
+	"social/pkg/util"
+)
+
+// within reports whether got is at most a minute away from want.
+func within(got, want time.Time) bool {
+	d := got.Sub(want)
+	return d > -time.Minute && d < time.Minute
+}
+
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "session_id" {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSessionRenewal(t *testing.T) {
+	store := memory.New()
+	app := &handler.App{Config: config.Default(), Queries: store}
+	protected := app.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	userID := insertMemoryUser(t, store, true)
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		expiresIn      time.Duration
+		absoluteIn     time.Duration
+		rememberMe     bool
+		expectedCode   int
+		expectedExpiry time.Duration // zero when the session must not be renewed
+	}{
+		// Sessions are renewed once less than half of the 24h idle timeout is left.
+		{"renewed near its expiry", time.Hour, 30 * 24 * time.Hour, false, http.StatusNoContent, 24 * time.Hour},
+		{"not renewed early", 20 * time.Hour, 30 * 24 * time.Hour, false, http.StatusNoContent, 0},
+		{"renewed up to its absolute expiry", time.Hour, 2 * time.Hour, false, http.StatusNoContent, 2 * time.Hour},
+		{"remember me renewed for 14 days", time.Hour, 30 * 24 * time.Hour, true, http.StatusNoContent, 14 * 24 * time.Hour},
+		{"past its absolute expiry", -time.Minute, -time.Minute, false, http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, csrf := util.UUIDGen(), util.UUIDGen()
+			err := store.InsertData("sessions",
+				[]string{"id", "user_id", "session_token", "csrf_token", "expires_at", "absolute_expires_at", "remember_me"},
+				[]any{util.UUIDGen(), userID, token, csrf, now.Add(tt.expiresIn), now.Add(tt.absoluteIn), tt.rememberMe})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/posts", nil)
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: token})
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: csrf})
+			rec := httptest.NewRecorder()
+			protected.ServeHTTP(rec, req)
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rec.Code, rec.Body.String())
+			}
+
+			if tt.expectedCode != http.StatusNoContent {
+				return
+			}
+
+			session, err := store.FetchSessionAuth(token, csrf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cookie := sessionCookie(rec)
+			if tt.expectedExpiry == 0 {
+				if cookie != nil || !session.ExpiresAt.Equal(now.Add(tt.expiresIn)) {
+					t.Errorf("Expected the session to be left alone, got expiry %v and cookie %v", session.ExpiresAt, cookie)
+				}
+				return
+			}
+
+			if !within(session.ExpiresAt, now.Add(tt.expectedExpiry)) {
+				t.Errorf("Expected the session to expire in %v, got %v", tt.expectedExpiry, session.ExpiresAt.Sub(now))
+			}
+			if cookie == nil {
+				t.Fatal("Expected the session cookie to be refreshed")
+			}
+			// Without "remember me", the cookie lasts until the browser is closed.
+			if tt.rememberMe != !cookie.Expires.IsZero() {
+				t.Errorf("Expected a persistent cookie only with remember me, got expiry %v", cookie.Expires)
+			}
+		})
+	}
+}
+
+func TestLoginSessionExpiry(t *testing.T) {
+	for _, rememberMe := range []bool{false, true} {
+		app, store := throttledLoginApp(t)
+		email := insertLoginUser(t, store)
+
+		body := `{"remember_me": false}`
+		idle := 24 * time.Hour
+		if rememberMe {
+			body = `{"remember_me": true}`
+			idle = 14 * 24 * time.Hour
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(body))
+		req.SetBasicAuth(email, throttleTestPassword)
+		rec := httptest.NewRecorder()
+		app.Login(rec, req)
+		expectStatus(t, rec, http.StatusOK)
+
+		var csrf string
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == "csrf_token" {
+				csrf = c.Value
+			}
+		}
+		session, err := store.FetchSessionAuth(sessionCookie(rec).Value, csrf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Now()
+		if !within(session.ExpiresAt, now.Add(idle)) {
+			t.Errorf("remember me %v: expected the session to expire in %v, got %v", rememberMe, idle, session.ExpiresAt.Sub(now))
+		}
+		if session.AbsoluteExpiresAt == nil || !within(*session.AbsoluteExpiresAt, now.Add(30*24*time.Hour)) {
+			t.Errorf("remember me %v: expected an absolute expiry in 30 days, got %v", rememberMe, session.AbsoluteExpiresAt)
+		}
+		if session.RememberMe != rememberMe {
+			t.Errorf("Expected remember me %v to be stored", rememberMe)
+		}
+	}
+}
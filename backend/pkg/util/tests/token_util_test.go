@@ -0,0 +1,35 @@
+package test
+
+import (
+	"social/pkg/util"
+	"testing"
+)
+
+func TestGenerateToken(t *testing.T) {
+	first, err := util.GenerateToken()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second, err := util.GenerateToken()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if first == "" || first == second {
+		t.Errorf("Expected two distinct non-empty tokens, got %q and %q", first, second)
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	hash := util.HashToken("reset-token")
+
+	if hash != util.HashToken("reset-token") {
+		t.Error("Expected hashing the same token twice to give the same digest")
+	}
+	if hash == util.HashToken("other-token") {
+		t.Error("Expected different tokens to give different digests")
+	}
+	if len(hash) != 64 {
+		t.Errorf("Expected a 64 character hex digest, got %d characters", len(hash))
+	}
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"encoding/base32"
+	"social/pkg/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 appendix B, base32 encoded.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 test vectors, truncated to the 6 digits we use.
+	cases := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range cases {
+		code, err := util.TOTPCode(rfcSecret, time.Unix(tc.unix, 0))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if code != tc.expected {
+			t.Errorf("At %d expected code %s but got %s", tc.unix, tc.expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	now := time.Now()
+	code, _ := util.TOTPCode(secret, now)
+
+	if _, ok := util.ValidateTOTP(secret, code, now); !ok {
+		t.Error("Expected current code to be valid")
+	}
+	if _, ok := util.ValidateTOTP(secret, code, now.Add(30*time.Second)); !ok {
+		t.Error("Expected code from the previous period to be accepted")
+	}
+	if _, ok := util.ValidateTOTP(secret, code, now.Add(5*time.Minute)); ok {
+		t.Error("Expected old code to be rejected")
+	}
+	if _, ok := util.ValidateTOTP(secret, "12345", now); ok {
+		t.Error("Expected code with wrong length to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := util.TOTPURI("Social Network", "user@example.com", "ABCDEF")
+	if !strings.HasPrefix(uri, "otpauth://totp/Social%20Network:user@example.com?") {
+		t.Errorf("Unexpected otpauth URI: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=ABCDEF") {
+		t.Errorf("Expected URI to contain the secret, got %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := util.GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("Expected 10 codes, got %d", len(codes))
+	}
+
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("Unexpected recovery code format: %s", code)
+		}
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if util.NormalizeRecoveryCode(typed) != code {
+			t.Errorf("Expected %q to normalize to %q", typed, code)
+		}
+	}
+}
+
+func TestRecoveryCodesAreUniform(t *testing.T) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	codes, err := util.GenerateRecoveryCodes(5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[rune]int)
+	for _, code := range codes {
+		for _, c := range strings.ReplaceAll(code, "-", "") {
+			counts[c]++
+		}
+	}
+
+	// A random byte modulo 31 picks the first 8 letters 9 times out of 256 and
+	// the others 8 times, about 200 more draws each here. Uniform draws keep the
+	// two averages within a few dozen of each other.
+	var first, rest float64
+	for i, c := range alphabet {
+		if i < 8 {
+			first += float64(counts[c]) / 8
+		} else {
+			rest += float64(counts[c]) / float64(len(alphabet)-8)
+		}
+	}
+	if first-rest > 80 {
+		t.Errorf("Expected letters to be drawn uniformly, the first ones average %.0f draws and the others %.0f", first, rest)
+	}
+}
@@ -33,3 +33,25 @@ func TestValidatePassword(t *testing.T) {
 		}
 	})
 }
+
+func TestCheckPasswordStrength(t *testing.T) {
+	cases := []struct {
+		password string
+		valid    bool
+	}{
+		{"", false},
+		{"short", false},
+		{"1234567", false},
+		{"12345678", true},
+		{"a much longer passphrase", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.password, func(t *testing.T) {
+			err := util.CheckPasswordStrength(tc.password)
+			if (err == nil) != tc.valid {
+				t.Errorf("For password '%s', expected valid=%v but got error %v", tc.password, tc.valid, err)
+			}
+		})
+	}
+}
@@ -1,7 +1,24 @@
 package util
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
 
 func ValidatePassword(password string, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+// CheckPasswordStrength applies the password rules enforced at registration.
+func CheckPasswordStrength(password string) error {
+	if password == "" {
+		return errors.New("Password is required.")
+	}
+	if len(password) < minPasswordLength {
+		return errors.New("Password must be at least 8 characters.")
+	}
+	return nil
+}
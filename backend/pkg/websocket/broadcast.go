@@ -2,14 +2,16 @@ package websocket
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
+
+	"social/pkg/metrics"
 )
 
 // Broadcast a payload to all clients except skip (or nil to send to all)
 func (h *Hub) BroadcastToOthers(skip *Client, payload map[string]any) {
 	data, err := json.Marshal(payload)
 	if err != nil {
-		log.Println("broadcastToOthers: marshal error:", err)
+		slog.Error("broadcastToOthers: marshal error", "err", err)
 		return
 	}
 	h.Mu.RLock()
@@ -21,6 +23,7 @@ func (h *Hub) BroadcastToOthers(skip *Client, payload map[string]any) {
 		select {
 		case client.Send <- data:
 		default:
+			metrics.WebsocketDroppedSends.Inc("broadcast")
 		}
 	}
 }
@@ -28,7 +31,7 @@ func (h *Hub) BroadcastToOthers(skip *Client, payload map[string]any) {
 func (h *Hub) BroadcastToSpecific(users []string, payload map[string]any) {
 	data, err := json.Marshal(payload)
 	if err != nil {
-		log.Println("specific broadcast error:", err)
+		slog.Error("BroadcastToSpecific: marshal error", "err", err)
 		return
 	}
 
@@ -46,6 +49,7 @@ func (h *Hub) SendIfActive(userID string, data []byte) {
 			select {
 			case client.Send <- data:
 			default:
+				metrics.WebsocketDroppedSends.Inc("user")
 			}
 			return
 		}
@@ -67,6 +71,7 @@ func (h *Hub) BroadcastToGroup(skip *Client, groupID string, data []byte) {
 		select {
 		case client.Send <- data:
 		default:
+			metrics.WebsocketDroppedSends.Inc("group")
 		}
 	}
 }
@@ -2,19 +2,31 @@ package websocket
 
 import (
 	"encoding/json"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 
 	"github.com/gorilla/websocket"
 )
 
 type Client struct {
-	UserID      string
+	UserID    string
+	SessionID string
+	// RequestID is the id of the request that opened the connection.
+	RequestID string
+	ReadOnly  bool
+	// Allowed lists the message types a personal access token may send, nil
+	// allowing all of them for a session.
+	Allowed     map[string]bool
 	Groups      []string
 	Conn        *websocket.Conn
 	Send        chan []byte
 	ProcessChan chan map[string]any
 	Hubb        *Hub
 	Once        sync.Once
+
+	// log carries the correlation id of the message being processed.
+	log atomic.Pointer[slog.Logger]
 }
 
 type Message struct {
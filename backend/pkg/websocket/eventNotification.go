@@ -9,10 +9,10 @@ import (
 	"social/pkg/util"
 )
 
-func (c *Client) SendEventNotification(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) SendEventNotification(msg map[string]any, q repository.Store, h *Hub) {
 	data, err := json.Marshal(msg["data"])
 	if err != nil {
-		fmt.Println("Error marshalling data:", err)
+		c.logger().Error("failed to marshal event data", "err", err)
 		c.SendError("Invalid data encoding")
 		return
 	}
@@ -20,20 +20,20 @@ func (c *Client) SendEventNotification(msg map[string]any, q *repository.Query,
 	var event model.AddEventData
 
 	if err = json.Unmarshal(data, &event); err != nil {
-		fmt.Printf("Error unmarshalling event data: %v\n", err)
+		c.logger().Warn("failed to unmarshal event data", "err", err)
 		c.SendError("Invalid messsage format")
 		return
 	}
 
 	groupId, err := q.FetchGroupId(event.GroupTitle)
 	if err != nil {
-		fmt.Println("Error fetching group ID:", err)
+		c.logger().Error("failed to fetch group id", "err", err)
 		c.SendError("group not found")
 		return
 	}
 
 	if event.Title == "" || event.EventTime.IsZero() {
-		fmt.Println("Missing event name or time data")
+		c.logger().Warn("missing event name or time")
 		c.SendError("Missing event name or time data")
 		return
 	}
@@ -56,7 +56,7 @@ func (c *Client) SendEventNotification(msg map[string]any, q *repository.Query,
 		return
 	}
 	if existingEvent {
-		fmt.Println("Event with the same details already exists")
+		c.logger().Warn("event with the same details already exists")
 		c.SendError("Event with the same details already exists")
 		return
 	}
@@ -81,14 +81,14 @@ func (c *Client) SendEventNotification(msg map[string]any, q *repository.Query,
 		event.Description,
 	})
 	if err != nil {
-		fmt.Println("Error inserting event:", err)
+		c.logger().Error("failed to insert event", "err", err)
 		c.SendError("failed to add event")
 		return
 	}
 
 	memberIds, err := q.FetchAllGroupMembersId(groupId)
 	if err != nil {
-		fmt.Println("Error fetching group members:", err)
+		c.logger().Error("failed to fetch group members", "err", err)
 		c.SendError("failed to fetch group members")
 		return
 	}
@@ -140,7 +140,7 @@ func (c *Client) SendEventNotification(msg map[string]any, q *repository.Query,
 			"group-event",
 		})
 		if err != nil {
-			fmt.Println("Error inserting notification:", err)
+			c.logger().Error("failed to insert notification", "err", err)
 			c.SendError("failed to add notification")
 			return
 		}
@@ -149,7 +149,7 @@ func (c *Client) SendEventNotification(msg map[string]any, q *repository.Query,
 	var userData model.UserData
 	err = q.FetchUserInfo(c.UserID, &userData)
 	if err != nil {
-		fmt.Println("Error fetching user data:", err)
+		c.logger().Error("failed to fetch user data", "err", err)
 		c.SendError("failed to fetch user data")
 		return
 	}
@@ -170,7 +170,7 @@ func (c *Client) SendEventNotification(msg map[string]any, q *repository.Query,
 
 	sendData, err := json.Marshal(payload)
 	if err != nil {
-		fmt.Println("Error marshalling event data:", err)
+		c.logger().Error("failed to marshal event notification", "err", err)
 		c.SendError("failed to marshal event data")
 		return
 	}
@@ -7,7 +7,7 @@ import (
 	"social/pkg/repository"
 )
 
-func (c *Client) ExitGroup(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) ExitGroup(msg map[string]any, q repository.Store, h *Hub) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -13,7 +13,7 @@ import (
 // FollowService encapsulates follow request operations.
 // It keeps the repository and hub for notifications.
 type FollowService struct {
-	Query *repository.Query
+	Query repository.Store
 	Hub   *Hub
 }
 
@@ -69,7 +69,7 @@ func (svc *FollowService) sendNotification(notifID, recipientID, actorID, ntype,
 }
 
 // FollowRequest handles sending, re-sending, or auto-accepting follow requests.
-func (c *Client) FollowRequest(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) FollowRequest(msg map[string]any, q repository.Store, h *Hub) {
 	svc := FollowService{Query: q, Hub: h}
 
 	req, ok := c.decodeFollowRequest(msg)
@@ -176,7 +176,7 @@ func (svc *FollowService) handleNewFollow(c *Client, req model.FollowRequest, us
 }
 
 // RespondFollowRequest handles accept or decline of a pending request.
-func (c *Client) RespondFollowRequest(msg map[string]any, q *repository.Query) {
+func (c *Client) RespondFollowRequest(msg map[string]any, q repository.Store) {
 	req, ok := c.decodeFollowRequest(msg)
 	if !ok {
 		return
@@ -225,7 +225,7 @@ func (c *Client) RespondFollowRequest(msg map[string]any, q *repository.Query) {
 }
 
 // CancelFollowRequest deletes a pending follow request.
-func (c *Client) CancelFollowRequest(msg map[string]any, q *repository.Query) {
+func (c *Client) CancelFollowRequest(msg map[string]any, q repository.Store) {
 	req, ok := c.decodeFollowRequest(msg)
 	if !ok {
 		return
@@ -7,7 +7,7 @@ import (
 	"social/pkg/repository"
 )
 
-func (c *Client) CancelGroupInvitation(msg map[string]any, q *repository.Query) {
+func (c *Client) CancelGroupInvitation(msg map[string]any, q repository.Store) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -69,7 +69,7 @@ func (c *Client) CancelGroupInvitation(msg map[string]any, q *repository.Query)
 	}
 }
 
-func (c *Client) CancelGroupJoinRequest(msg map[string]any, q *repository.Query) {
+func (c *Client) CancelGroupJoinRequest(msg map[string]any, q repository.Store) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
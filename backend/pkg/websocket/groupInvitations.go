@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -9,7 +10,7 @@ import (
 	"social/pkg/util"
 )
 
-func (c *Client) SendInvitation(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) SendInvitation(msg map[string]any, q repository.Store, h *Hub) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -91,7 +92,7 @@ func (c *Client) SendInvitation(msg map[string]any, q *repository.Query, h *Hub)
 		"pending",
 	})
 	if err != nil {
-		fmt.Println(err)
+		c.logger().Error("failed to store group invitation", "err", err)
 		c.SendError("failed to send invitation")
 		return
 	}
@@ -138,7 +139,7 @@ func (c *Client) SendInvitation(msg map[string]any, q *repository.Query, h *Hub)
 }
 
 // FIXED: Complete RespondSendInvitation function with proper logic
-func (c *Client) RespondSendInvitation(msg map[string]any, q *repository.Query) {
+func (c *Client) RespondSendInvitation(msg map[string]any, q repository.Store) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -205,44 +206,49 @@ func (c *Client) RespondSendInvitation(msg map[string]any, q *repository.Query)
 		return
 	}
 
-	// Update invitation status
-	err = q.UpdateData("group_invitations", []string{
-		"group_id",
-		"receiver_id",
-		"status",
-	}, []any{
-		request.GroupId,
-		c.UserID,
-		"pending",
-	}, []string{
-		"status",
-	}, []any{
-		request.ResponseStatus,
-	})
-	if err != nil {
-		c.SendError("Error updating invitation status")
-		return
-	}
+	// Update invitation status and, if accepted, add user to group members
+	// together, so an accepted invitation always comes with the membership.
+	failure := "Error updating invitation status"
+	err = q.WithTx(context.Background(), func(tx repository.Store) error {
+		err := tx.UpdateData("group_invitations", []string{
+			"group_id",
+			"receiver_id",
+			"status",
+		}, []any{
+			request.GroupId,
+			c.UserID,
+			"pending",
+		}, []string{
+			"status",
+		}, []any{
+			request.ResponseStatus,
+		})
+		if err != nil {
+			return err
+		}
 
-	// If accepted, add user to group members
-	if request.ResponseStatus == "accepted" {
-		memberID := util.UUIDGen()
-		err = q.InsertData("group_members", []string{
+		if request.ResponseStatus != "accepted" {
+			return nil
+		}
+		failure = "Error adding user to group"
+		return tx.InsertData("group_members", []string{
 			"id",
 			"group_id",
 			"user_id",
 			"role",
 		}, []any{
-			memberID,
+			util.UUIDGen(),
 			request.GroupId,
 			c.UserID,
 			"member",
 		})
-		if err != nil {
-			c.SendError("Error adding user to group")
-			return
-		}
+	})
+	if err != nil {
+		c.SendError(failure)
+		return
+	}
 
+	if request.ResponseStatus == "accepted" {
 		// Send success response to client
 		c.SendSuccess(fmt.Sprintf("Successfully joined group %s", request.GroupId))
 
@@ -259,14 +265,15 @@ func (c *Client) RespondSendInvitation(msg map[string]any, q *repository.Query)
 		}, []any{
 			systemNotificationID,
 			c.UserID,
-			"system", // System notification
+			c.UserID, // actor_id references users, the user joined themselves
 			"group_join_success",
 			"You have successfully joined the group",
 			request.GroupId, // Store group_id for reference
 			"group",
 		})
 		if err != nil {
-			// Don't return error here as the main operation succeeded
+			// The user has joined, only the notification is missing.
+			c.logger().Error("failed to store group join notification", "group_id", request.GroupId, "err", err)
 		}
 
 	} else {
@@ -275,7 +282,7 @@ func (c *Client) RespondSendInvitation(msg map[string]any, q *repository.Query)
 	}
 }
 
-func (c *Client) SendMemberInvitationProposal(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) SendMemberInvitationProposal(msg map[string]any, q repository.Store, h *Hub) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -325,8 +332,7 @@ func (c *Client) SendMemberInvitationProposal(msg map[string]any, q *repository.
 	}
 
 	// Get group info for notification
-	var groupTitle string
-	err = q.Db.QueryRow("SELECT title FROM groups WHERE id = ?", request.GroupId).Scan(&groupTitle)
+	groupTitle, err := q.FetchGroupTitle(request.GroupId)
 	if err != nil {
 		c.SendError("Error fetching group information")
 		return
@@ -3,7 +3,6 @@ package websocket
 import (
 	"encoding/json"
 	"html"
-	"log"
 	"strings"
 
 	"social/pkg/model"
@@ -11,7 +10,7 @@ import (
 	"social/pkg/util"
 )
 
-func (c *Client) GroupMessage(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) GroupMessage(msg map[string]any, q repository.Store, h *Hub) {
 	data, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -67,7 +66,7 @@ func (c *Client) GroupMessage(msg map[string]any, q *repository.Query, h *Hub) {
 
 	userData, err := json.Marshal(raw)
 	if err != nil {
-		log.Println("failed to marshal userdata: ", err)
+		c.logger().Error("failed to marshal group message", "err", err)
 		c.SendError("Failed to notify active group members")
 		return
 	}
@@ -81,7 +80,7 @@ func (c *Client) GroupMessage(msg map[string]any, q *repository.Query, h *Hub) {
 
 	groupData, err := json.Marshal(payload)
 	if err != nil {
-		log.Println("failed to marshal userdata: ", err)
+		c.logger().Error("failed to marshal group message", "err", err)
 		c.SendError("Failed to notify active group members")
 		return
 	}
@@ -89,7 +88,7 @@ func (c *Client) GroupMessage(msg map[string]any, q *repository.Query, h *Hub) {
 	h.BroadcastToGroup(c, message.GroupId, groupData)
 }
 
-func (c *Client) LoadGroupMessages(msg map[string]any, q *repository.Query) {
+func (c *Client) LoadGroupMessages(msg map[string]any, q repository.Store) {
 	data, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -8,7 +8,7 @@ import (
 	"social/pkg/util"
 )
 
-func (c *Client) GroupJoinRequest(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) GroupJoinRequest(msg map[string]any, q repository.Store, h *Hub) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -126,7 +126,7 @@ func (c *Client) GroupJoinRequest(msg map[string]any, q *repository.Query, h *Hu
 	}, userData)
 }
 
-func (c *Client) RespondGroupJoinRequest(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) RespondGroupJoinRequest(msg map[string]any, q repository.Store, h *Hub) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -241,7 +241,7 @@ func (c *Client) RespondGroupJoinRequest(msg map[string]any, q *repository.Query
 }
 
 // Helper function to fetch the latest join request with user info
-func fetchLatestJoinRequest(q *repository.Query, groupID, userID string) *model.GroupJoinRequest {
+func fetchLatestJoinRequest(q repository.Store, groupID, userID string) *model.GroupJoinRequest {
 	var group model.GroupData
 	if err := q.FetchGroupJoinRequest(groupID, &group); err != nil {
 		return nil
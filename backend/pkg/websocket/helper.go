@@ -2,7 +2,10 @@ package websocket
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 func (c *Client) SendError(text string) {
@@ -13,7 +16,7 @@ func (c *Client) SendError(text string) {
 	select {
 	case c.Send <- payload:
 	default:
-		log.Println("sendError: channel full")
+		c.logger().Warn("sendError: channel full")
 	}
 }
 
@@ -25,7 +28,7 @@ func (c *Client) SendSuccess(text string) {
 	select {
 	case c.Send <- payload:
 	default:
-		log.Println("sendSuccess: channel full")
+		c.logger().Warn("sendSuccess: channel full")
 	}
 }
 
@@ -33,14 +36,14 @@ func (c *Client) Cleanup() {
 	c.Once.Do(func() {
 		defer func() {
 			if r := recover(); r != nil {
-				log.Printf("recover in Cleanup: %v", r)
+				c.logger().Error("recover in Cleanup", "panic", r)
 			}
 		}()
 
 		select {
 		case c.Hubb.Unregister <- c:
 		default:
-			log.Println("Unregister channel full or closed")
+			c.logger().Warn("Unregister channel full or closed")
 		}
 
 		close(c.Send)
@@ -48,3 +51,27 @@ func (c *Client) Cleanup() {
 		_ = c.Conn.Close()
 	})
 }
+
+// Close sends a close frame with the given code and reason and closes the
+// underlying connection.
+func (c *Client) Close(code int, reason string) {
+	_ = c.Conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(writeWait),
+	)
+	_ = c.Conn.Close()
+}
+
+// logger returns the logger of the last message processed, which carries its
+// correlation id, or the connection's logger before the first message.
+func (c *Client) logger() *slog.Logger {
+	if logger := c.log.Load(); logger != nil {
+		return logger
+	}
+	return c.connectionLogger()
+}
+
+func (c *Client) connectionLogger() *slog.Logger {
+	return slog.With("request_id", c.RequestID, "user_id", c.UserID)
+}
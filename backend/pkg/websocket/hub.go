@@ -1,8 +1,14 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"sync"
+
+	"social/pkg/metrics"
+	"social/pkg/repository"
+
+	"github.com/gorilla/websocket"
 )
 
 type Hub struct {
@@ -11,6 +17,15 @@ type Hub struct {
 	Register   chan *Client
 	Unregister chan *Client
 	Mu         sync.RWMutex
+
+	// closing is set by Shutdown, clients connecting afterwards are turned away.
+	closing bool
+	// processing counts the clients whose messages are still being processed.
+	processing sync.WaitGroup
+	// quit stops Run.
+	quit chan struct{}
+	// ping is answered by Run, to check that it is still processing registrations.
+	ping chan struct{}
 }
 
 func NewHub() *Hub {
@@ -19,6 +34,8 @@ func NewHub() *Hub {
 		Groups:     make(map[string]map[*Client]bool),
 		Register:   make(chan *Client, 100),
 		Unregister: make(chan *Client, 100),
+		quit:       make(chan struct{}),
+		ping:       make(chan struct{}),
 	}
 }
 
@@ -58,11 +75,22 @@ func (h *Hub) NotifyUserOffline(userID string) {
 	}
 }
 
+// Run keeps track of the connected clients until Shutdown has drained them.
 func (h *Hub) Run() {
 	for {
 		select {
+		case <-h.quit:
+			return
+
+		case <-h.ping:
+
 		case c := <-h.Register:
 			h.Mu.Lock()
+			if h.closing {
+				h.Mu.Unlock()
+				c.Close(websocket.CloseGoingAway, "server shutting down")
+				continue
+			}
 			h.Clients[c] = true
 			for _, groupID := range c.Groups {
 				if _, ok := h.Groups[groupID]; !ok {
@@ -89,3 +117,115 @@ func (h *Hub) Run() {
 		}
 	}
 }
+
+// DisconnectSessions closes the connections of every client opened with one of
+// the given sessions or personal access tokens. The read pump then exits and
+// unregisters the client.
+func (h *Hub) DisconnectSessions(sessionIDs ...string) {
+	if len(sessionIDs) == 0 {
+		return
+	}
+
+	revoked := make(map[string]bool, len(sessionIDs))
+	for _, id := range sessionIDs {
+		revoked[id] = true
+	}
+
+	h.Mu.RLock()
+	var clients []*Client
+	for client := range h.Clients {
+		if revoked[client.SessionID] {
+			clients = append(clients, client)
+		}
+	}
+	h.Mu.RUnlock()
+
+	for _, client := range clients {
+		client.Close(websocket.ClosePolicyViolation, "session revoked")
+	}
+}
+
+// Ping returns nil once Run has picked up a ping, which shows its loop is not
+// stuck and still registers new clients, or ctx's error if that does not
+// happen in time.
+func (h *Hub) Ping(ctx context.Context) error {
+	select {
+	case h.ping <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Process handles the messages the client sends until its read pump stops.
+// Shutdown waits for the messages already received to be processed.
+func (h *Hub) Process(c *Client, q repository.Store) {
+	h.Mu.Lock()
+	if h.closing {
+		h.Mu.Unlock()
+		c.Close(websocket.CloseGoingAway, "server shutting down")
+		return
+	}
+	h.processing.Add(1)
+	h.Mu.Unlock()
+
+	go func() {
+		defer h.processing.Done()
+		c.ProcessMessages(q, h)
+	}()
+}
+
+// Shutdown sends a going away close frame to every client, then waits until
+// the messages they had sent are processed or ctx is done. Run returns afterwards.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	defer close(h.quit)
+
+	h.Mu.Lock()
+	h.closing = true
+	clients := make([]*Client, 0, len(h.Clients))
+	for client := range h.Clients {
+		clients = append(clients, client)
+	}
+	h.Mu.Unlock()
+
+	for _, client := range clients {
+		client.Close(websocket.CloseGoingAway, "server shutting down")
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		h.processing.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RegisterMetrics exposes the number of connected clients and group
+// subscriptions in the metrics registry.
+func (h *Hub) RegisterMetrics(registry *metrics.Registry) {
+	registry.NewGaugeFunc("websocket_clients", "Connected websocket clients.", func() float64 {
+		h.Mu.RLock()
+		defer h.Mu.RUnlock()
+		return float64(len(h.Clients))
+	})
+	registry.NewGaugeFunc("websocket_groups", "Groups with at least one connected member.", func() float64 {
+		h.Mu.RLock()
+		defer h.Mu.RUnlock()
+		return float64(len(h.Groups))
+	})
+	registry.NewGaugeFunc("websocket_group_subscriptions", "Group memberships of connected clients.", func() float64 {
+		h.Mu.RLock()
+		defer h.Mu.RUnlock()
+		subscriptions := 0
+		for _, members := range h.Groups {
+			subscriptions += len(members)
+		}
+		return float64(subscriptions)
+	})
+}
@@ -1,15 +1,58 @@
 package websocket
 
 import (
-	"log"
-
+	"social/pkg/logging"
+	"social/pkg/metrics"
 	"social/pkg/repository"
+	"social/pkg/util"
+
+	"github.com/gorilla/websocket"
 )
 
-// processMessages dispatches on msg["type"]
-func (c *Client) ProcessMessages(q *repository.Query, h *Hub) {
+// readOnlyMessages are the message types a client with an unverified email may send.
+var readOnlyMessages = map[any]bool{
+	"load_private_messages": true,
+	"load_group_messages":   true,
+}
+
+// processMessages dispatches on msg["type"]. Every message is logged with its
+// type and a correlation id, taken from its correlation_id field when the client
+// sends one, but never with its content.
+func (c *Client) ProcessMessages(q repository.Store, h *Hub) {
 	for msg := range c.ProcessChan {
-		log.Printf("User %s sent: %v", c.UserID, msg)
+		msgType, _ := msg["type"].(string)
+		correlationID, _ := msg["correlation_id"].(string)
+		if !logging.ValidID(correlationID) {
+			correlationID = util.UUIDGen()
+		}
+		logger := c.connectionLogger().With("message_type", msgType, "correlation_id", correlationID)
+		c.log.Store(logger)
+		logger.Info("websocket message")
+
+		// The session may have been revoked where the hub cannot close the
+		// connection, such as by the admin CLI disabling the user.
+		active, err := q.ConnectionActive(c.SessionID)
+		if err != nil {
+			logger.Error("failed to check the session", "err", err)
+			c.SendError("Internal server error")
+			continue
+		}
+		if !active {
+			metrics.WebsocketMessages.Inc("rejected_session")
+			c.Close(websocket.ClosePolicyViolation, "session revoked")
+			continue
+		}
+
+		if c.ReadOnly && !readOnlyMessages[msg["type"]] {
+			metrics.WebsocketMessages.Inc("rejected_unverified")
+			c.SendError("Email address not verified")
+			continue
+		}
+		if c.Allowed != nil && !c.Allowed[msgType] {
+			metrics.WebsocketMessages.Inc("rejected_scope")
+			c.SendError("Forbidden: token scopes do not allow " + msgType)
+			continue
+		}
 		switch msg["type"] {
 		case "follow_request":
 			c.FollowRequest(msg, q, h)
@@ -52,7 +95,10 @@ func (c *Client) ProcessMessages(q *repository.Query, h *Hub) {
 		case "group_event":
 			c.SendEventNotification(msg, q, h)
 		default:
+			// Only known types become label values, clients choose the rest.
+			msgType = "unknown"
 			c.SendError("Unknown message type")
 		}
+		metrics.WebsocketMessages.Inc(msgType)
 	}
 }
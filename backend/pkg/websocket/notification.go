@@ -31,7 +31,7 @@ func (h *Hub) InfoBasedNotification(recipients []string, info any) {
 	h.BroadcastToSpecific(recipients, payload)
 }
 
-func (c *Client) ReadNotification(msg map[string]any, q *repository.Query) {
+func (c *Client) ReadNotification(msg map[string]any, q repository.Store) {
 	data, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -75,7 +75,7 @@ func (c *Client) ReadNotification(msg map[string]any, q *repository.Query) {
 	}
 }
 
-func (c *Client) DeleteNotification(msg map[string]any, q *repository.Query) {
+func (c *Client) DeleteNotification(msg map[string]any, q repository.Store) {
 	data, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
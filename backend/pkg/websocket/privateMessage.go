@@ -10,7 +10,7 @@ import (
 	"social/pkg/util"
 )
 
-func (c *Client) PrivateMessage(msg map[string]any, q *repository.Query, h *Hub) {
+func (c *Client) PrivateMessage(msg map[string]any, q repository.Store, h *Hub) {
 	data, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -104,7 +104,7 @@ func (c *Client) PrivateMessage(msg map[string]any, q *repository.Query, h *Hub)
 	}, userData)
 }
 
-func (c *Client) ReadPrivateMessage(msg map[string]any, q *repository.Query) {
+func (c *Client) ReadPrivateMessage(msg map[string]any, q repository.Store) {
 	data, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -139,7 +139,7 @@ func (c *Client) ReadPrivateMessage(msg map[string]any, q *repository.Query) {
 	}
 }
 
-func (c *Client) LoadPrivateMessages(msg map[string]any, q *repository.Query) {
+func (c *Client) LoadPrivateMessages(msg map[string]any, q repository.Store) {
 	data, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
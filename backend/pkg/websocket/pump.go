@@ -2,7 +2,6 @@ package websocket
 
 import (
 	"encoding/json"
-	"log"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,10 +13,16 @@ const (
 	writeWait  = 10 * time.Second
 )
 
-// readPump reads raw frames, unmarshals, and pushes into processChan
+// readPump reads raw frames, unmarshals, and pushes into processChan.
+// processChan is closed when the connection ends, which stops ProcessMessages
+// once the remaining messages are handled.
 func (c *Client) ReadPump() {
 	defer func() {
-		c.Hubb.Unregister <- c
+		close(c.ProcessChan)
+		select {
+		case c.Hubb.Unregister <- c:
+		case <-c.Hubb.quit:
+		}
 		c.Conn.Close()
 	}()
 	c.Conn.SetReadLimit(512)
@@ -31,7 +36,7 @@ func (c *Client) ReadPump() {
 		_, raw, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("Unexpected close: %v", err)
+				c.connectionLogger().Warn("unexpected websocket close", "err", err)
 			}
 			break
 		}
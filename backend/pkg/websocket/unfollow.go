@@ -7,7 +7,7 @@ import (
 	"social/pkg/repository"
 )
 
-func (c *Client) Unfollow(msg map[string]any, q *repository.Query) {
+func (c *Client) Unfollow(msg map[string]any, q repository.Store) {
 	dataBytes, err := json.Marshal(msg["data"])
 	if err != nil {
 		c.SendError("Invalid data encoding")
@@ -1,45 +1,151 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"social/pkg/cli"
+	"social/pkg/config"
 	db "social/pkg/db"
 	handler "social/pkg/handler"
+	"social/pkg/logging"
+	"social/pkg/mail"
+	"social/pkg/metrics"
 	"social/pkg/model"
+	"social/pkg/oidc"
 	"social/pkg/repository"
+	"social/pkg/util"
 	"social/pkg/websocket"
 )
 
 func main() {
-	db, err := db.DBInstance()
+	cfg, args, err := config.LoadWithArgs(os.Args[1:])
 	if err != nil {
-		fmt.Println(err)
+		log.Fatal(err)
 	}
+	slog.SetDefault(logging.New(os.Stderr, cfg.Log.Format, cfg.Log.SlogLevel()))
+	util.SetDataDir(cfg.Data.Dir)
+
+	if len(args) > 0 {
+		os.Exit(runCommand(cfg, args))
+	}
+
+	schemaVersion, err := db.LatestSchemaVersion(cfg.Database)
+	if err != nil {
+		slog.Error("failed to read migrations", "err", err)
+	}
+
+	reader, err := db.OpenReader(cfg.Database)
+	if err != nil {
+		slog.Error("failed to open database read pool", "err", err)
+		os.Exit(1)
+	}
+	db, err := db.DBInstance(cfg.Database)
+	if err != nil {
+		slog.Error("failed to open database", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	hub := websocket.NewHub()
 	go hub.Run()
 
-	app := handler.App{
-		Queries: repository.Query{
-			Db: db,
+	metrics.RegisterDB(db)
+	if reader != nil {
+		metrics.RegisterReadDB(reader)
+	}
+	hub.RegisterMetrics(metrics.Default)
+
+	app := &handler.App{
+		Config: cfg,
+		Queries: &repository.Query{
+			Db:      db,
+			Reader:  reader,
+			Dialect: repository.Dialect(cfg.Database.Driver),
 		},
-		User: &model.User{},
-		Hub:  hub,
+		User:   &model.User{},
+		Hub:    hub,
+		Mailer: mail.New(cfg.Mail),
+		OIDC:   oidc.NewProviders(cfg.OIDC),
+
+		SchemaVersion: schemaVersion,
 	}
+	app.RunJobs(ctx)
 
 	server := http.Server{
-		Addr:    ":8000",
-		Handler: app.WithCORS(app.RouteChecker(app.Routes())),
+		Addr:    cfg.Server.Addr,
+		Handler: app.RequestLogger(app.InstrumentRequests(app.WithCORS(app.Routes()))),
 	}
+	serverErr := make(chan error, 1)
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
-			log.Println(err)
-			os.Exit(0)
-		}
+		serverErr <- server.ListenAndServe()
 	}()
-	fmt.Printf("Listening on port %s\n", server.Addr)
-	select {}
+	slog.Info("listening", "addr", server.Addr)
+
+	listenFailed := false
+	select {
+	case err := <-serverErr:
+		slog.Error("server stopped", "err", err)
+		listenFailed = true
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	}
+	stop()
+
+	// Stop accepting connections and wait for the requests in flight, then tell
+	// websocket clients to reconnect elsewhere and finish the messages they sent.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("http shutdown", "err", err)
+	}
+	if err := hub.Shutdown(shutdownCtx); err != nil {
+		slog.Error("websocket shutdown", "err", err)
+	}
+	if err := app.Wait(shutdownCtx); err != nil {
+		slog.Error("background work shutdown", "err", err)
+	}
+	if reader != nil {
+		if err := reader.Close(); err != nil {
+			slog.Error("failed to close database", "err", err)
+		}
+	}
+	if db != nil {
+		if err := db.Close(); err != nil {
+			slog.Error("failed to close database", "err", err)
+		}
+	}
+
+	if listenFailed {
+		os.Exit(1)
+	}
+}
+
+// runCommand runs an admin command instead of the server and returns the exit
+// status: 2 for invalid usage and 1 when the command failed.
+func runCommand(cfg *config.Config, args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := cli.Run(ctx, cfg, args, os.Stdin, os.Stdout)
+	if err == nil {
+		return 0
+	}
+	if err != cli.ErrUsage {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if errors.Is(err, cli.ErrUsage) {
+		return 2
+	}
+	return 1
 }
@@ -1,30 +1,50 @@
 'use client';
 
-import { useState } from 'react';
+import { useState, useEffect } from 'react';
 import { useRouter } from 'next/navigation';
 import Link from 'next/link';
 import { useToast } from '@/hooks/use-toast';
 import LoginForm from '@/components/auth/LoginForm';
+import TwoFactorForm from '@/components/auth/TwoFactorForm';
 import { useAuth } from '@/context/AuthContext';
 
 export default function LoginPage() {
   const router = useRouter();
   const { toast } = useToast();
-  const { login } = useAuth();
+  const { login, loginTwoFactor } = useAuth();
   const [isLoading, setIsLoading] = useState(false);
+  // Set while the login waits for a two-factor code.
+  const [mfaToken, setMfaToken] = useState(null);
+
+  // Provider sign-ins of accounts with two-factor authentication come back
+  // with the token in the URL.
+  useEffect(() => {
+    const token = new URLSearchParams(window.location.search).get('mfa_token');
+    if (token) {
+      setMfaToken(token);
+    }
+  }, []);
+
+  const loggedIn = () => {
+    toast({
+      title: "Success",
+      description: "You have been logged in successfully",
+    });
+
+    router.push('/');
+  };
 
   const handleLogin = async (formData) => {
     setIsLoading(true);
 
     try {
-      await login(formData.email, formData.password, formData.rememberMe);
-
-      toast({
-        title: "Success",
-        description: "You have been logged in successfully",
-      });
+      const result = await login(formData.email, formData.password, formData.rememberMe);
+      if (result?.mfaToken) {
+        setMfaToken(result.mfaToken);
+        return;
+      }
 
-      router.push('/');
+      loggedIn();
     } catch (error) {
       // AuthError objects have structured properties
       if (error.type === 'validation' || error.type === 'rate_limit') {
@@ -43,6 +63,43 @@ export default function LoginPage() {
     }
   };
 
+  const handleTwoFactor = async (code) => {
+    setIsLoading(true);
+
+    try {
+      await loginTwoFactor(mfaToken, code);
+      loggedIn();
+    } catch (error) {
+      if (error.type === 'validation' || error.type === 'rate_limit') {
+        throw error;
+      }
+      toast({
+        title: "Login Failed",
+        description: error.message,
+        variant: "destructive",
+      });
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  if (mfaToken) {
+    return (
+      <div className="space-y-6">
+        <div className="text-center">
+          <h1 className="text-2xl font-bold">Two-factor authentication</h1>
+          <p className="text-gray-500 mt-2">Enter the code from your authenticator app</p>
+        </div>
+
+        <TwoFactorForm
+          onSubmit={handleTwoFactor}
+          onCancel={() => setMfaToken(null)}
+          isLoading={isLoading}
+        />
+      </div>
+    );
+  }
+
   return (
     <div className="space-y-6">
       <div className="text-center">
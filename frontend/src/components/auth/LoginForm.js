@@ -1,11 +1,20 @@
 'use client';
 
-import React, { useState, useCallback, memo } from 'react';
+import React, { useState, useCallback, useEffect, memo } from 'react';
 import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
 import { Checkbox } from '@/components/ui/checkbox';
 import { Label } from '@/components/ui/label';
 import { Eye, EyeOff } from 'lucide-react';
+import { API_BASE_URL } from '@/context/AuthContext';
+
+// Messages for the error codes the backend appends when a provider sign-in fails.
+const OIDC_ERRORS = {
+  invalid_state: 'The sign-in request expired. Please try again.',
+  access_denied: 'Sign-in was cancelled.',
+  email_taken: 'An account with this email already exists. Log in with your password instead.',
+  email_not_verified: 'Please verify your email address before logging in.',
+};
 
 const LoginForm = memo(({ onSubmit, isLoading }) => {
   const [email, setEmail] = useState('');
@@ -13,6 +22,19 @@ const LoginForm = memo(({ onSubmit, isLoading }) => {
   const [rememberMe, setRememberMe] = useState(true);
   const [fieldErrors, setFieldErrors] = useState({});
   const [showPassword, setShowPassword] = useState(false);
+  const [providers, setProviders] = useState([]);
+
+  useEffect(() => {
+    fetch(`${API_BASE_URL}/api/oidcProviders`)
+      .then(res => (res.ok ? res.json() : { message: [] }))
+      .then(data => setProviders(data.message || []))
+      .catch(() => setProviders([]));
+
+    const error = new URLSearchParams(window.location.search).get('error');
+    if (error) {
+      setFieldErrors({ general: OIDC_ERRORS[error] || 'Sign-in with the provider failed. Please try again.' });
+    }
+  }, []);
 
   const handleEmailChange = useCallback((e) => {
     setEmail(e.target.value);
@@ -143,6 +165,21 @@ const LoginForm = memo(({ onSubmit, isLoading }) => {
       >
         {isLoading ? 'Logging in...' : 'Login'}
       </Button>
+
+      {providers.map(provider => (
+        <Button
+          key={provider.name}
+          type="button"
+          variant="outline"
+          className="w-full"
+          disabled={isLoading}
+          onClick={() => {
+            window.location.href = `${API_BASE_URL}/api/oidcLogin?provider=${encodeURIComponent(provider.name)}&remember_me=${rememberMe}`;
+          }}
+        >
+          Sign in with {provider.display_name}
+        </Button>
+      ))}
     </form>
   );
 });
@@ -0,0 +1,79 @@
+'use client';
+
+import React, { useState, useCallback, memo } from 'react';
+import { Button } from '@/components/ui/button';
+import { Input } from '@/components/ui/input';
+import { Label } from '@/components/ui/label';
+
+// Second step of the login of an account with two-factor authentication.
+const TwoFactorForm = memo(({ onSubmit, onCancel, isLoading }) => {
+  const [code, setCode] = useState('');
+  const [error, setError] = useState(null);
+
+  const handleSubmit = useCallback(async (e) => {
+    e.preventDefault();
+    setError(null);
+
+    if (!code.trim()) {
+      setError('Code is required');
+      return;
+    }
+
+    try {
+      await onSubmit(code);
+    } catch (error) {
+      if (error.type === 'validation' || error.type === 'rate_limit') {
+        setError(error.message);
+      }
+      // Other errors are handled by parent component
+    }
+  }, [code, onSubmit]);
+
+  return (
+    <form onSubmit={handleSubmit} className="space-y-4">
+      <div className="space-y-2">
+        <Label htmlFor="code">Authentication code</Label>
+        <Input
+          id="code"
+          type="text"
+          inputMode="numeric"
+          autoComplete="one-time-code"
+          placeholder="Enter the code from your app or a recovery code"
+          value={code}
+          onChange={(e) => setCode(e.target.value)}
+          className={error ? "border-red-500 focus:border-red-500" : ""}
+          disabled={isLoading}
+          autoFocus
+          required
+        />
+        {error && (
+          <p className="text-red-500 text-xs mt-1">
+            {error}
+          </p>
+        )}
+      </div>
+
+      <Button
+        type="submit"
+        className="w-full bg-social hover:bg-social-dark disabled:opacity-50"
+        disabled={isLoading}
+      >
+        {isLoading ? 'Verifying...' : 'Verify'}
+      </Button>
+
+      <Button
+        type="button"
+        variant="ghost"
+        className="w-full"
+        onClick={onCancel}
+        disabled={isLoading}
+      >
+        Back to login
+      </Button>
+    </form>
+  );
+});
+
+TwoFactorForm.displayName = 'TwoFactorForm';
+
+export default TwoFactorForm;
@@ -17,6 +17,7 @@ import PostCard from "@/components/post/PostCard";
 import GroupChat from "@/components/group/GroupChat";
 import { useAuth } from "@/context/AuthContext";
 import { toast } from "sonner";
+import { csrfHeaders } from "@/lib/utils";
 
 const GroupContent = ({
   groupData,
@@ -81,6 +82,7 @@ const GroupContent = ({
           credentials: "include",
           headers: {
             "Content-Type": "application/json",
+            ...csrfHeaders(),
           },
           body: JSON.stringify({ post_id: postId }),
         });
@@ -156,6 +158,7 @@ const GroupContent = ({
           credentials: "include",
           headers: {
             "Content-Type": "application/json",
+            ...csrfHeaders(),
           },
           body: JSON.stringify({ comment_id: commentId }),
         });
@@ -248,6 +251,7 @@ const GroupContent = ({
         const response = await fetch(`${API_BASE_URL}/api/addComment`, {
           method: "POST",
           credentials: "include",
+          headers: csrfHeaders(),
           body: formData
         });
 
@@ -12,7 +12,7 @@ import { Avatar, AvatarFallback, AvatarImage } from "@/components/ui/avatar";
 import { Button } from "@/components/ui/button";
 import PostCard from "@/components/post/PostCard";
 import { usePosts } from "@/context/PostContext";
-import { formatAvatarUrl } from "@/lib/utils";
+import { csrfHeaders, formatAvatarUrl } from "@/lib/utils";
 import {
   Users,
   Image as ImageIcon,
@@ -59,6 +59,7 @@ const UserProfileModal = ({ isOpen, onClose, user }) => {
         headers: {
           "Content-Type": "application/json",
           Accept: "application/json",
+          ...csrfHeaders(),
         },
         credentials: "include",
         body: JSON.stringify({ user_id: user.id }),
@@ -3,6 +3,7 @@
 import { createContext, useContext, useState, useEffect, useRef } from "react";
 import { wsManager } from "@/utils/websocket";
 import userService from "@/services/userService";
+import { csrfHeaders, getCSRFToken } from "@/lib/utils";
 
 const AuthContext = createContext();
 
@@ -48,7 +49,7 @@ export const AuthProvider = ({ children }) => {
       // User authenticated and WebSocket not connected
       wsManager.setAuthState(true);
       const wsUrl = process.env.NEXT_PUBLIC_WEBSOCKET_URL || "ws://localhost:8000/api/ws";
-      wsManager.connect(wsUrl);
+      wsManager.connect(`${wsUrl}?csrf_token=${encodeURIComponent(getCSRFToken())}`);
     } else if (!user && wsManager.isConnected()) {
       // User not authenticated and WebSocket is connected
       wsManager.setAuthState(false);
@@ -128,6 +129,7 @@ export const AuthProvider = ({ children }) => {
           Authorization: `Basic ${credentials}`,
         },
         credentials: "include",
+        body: JSON.stringify({ remember_me: Boolean(rememberMe) }),
       });
 
       if (!response.ok) {
@@ -165,6 +167,14 @@ export const AuthProvider = ({ children }) => {
         }
       }
 
+      // Accounts with two-factor authentication get no session yet, but a
+      // token to send with a code to loginTwoFactor.
+      const data = await response.json().catch(() => ({}));
+      if (data.data?.mfa_required) {
+        setLoading(false);
+        return { mfaToken: data.data.mfa_token };
+      }
+
       // CRITICAL FIX: checkAuth handles BOTH user state AND WebSocket - no duplication
       await checkAuth(); // This will set loading to false AND manage WebSocket properly
 
@@ -185,6 +195,63 @@ export const AuthProvider = ({ children }) => {
     }
   };
 
+  // Second step of a login with two-factor authentication: the token returned
+  // by login and a code from the authenticator app, or a recovery code.
+  const loginTwoFactor = async (mfaToken, code) => {
+    let response;
+    try {
+      setLoading(true);
+      response = await fetch(`${API_BASE_URL}/api/v1/login/2fa`, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          Accept: "application/json",
+        },
+        credentials: "include",
+        body: JSON.stringify({ mfa_token: mfaToken, code: code.trim() }),
+      });
+    } catch (error) {
+      setLoading(false);
+      console.error("Unexpected two-factor login error:", error);
+      throw new AuthError(
+        "Network error. Please check your connection.",
+        "network",
+      );
+    }
+
+    if (!response.ok) {
+      setLoading(false);
+      const errorData = await response.json().catch(() => ({}));
+
+      switch (response.status) {
+        case 401:
+          throw new AuthError(
+            errorData.error || "Invalid code",
+            "validation",
+            "code",
+            401,
+          );
+        case 429:
+          throw new AuthError(
+            "Too many attempts. Please log in again.",
+            "rate_limit",
+            null,
+            429,
+          );
+        default:
+          throw new AuthError(
+            errorData.error || "Login failed. Please try again.",
+            "general",
+            null,
+            response.status,
+          );
+      }
+    }
+
+    await checkAuth();
+    return true;
+  };
+
   //  Logout with proper cleanup
   const logout = async () => {
     try {
@@ -199,6 +266,7 @@ export const AuthProvider = ({ children }) => {
         credentials: "include",
         headers: {
           Accept: "application/json",
+          ...csrfHeaders(),
         },
       });
     } catch (error) {
@@ -287,6 +355,7 @@ export const AuthProvider = ({ children }) => {
         currentUser,
         loading,
         login,
+        loginTwoFactor,
         logout,
         getUserById,
         getAllUsers,
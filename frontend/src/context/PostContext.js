@@ -3,6 +3,7 @@
 import React, { createContext, useState, useEffect, useContext, useCallback } from 'react';
 import { toast } from "@/components/ui/sonner";
 import { useAuth } from '@/context/AuthContext';
+import { csrfHeaders } from '@/lib/utils';
 
 // API base URL
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8000';
@@ -198,6 +199,7 @@ export const PostProvider = ({ children }) => {
       const response = await fetch(`${API_BASE_URL}/api/addPost`, {
         method: 'POST',
         credentials: 'include',
+        headers: csrfHeaders(),
         body: formData
       });
 
@@ -296,6 +298,7 @@ export const PostProvider = ({ children }) => {
       const response = await fetch(`${API_BASE_URL}/api/addComment`, {
         method: 'POST',
         credentials: 'include',
+        headers: csrfHeaders(),
         body: formData
       });
 
@@ -410,6 +413,7 @@ export const PostProvider = ({ children }) => {
         credentials: 'include',
         headers: {
           'Content-Type': 'application/json',
+          ...csrfHeaders(),
         },
         body: JSON.stringify({ post_id: postId }),
       });
@@ -463,6 +467,7 @@ export const PostProvider = ({ children }) => {
         credentials: 'include',
         headers: {
           'Content-Type': 'application/json',
+          ...csrfHeaders(),
         },
         body: JSON.stringify({ post_id: postId, comment_id: commentId }),
       });
@@ -29,3 +29,22 @@ export const formatAvatarUrl = (url) => {
   // Otherwise, prefix with the backend URL
   return `${API_BASE_URL}/${url}`;
 };
+
+/**
+ * Reads the CSRF token the backend sets as a cookie on login
+ * @returns {string} - The token, or an empty string when logged out
+ */
+export const getCSRFToken = () => {
+  if (typeof document === 'undefined') return '';
+
+  const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+  return match ? decodeURIComponent(match[1]) : '';
+};
+
+/**
+ * Headers the backend requires on every POST, PATCH and DELETE request
+ * @returns {object} - The X-CSRF-Token header
+ */
+export const csrfHeaders = () => ({
+  'X-CSRF-Token': getCSRFToken(),
+});
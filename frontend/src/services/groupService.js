@@ -1,5 +1,6 @@
 // frontend/src/services/groupService.js - CORRECTED: Perfect backend integration
 import { webSocketOperations } from "@/utils/websocket";
+import { csrfHeaders } from "@/lib/utils";
 
 // API base URL
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8000";
@@ -74,7 +75,7 @@ const getHeaders = (includeContentType = false) => {
     headers["Content-Type"] = "application/json";
   }
 
-  return headers;
+  return { ...headers, ...csrfHeaders() };
 };
 
 // Helper function to handle WebSocket errors with exact backend error messages
@@ -1,3 +1,5 @@
+import { csrfHeaders } from "@/lib/utils";
+
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8000";
 
 class UserService {
@@ -21,6 +23,7 @@ class UserService {
         headers: {
           "Content-Type": "application/json",
           Accept: "application/json",
+          ...csrfHeaders(),
         },
         credentials: "include",
         body: JSON.stringify(userData),